@@ -68,7 +68,8 @@ func main() {
 
 	coreRepoFactory := core.NewFactory(db)
 	withdrawRepoFactory := repository.NewFactory(db)
-	service := withdraws.NewService(coreRepoFactory, withdrawRepoFactory)
+	// the banker never creates withdrawals, so it needs no fee policy or router
+	service := withdraws.NewService(coreRepoFactory, withdrawRepoFactory, nil, nil, nil, nil)
 
 	// init bank client
 	client, err := integrations.NewBankClient(enums.BankType(conf.Bank), conf.BankConfig)
@@ -116,7 +117,7 @@ func main() {
 			return
 		default:
 			err := processWithdraws(ctx, worker, func(c context.Context) ([]withdraws.Withdrawal, error) {
-				return withdrawRepoFactory.New(nil).GetUnFinishedWithdraws(c, conf.Prefix, enums.BankType(conf.Bank))
+				return service.GetUnFinishedWithdraws(c, conf.Prefix, enums.BankType(conf.Bank))
 			})
 			if err != nil {
 				logger.Get().Error("error processing withdraws", "err", utils.Stringify(err))
@@ -140,7 +141,7 @@ func processWithdraws(
 		log := logger.Get().With("withdraw_id", wd.ID, "status", wd.Status)
 
 		switch wd.Status {
-		case enums.NEW:
+		case enums.NEW, enums.SENDING:
 			if err := worker.SendToBank(ctx, &wd); err != nil {
 				log.Error("failed to enqueue withdrawal send", "err", utils.Stringify(err))
 			} else {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
 	"syscall"
@@ -37,7 +38,7 @@ func main() {
 	}
 	coreFactory := core.NewFactory(db)
 	depFactory := repository.NewFactory(db)
-	service := deposits.New(coreFactory, depFactory)
+	service := deposits.New(coreFactory, depFactory, nil)
 
 	// Graceful shutdown setup
 	ctx, cancel := context.WithCancel(context.Background())
@@ -78,7 +79,9 @@ func processDeposits(ctx context.Context, service deposits.Service, prefix strin
 	for _, d := range depositsList {
 		logger := logger.Get().With("deposit_id", d.ID)
 		err := service.Apply(ctx, &d)
-		if err != nil {
+		if errors.Is(err, deposits.ErrNotDue) || errors.Is(err, deposits.ErrAlreadyCancelled) {
+			logger.Info("skipped deposit changed since listing", "err", err.Error())
+		} else if err != nil {
 			logger.Error("failed to apply deposit", "err", utils.Stringify(err))
 		} else {
 			logger.Info("applied deposit")
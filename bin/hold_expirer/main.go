@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"wallet/lib/config"
+	"wallet/lib/core"
+	"wallet/lib/holds"
+	"wallet/lib/holds/repository"
+	"wallet/lib/utils"
+	"wallet/lib/utils/db"
+	"wallet/lib/utils/logger"
+)
+
+type Config struct {
+	DbDsn         string
+	Prefix        string
+	SleepInterval time.Duration
+}
+
+func (c *Config) FillDefaults() {
+	if c.SleepInterval == time.Duration(0) {
+		c.SleepInterval = 10 * time.Second
+	}
+}
+
+func main() {
+	conf := Config{}
+	config.Load(&conf)
+	db, err := db.Connect(conf.DbDsn)
+	if err != nil {
+		logger.Get().Error("failed to connect DB:", "err", utils.Stringify(err))
+		os.Exit(1)
+	}
+	coreFactory := core.NewFactory(db)
+	holdFactory := repository.NewFactory(db)
+	service := holds.New(coreFactory, holdFactory)
+
+	// Graceful shutdown setup
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		logger.Get().Info("shutting down")
+		cancel()
+	}()
+
+	logger.Get().Info("hold expirer started", "prefix", conf.Prefix)
+
+	// Infinite loop
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Get().Info("exiting loop")
+			return
+		default:
+			err := processHolds(ctx, service, conf.Prefix)
+			if err != nil {
+				logger.Get().Error("error processing holds", "err", utils.Stringify(err))
+			}
+			time.Sleep(conf.SleepInterval)
+		}
+	}
+}
+
+func processHolds(ctx context.Context, service holds.Service, prefix string) error {
+	holdsList, err := service.GetExpiredHolds(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range holdsList {
+		logger := logger.Get().With("hold_id", h.ID)
+		err := service.Expire(ctx, &h)
+		if err != nil {
+			logger.Error("failed to expire hold", "err", utils.Stringify(err))
+		} else {
+			logger.Info("expired hold")
+		}
+	}
+
+	return nil
+}
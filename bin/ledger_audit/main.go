@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"syscall"
+	"wallet/lib/audit"
+	"wallet/lib/audit/repository"
+	"wallet/lib/config"
+	"wallet/lib/utils"
+	"wallet/lib/utils/db"
+	"wallet/lib/utils/logger"
+)
+
+// Exit codes: 0 when the ledger is consistent, 1 when the audit could not run,
+// 2 when inconsistencies were found.
+const (
+	exitFailed       = 1
+	exitInconsistent = 2
+)
+
+type Config struct {
+	DbDsn  string
+	Prefix string
+	// JsonReport prints the full report to stdout as JSON.
+	JsonReport bool
+}
+
+func main() {
+	conf := Config{}
+	config.Load(&conf)
+	db, err := db.Connect(conf.DbDsn)
+	if err != nil {
+		logger.Get().Error("failed to connect DB:", "err", utils.Stringify(err))
+		os.Exit(exitFailed)
+	}
+	service := audit.New(repository.NewFactory(db))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger.Get().Info("ledger audit started", "prefix", conf.Prefix)
+	report, err := service.Run(ctx, conf.Prefix)
+	if err != nil {
+		logger.Get().Error("ledger audit failed", "err", utils.Stringify(err))
+		os.Exit(exitFailed)
+	}
+
+	for _, drift := range report.Drifts {
+		logger.Get().Warn("wallet balance drift",
+			"user_id", drift.UserID,
+			"currency", drift.Currency,
+			"available_balance", drift.AvailableBalance,
+			"transactions_available", drift.TransactionsAvailable,
+			"blocked_balance", drift.BlockedBalance,
+			"transactions_blocked", drift.TransactionsBlocked,
+		)
+	}
+	for _, issue := range report.LinkIssues {
+		logger.Get().Warn("inconsistent linked transaction",
+			"source", issue.Source,
+			"source_id", issue.SourceID,
+			"field", issue.Field,
+			"transaction_id", issue.TransactionID,
+			"problem", issue.Problem,
+		)
+	}
+	logger.Get().Info("ledger audit finished",
+		"wallets_checked", report.WalletsChecked,
+		"drifts", len(report.Drifts),
+		"link_issues", len(report.LinkIssues),
+	)
+
+	if conf.JsonReport {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			logger.Get().Error("failed to write report", "err", utils.Stringify(err))
+			os.Exit(exitFailed)
+		}
+	}
+	if !report.Consistent() {
+		os.Exit(exitInconsistent)
+	}
+}
@@ -8,22 +8,40 @@ import (
 	"syscall"
 	"time"
 
+	"wallet/lib/adjustments"
+	adjustments_repository "wallet/lib/adjustments/repository"
+	"wallet/lib/beneficiaries"
+	beneficiaries_repository "wallet/lib/beneficiaries/repository"
 	"wallet/lib/config"
 	"wallet/lib/core"
 	"wallet/lib/deposits"
 	deposits_repository "wallet/lib/deposits/repository"
+	"wallet/lib/exchange"
+	exchange_repository "wallet/lib/exchange/repository"
+	"wallet/lib/fees"
+	"wallet/lib/holds"
+	holds_repository "wallet/lib/holds/repository"
+	"wallet/lib/idempotency"
 	"wallet/lib/rest"
+	"wallet/lib/transfers"
+	transfers_repository "wallet/lib/transfers/repository"
 	"wallet/lib/utils/db"
 	"wallet/lib/utils/logger"
 	"wallet/lib/withdraws"
 	withdraws_repository "wallet/lib/withdraws/repository"
+	"wallet/lib/withdraws/routing"
 )
 
 type Config struct {
-	DbDsn     string
-	AuthToken string
-	BindAt    int           // port number (e.g. 8080)
-	GraceTime time.Duration // graceful shutdown timeout
+	DbDsn         string
+	AuthToken     string
+	AdminTokens   map[string]string // operator name to their token for /api/v1/admin, admin endpoints are closed when empty
+	BindAt        int               // port number (e.g. 8080)
+	GraceTime     time.Duration     // graceful shutdown timeout
+	Fx            exchange.Config
+	Fees          fees.Config          // withdrawal fees
+	Routing       routing.Config       // picks the bank of withdrawals created without one
+	Beneficiaries beneficiaries.Config // saved payout destinations
 }
 
 func (c *Config) FillDefaults() {
@@ -40,6 +58,16 @@ func main() {
 	conf := Config{}
 	config.Load(&conf)
 
+	// an operator shares no token, or their changes could not be told apart
+	adminTokens := make(map[string]bool, len(conf.AdminTokens))
+	for operator, token := range conf.AdminTokens {
+		if token == "" || adminTokens[token] || len(operator) > 64 {
+			logger.Get().Error("invalid admin token", "operator", operator)
+			os.Exit(1)
+		}
+		adminTokens[token] = true
+	}
+
 	// Connect to database
 	db, err := db.Connect(conf.DbDsn)
 	if err != nil {
@@ -51,13 +79,48 @@ func main() {
 	coreRepoFactory := core.NewFactory(db)
 	depositRepoFactory := deposits_repository.NewFactory(db)
 	withdrawRepoFactory := withdraws_repository.NewFactory(db)
+	idempotencyRepoFactory := idempotency.NewFactory(db)
+	transferRepoFactory := transfers_repository.NewFactory(db)
+	quoteRepoFactory := exchange_repository.NewFactory(db)
+	adjustmentRepoFactory := adjustments_repository.NewFactory(db)
+	holdRepoFactory := holds_repository.NewFactory(db)
+	beneficiaryRepoFactory := beneficiaries_repository.NewFactory(db)
+
+	// Build rate provider
+	rateProvider, err := exchange.NewStaticRateProvider(conf.Fx.Rates)
+	if err != nil {
+		logger.Get().Error("failed to load fx rates", "err", err)
+		os.Exit(1)
+	}
+
+	// Build fee policy
+	feePolicy, err := fees.New(conf.Fees)
+	if err != nil {
+		logger.Get().Error("failed to load fee policy", "err", err)
+		os.Exit(1)
+	}
+
+	// Build withdrawal router; without one withdrawals must name their bank
+	var router routing.Router
+	if len(conf.Routing.Default) != 0 {
+		router, err = routing.New(conf.Routing)
+		if err != nil {
+			logger.Get().Error("failed to load withdrawal routing", "err", err)
+			os.Exit(1)
+		}
+	}
 
 	// Build services
-	depositService := deposits.New(coreRepoFactory, depositRepoFactory)
-	withdrawService := withdraws.NewService(coreRepoFactory, withdrawRepoFactory)
+	depositService := deposits.New(coreRepoFactory, depositRepoFactory, idempotencyRepoFactory)
+	beneficiaryService := beneficiaries.New(beneficiaryRepoFactory, conf.Beneficiaries)
+	withdrawService := withdraws.NewService(coreRepoFactory, withdrawRepoFactory, idempotencyRepoFactory, feePolicy, router, beneficiaryService)
+	transferService := transfers.New(coreRepoFactory, transferRepoFactory)
+	exchangeService := exchange.New(coreRepoFactory, quoteRepoFactory, rateProvider, conf.Fx)
+	adjustmentService := adjustments.New(coreRepoFactory, adjustmentRepoFactory)
+	holdService := holds.New(coreRepoFactory, holdRepoFactory)
 
 	// Create HTTP server
-	server := rest.New(conf.BindAt, conf.AuthToken, depositService, withdrawService, coreRepoFactory)
+	server := rest.New(conf.BindAt, conf.AuthToken, conf.AdminTokens, depositService, withdrawService, transferService, exchangeService, adjustmentService, holdService, beneficiaryService, coreRepoFactory)
 
 	// Handle signals for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
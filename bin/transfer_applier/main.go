@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"wallet/lib/config"
+	"wallet/lib/core"
+	"wallet/lib/transfers"
+	"wallet/lib/transfers/repository"
+	"wallet/lib/utils"
+	"wallet/lib/utils/db"
+	"wallet/lib/utils/logger"
+)
+
+type Config struct {
+	DbDsn         string
+	Prefix        string
+	SleepInterval time.Duration
+}
+
+func (c *Config) FillDefaults() {
+	if c.SleepInterval == time.Duration(0) {
+		c.SleepInterval = 10 * time.Second
+	}
+}
+
+func main() {
+	conf := Config{}
+	config.Load(&conf)
+	db, err := db.Connect(conf.DbDsn)
+	if err != nil {
+		logger.Get().Error("failed to connect DB:", "err", utils.Stringify(err))
+		os.Exit(1)
+	}
+	coreFactory := core.NewFactory(db)
+	transferFactory := repository.NewFactory(db)
+	service := transfers.New(coreFactory, transferFactory)
+
+	// Graceful shutdown setup
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		logger.Get().Info("shutting down")
+		cancel()
+	}()
+
+	logger.Get().Info("transfer worker started", "prefix", conf.Prefix)
+
+	// Infinite loop
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Get().Info("exiting loop")
+			return
+		default:
+			err := processTransfers(ctx, service, conf.Prefix)
+			if err != nil {
+				logger.Get().Error("error processing transfers", "err", utils.Stringify(err))
+			}
+			time.Sleep(conf.SleepInterval)
+		}
+	}
+}
+
+func processTransfers(ctx context.Context, service transfers.Service, prefix string) error {
+	transfersList, err := service.GetApplicableTransfers(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transfersList {
+		logger := logger.Get().With("transfer_id", t.ID)
+		err := service.Apply(ctx, &t)
+		if err != nil {
+			logger.Error("failed to apply held transfer", "err", utils.Stringify(err))
+		} else {
+			logger.Info("applied held transfer")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+package adjustments
+
+import "errors"
+
+var ErrInvalidAmount = errors.New("adjustment amount must not be zero")
+var ErrInvalidBalance = errors.New("adjustment balance must be available or blocked")
+var ErrMissingReason = errors.New("adjustment needs a reason")
+var ErrMissingActor = errors.New("adjustment needs an actor")
+var ErrNotFound = errors.New("adjustment not found")
+var ErrInvalidState = errors.New("adjustment is already reviewed")
+var ErrSameOperator = errors.New("adjustment must be approved by another operator")
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"wallet/lib/adjustments/repository/internal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Adjustment = internal.Adjustment
+type Balance = internal.Balance
+type Status = internal.Status
+
+const (
+	Available = internal.Available
+	Blocked   = internal.Blocked
+
+	Pending  = internal.Pending
+	Approved = internal.Approved
+	Rejected = internal.Rejected
+)
+
+type Repo interface {
+	Create(context.Context, *Adjustment) error
+	Update(context.Context, *Adjustment) error
+	Get(ctx context.Context, id uuid.UUID) (*Adjustment, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*Adjustment, error)
+	List(ctx context.Context, userID uuid.UUID, status Status) ([]Adjustment, error)
+
+	GetDBTransaction() *gorm.DB
+	Commit() error
+	RollBack() error
+}
+
+type RepoFactory interface {
+	New(tx *gorm.DB) Repo
+}
+
+func NewFactory(db *gorm.DB) RepoFactory {
+	return &repoFactory{
+		db: db,
+	}
+}
+
+type repoFactory struct {
+	db *gorm.DB
+}
+
+func (rf *repoFactory) New(tx *gorm.DB) Repo {
+	if tx == nil {
+		tx = rf.db.Begin()
+	}
+	return internal.NewAdjustmentRepo(tx)
+}
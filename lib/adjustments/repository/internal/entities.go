@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"time"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+)
+
+// Balance names which balance of a wallet an adjustment changes.
+type Balance string
+
+const (
+	Available = Balance("available")
+	Blocked   = Balance("blocked")
+)
+
+type Status string
+
+const (
+	Pending  = Status("pending")
+	Approved = Status("approved")
+	Rejected = Status("rejected")
+)
+
+// Adjustment is a manual correction of a wallet balance. It is proposed by
+// one operator and only posted once a different operator approves it.
+type Adjustment struct {
+	ID            uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID        uuid.UUID     `gorm:"type:uuid;not null;index" json:"user_id"`
+	Currency      core.Currency `gorm:"type:varchar(3);not null" json:"currency"`
+	Balance       Balance       `gorm:"type:varchar(16);not null" json:"balance"`
+	Amount        int64         `gorm:"not null" json:"amount"` // positive credits, negative debits
+	Reason        string        `gorm:"size:255;not null" json:"reason"`
+	Status        Status        `gorm:"type:varchar(16);not null;index" json:"status"`
+	ProposedBy    string        `gorm:"size:64;not null" json:"proposed_by"`
+	ProposedAt    time.Time     `gorm:"not null;index" json:"proposed_at"`
+	ReviewedBy    string        `gorm:"size:64" json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time    `json:"reviewed_at,omitempty"`
+	TransactionID uint64        `gorm:"index" json:"transaction_id"`
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type adjustmentRepo struct {
+	tx *gorm.DB
+}
+
+func NewAdjustmentRepo(tx *gorm.DB) *adjustmentRepo {
+	return &adjustmentRepo{tx: tx}
+}
+
+// Create inserts a new adjustment and fills in ID automatically.
+func (r *adjustmentRepo) Create(ctx context.Context, adjustment *Adjustment) error {
+	if adjustment.ID == uuid.Nil {
+		adjustment.ID = uuid.New()
+	}
+	return r.tx.WithContext(ctx).Create(adjustment).Error
+}
+
+// Update updates an existing adjustment by ID.
+func (r *adjustmentRepo) Update(ctx context.Context, adjustment *Adjustment) error {
+	return r.tx.WithContext(ctx).
+		Model(&Adjustment{}).
+		Where("id = ?", adjustment.ID).
+		Updates(adjustment).Error
+}
+
+// Get fetches an adjustment by ID.
+func (r *adjustmentRepo) Get(ctx context.Context, id uuid.UUID) (*Adjustment, error) {
+	var adjustment Adjustment
+	if err := r.tx.WithContext(ctx).First(&adjustment, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// GetForUpdate fetches an adjustment by ID with row-level locking.
+func (r *adjustmentRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*Adjustment, error) {
+	var adjustment Adjustment
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&adjustment, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// List returns adjustments, newest first, optionally of one wallet owner and in one status.
+func (r *adjustmentRepo) List(ctx context.Context, userID uuid.UUID, status Status) ([]Adjustment, error) {
+	var adjustments []Adjustment
+	query := r.tx.WithContext(ctx)
+	if userID != uuid.Nil {
+		query = query.Where("user_id = ?", userID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("proposed_at DESC").Find(&adjustments).Error; err != nil {
+		return nil, err
+	}
+	return adjustments, nil
+}
+
+// GetDBTransaction returns the underlying gorm.DB (transaction).
+func (r *adjustmentRepo) GetDBTransaction() *gorm.DB {
+	return r.tx
+}
+
+// Commit commits the transaction.
+func (r *adjustmentRepo) Commit() error {
+	return r.tx.Commit().Error
+}
+
+// RollBack rolls back the transaction.
+func (r *adjustmentRepo) RollBack() error {
+	return r.tx.Rollback().Error
+}
@@ -0,0 +1,182 @@
+package adjustments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"wallet/lib/adjustments/repository"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Adjustment = repository.Adjustment
+
+type Service interface {
+	// Propose records a pending adjustment of a wallet balance by actor.
+	Propose(ctx context.Context, adjustment *Adjustment, actor string) error
+	// Approve posts a pending adjustment. actor must differ from the proposer.
+	Approve(ctx context.Context, id uuid.UUID, actor string) (*Adjustment, error)
+	// Reject closes a pending adjustment without touching the wallet.
+	Reject(ctx context.Context, id uuid.UUID, actor string) (*Adjustment, error)
+	Get(ctx context.Context, id uuid.UUID) (*Adjustment, error)
+	List(ctx context.Context, userID uuid.UUID, status repository.Status) ([]Adjustment, error)
+}
+
+func New(
+	coreRepoFactory core.RepoFactory,
+	repoFactory repository.RepoFactory,
+) Service {
+	return &service{
+		coreRepoFactory: coreRepoFactory,
+		repoFactory:     repoFactory,
+	}
+}
+
+type service struct {
+	coreRepoFactory core.RepoFactory
+	repoFactory     repository.RepoFactory
+}
+
+func (s *service) Propose(ctx context.Context, adjustment *Adjustment, actor string) error {
+	if adjustment.Amount == 0 {
+		return ErrInvalidAmount
+	}
+	if adjustment.Balance != repository.Available && adjustment.Balance != repository.Blocked {
+		return ErrInvalidBalance
+	}
+	if strings.TrimSpace(adjustment.Reason) == "" {
+		return ErrMissingReason
+	}
+	if actor == "" {
+		return ErrMissingActor
+	}
+	if !adjustment.Currency.IsSupported() {
+		return core.ErrUnsupportedCurrency
+	}
+	adjustment.Status = repository.Pending
+	adjustment.ProposedBy = actor
+	adjustment.ProposedAt = time.Now()
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	if err := repo.Create(ctx, adjustment); err != nil {
+		return err
+	}
+	return repo.Commit()
+}
+
+func (s *service) Approve(ctx context.Context, id uuid.UUID, actor string) (*Adjustment, error) {
+	if actor == "" {
+		return nil, ErrMissingActor
+	}
+	repo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(repo.GetDBTransaction())
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	adjustment, err := s.getPendingForUpdate(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	if adjustment.ProposedBy == actor {
+		return nil, ErrSameOperator
+	}
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, adjustment.UserID, adjustment.Currency)
+	if err != nil {
+		return nil, err
+	}
+	// corrections are allowed on frozen wallets, but a closed wallet stays untouched
+	if wallet.Status == core.WalletClosed {
+		return nil, core.ErrWalletClosed
+	}
+	trx := &core.Transaction{
+		Kind:        core.KindAdjustment,
+		WalletID:    wallet.UserID,
+		Currency:    wallet.Currency,
+		Reference:   adjustment.ID,
+		Description: adjustment.Reason,
+	}
+	if adjustment.Balance == repository.Available {
+		wallet.AvailableBalance += adjustment.Amount
+		trx.Amount = adjustment.Amount
+	} else {
+		wallet.BlockedBalance += adjustment.Amount
+		trx.BlockedAmount = adjustment.Amount
+	}
+	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
+		return nil, err
+	}
+	if err := core.Post(ctx, coreRepo, core.Suspense, trx); err != nil {
+		return nil, err
+	}
+	review(adjustment, repository.Approved, actor)
+	adjustment.TransactionID = trx.ID
+	if err := repo.Update(ctx, adjustment); err != nil {
+		return nil, err
+	}
+	return adjustment, repo.Commit()
+}
+
+func (s *service) Reject(ctx context.Context, id uuid.UUID, actor string) (*Adjustment, error) {
+	if actor == "" {
+		return nil, ErrMissingActor
+	}
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	adjustment, err := s.getPendingForUpdate(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	review(adjustment, repository.Rejected, actor)
+	if err := repo.Update(ctx, adjustment); err != nil {
+		return nil, err
+	}
+	return adjustment, repo.Commit()
+}
+
+func (s *service) getPendingForUpdate(ctx context.Context, repo repository.Repo, id uuid.UUID) (*Adjustment, error) {
+	adjustment, err := repo.GetForUpdate(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if adjustment.Status != repository.Pending {
+		return nil, ErrInvalidState
+	}
+	return adjustment, nil
+}
+
+func review(adjustment *Adjustment, status repository.Status, actor string) {
+	now := time.Now()
+	adjustment.Status = status
+	adjustment.ReviewedBy = actor
+	adjustment.ReviewedAt = &now
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*Adjustment, error) {
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	adjustment, err := repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return adjustment, err
+}
+
+func (s *service) List(ctx context.Context, userID uuid.UUID, status repository.Status) ([]Adjustment, error) {
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	return repo.List(ctx, userID, status)
+}
@@ -0,0 +1,238 @@
+package adjustments_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/adjustments"
+	"wallet/lib/adjustments/repository"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// --- Mocks ---
+
+type MockAdjustmentRepo struct{ mock.Mock }
+
+func (m *MockAdjustmentRepo) Create(ctx context.Context, a *repository.Adjustment) error {
+	return m.Called(ctx, a).Error(0)
+}
+func (m *MockAdjustmentRepo) Update(ctx context.Context, a *repository.Adjustment) error {
+	return m.Called(ctx, a).Error(0)
+}
+func (m *MockAdjustmentRepo) Get(ctx context.Context, id uuid.UUID) (*repository.Adjustment, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*repository.Adjustment), args.Error(1)
+}
+func (m *MockAdjustmentRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*repository.Adjustment, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*repository.Adjustment), args.Error(1)
+}
+func (m *MockAdjustmentRepo) List(ctx context.Context, userID uuid.UUID, status repository.Status) ([]repository.Adjustment, error) {
+	args := m.Called(ctx, userID, status)
+	return args.Get(0).([]repository.Adjustment), args.Error(1)
+}
+func (m *MockAdjustmentRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockAdjustmentRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockAdjustmentRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockAdjustmentRepoFactory struct{ mock.Mock }
+
+func (m *MockAdjustmentRepoFactory) New(tx *gorm.DB) repository.Repo {
+	return m.Called(tx).Get(0).(repository.Repo)
+}
+
+type MockWalletRepo struct{ mock.Mock }
+
+func (m *MockWalletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) List(ctx context.Context, userID uuid.UUID) ([]core.Wallet, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) Update(ctx context.Context, wallet *core.Wallet) error {
+	// the real repo enforces the balance policy
+	if err := wallet.Validate(); err != nil {
+		return err
+	}
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateCreditLimit(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateStatus(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+
+type MockTransactionRepo struct{ mock.Mock }
+
+func (m *MockTransactionRepo) Create(ctx context.Context, trx *core.Transaction) error {
+	trx.ID = 42
+	return m.Called(ctx, trx).Error(0)
+}
+func (m *MockTransactionRepo) Get(ctx context.Context, filter core.TransactionFilter, pageNumber int, pageSize int) ([]core.Transaction, bool, error) {
+	args := m.Called(ctx, filter, pageNumber, pageSize)
+	return args.Get(0).([]core.Transaction), args.Bool(1), args.Error(2)
+}
+func (m *MockTransactionRepo) List(ctx context.Context, filter core.TransactionFilter, cursor uint64, limit int) ([]core.Transaction, uint64, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	return args.Get(0).([]core.Transaction), args.Get(1).(uint64), args.Error(2)
+}
+func (m *MockTransactionRepo) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency core.Currency, at time.Time) (*core.Balance, error) {
+	args := m.Called(ctx, userID, currency, at)
+	return args.Get(0).(*core.Balance), args.Error(1)
+}
+
+type MockLedgerRepo struct{ mock.Mock }
+
+func (m *MockLedgerRepo) Post(ctx context.Context, entries []core.LedgerEntry) error {
+	return m.Called(ctx, entries).Error(0)
+}
+func (m *MockLedgerRepo) GetBalance(ctx context.Context, account core.Account, currency core.Currency) (*core.AccountBalance, error) {
+	args := m.Called(ctx, account, currency)
+	return args.Get(0).(*core.AccountBalance), args.Error(1)
+}
+
+type MockCoreRepo struct{ mock.Mock }
+
+func (m *MockCoreRepo) Wallet() core.WalletRepo {
+	return m.Called().Get(0).(core.WalletRepo)
+}
+func (m *MockCoreRepo) Transaction() core.TransactionRepo {
+	return m.Called().Get(0).(core.TransactionRepo)
+}
+func (m *MockCoreRepo) Ledger() core.LedgerRepo {
+	return m.Called().Get(0).(core.LedgerRepo)
+}
+func (m *MockCoreRepo) CreditLimitChange() core.CreditLimitChangeRepo {
+	return m.Called().Get(0).(core.CreditLimitChangeRepo)
+}
+func (m *MockCoreRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockCoreRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockCoreRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockCoreRepoFactory struct{ mock.Mock }
+
+func (m *MockCoreRepoFactory) New(tx *gorm.DB) core.Repo {
+	return m.Called(tx).Get(0).(core.Repo)
+}
+
+// --- Tests ---
+
+func setup(adjustment *repository.Adjustment, wallet *core.Wallet) (adjustments.Service, *MockAdjustmentRepo) {
+	ctx := context.Background()
+	repo := new(MockAdjustmentRepo)
+	repoFactory := new(MockAdjustmentRepoFactory)
+	walletRepo := new(MockWalletRepo)
+	trxRepo := new(MockTransactionRepo)
+	ledgerRepo := new(MockLedgerRepo)
+	coreRepo := new(MockCoreRepo)
+	coreRepoFactory := new(MockCoreRepoFactory)
+
+	repo.On("Create", ctx, mock.Anything).Return(nil)
+	repo.On("Update", ctx, mock.Anything).Return(nil)
+	repo.On("GetForUpdate", ctx, adjustment.ID).Return(adjustment, nil)
+	repo.On("Commit").Return(nil)
+	repo.On("RollBack").Return(nil)
+	repoFactory.On("New", (*gorm.DB)(nil)).Return(repo)
+
+	walletRepo.On("GetOrCreateForUpdate", ctx, wallet.UserID, wallet.Currency).Return(wallet, nil)
+	walletRepo.On("Update", ctx, wallet).Return(nil)
+	trxRepo.On("Create", ctx, mock.Anything).Return(nil)
+	ledgerRepo.On("Post", ctx, mock.Anything).Return(nil)
+	coreRepo.On("Wallet").Return(walletRepo)
+	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
+	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
+
+	return adjustments.New(coreRepoFactory, repoFactory), repo
+}
+
+func newPending(wallet *core.Wallet, balance repository.Balance, amount int64) *repository.Adjustment {
+	return &repository.Adjustment{
+		ID:         uuid.New(),
+		UserID:     wallet.UserID,
+		Currency:   wallet.Currency,
+		Balance:    balance,
+		Amount:     amount,
+		Reason:     "fix double fee",
+		Status:     repository.Pending,
+		ProposedBy: "maker",
+	}
+}
+
+func TestService_Propose(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR}
+	service, repo := setup(&repository.Adjustment{}, wallet)
+
+	adjustment := &repository.Adjustment{UserID: wallet.UserID, Currency: core.IRR, Balance: repository.Available, Amount: 100, Reason: "refund"}
+	assert.NoError(t, service.Propose(context.Background(), adjustment, "maker"))
+	assert.Equal(t, repository.Pending, adjustment.Status)
+	assert.Equal(t, "maker", adjustment.ProposedBy)
+	repo.AssertCalled(t, "Commit")
+
+	invalid := &repository.Adjustment{UserID: wallet.UserID, Currency: core.IRR, Balance: "pending", Amount: 100, Reason: "refund"}
+	assert.ErrorIs(t, service.Propose(context.Background(), invalid, "maker"), adjustments.ErrInvalidBalance)
+}
+
+func TestService_Approve(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500, BlockedBalance: 300}
+	adjustment := newPending(wallet, repository.Blocked, -200)
+	service, repo := setup(adjustment, wallet)
+
+	approved, err := service.Approve(context.Background(), adjustment.ID, "checker")
+	assert.NoError(t, err)
+	assert.Equal(t, repository.Approved, approved.Status)
+	assert.Equal(t, "checker", approved.ReviewedBy)
+	assert.Equal(t, uint64(42), approved.TransactionID)
+	assert.Equal(t, int64(500), wallet.AvailableBalance)
+	assert.Equal(t, int64(100), wallet.BlockedBalance)
+	repo.AssertCalled(t, "Commit")
+
+	// approving twice is refused
+	_, err = service.Approve(context.Background(), adjustment.ID, "checker")
+	assert.ErrorIs(t, err, adjustments.ErrInvalidState)
+}
+
+func TestService_ApproveBySameOperator(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500}
+	adjustment := newPending(wallet, repository.Available, 100)
+	service, repo := setup(adjustment, wallet)
+
+	_, err := service.Approve(context.Background(), adjustment.ID, "maker")
+	assert.ErrorIs(t, err, adjustments.ErrSameOperator)
+	assert.Equal(t, int64(500), wallet.AvailableBalance)
+	repo.AssertNotCalled(t, "Commit")
+}
+
+func TestService_ApproveBreakingPolicy(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 50}
+	adjustment := newPending(wallet, repository.Available, -100)
+	service, repo := setup(adjustment, wallet)
+
+	_, err := service.Approve(context.Background(), adjustment.ID, "checker")
+	assert.ErrorIs(t, err, core.ErrNegativeBalance)
+	repo.AssertNotCalled(t, "Commit")
+}
+
+func TestService_Reject(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR}
+	adjustment := newPending(wallet, repository.Available, 100)
+	service, _ := setup(adjustment, wallet)
+
+	rejected, err := service.Reject(context.Background(), adjustment.ID, "maker")
+	assert.NoError(t, err)
+	assert.Equal(t, repository.Rejected, rejected.Status)
+	assert.Zero(t, rejected.TransactionID)
+	assert.Equal(t, int64(0), wallet.AvailableBalance)
+}
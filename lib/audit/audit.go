@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"time"
+	"wallet/lib/audit/repository"
+)
+
+type WalletDrift = repository.WalletDrift
+type LinkIssue = repository.LinkIssue
+
+// Report is the outcome of one audit run over the wallets of a shard.
+type Report struct {
+	Prefix         string        `json:"prefix"`
+	StartedAt      time.Time     `json:"started_at"`
+	FinishedAt     time.Time     `json:"finished_at"`
+	WalletsChecked int64         `json:"wallets_checked"`
+	Drifts         []WalletDrift `json:"drifts"`
+	LinkIssues     []LinkIssue   `json:"link_issues"`
+}
+
+// Consistent reports whether the audit found nothing wrong.
+func (r *Report) Consistent() bool {
+	return len(r.Drifts) == 0 && len(r.LinkIssues) == 0
+}
+
+type Service interface {
+	// Run audits the wallets whose owner ID starts with prefix ("" audits all of them).
+	Run(ctx context.Context, prefix string) (*Report, error)
+}
+
+type service struct {
+	repoFactory repository.RepoFactory
+}
+
+func New(repoFactory repository.RepoFactory) Service {
+	return &service{
+		repoFactory: repoFactory,
+	}
+}
+
+func (s *service) Run(ctx context.Context, prefix string) (*Report, error) {
+	report := &Report{
+		Prefix:     prefix,
+		StartedAt:  time.Now(),
+		Drifts:     []WalletDrift{},
+		LinkIssues: []LinkIssue{},
+	}
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+
+	var err error
+	if report.WalletsChecked, err = repo.CountWallets(ctx, prefix); err != nil {
+		return nil, err
+	}
+	drifts, err := repo.GetWalletDrifts(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	report.Drifts = append(report.Drifts, drifts...)
+	issues, err := repo.GetLinkIssues(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	report.LinkIssues = append(report.LinkIssues, issues...)
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
@@ -0,0 +1,83 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+	"wallet/lib/audit"
+	"wallet/lib/audit/repository"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// --- Mocks ---
+
+type MockAuditRepo struct{ mock.Mock }
+
+func (m *MockAuditRepo) CountWallets(ctx context.Context, prefix string) (int64, error) {
+	args := m.Called(ctx, prefix)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockAuditRepo) GetWalletDrifts(ctx context.Context, prefix string) ([]repository.WalletDrift, error) {
+	args := m.Called(ctx, prefix)
+	return args.Get(0).([]repository.WalletDrift), args.Error(1)
+}
+func (m *MockAuditRepo) GetLinkIssues(ctx context.Context, prefix string) ([]repository.LinkIssue, error) {
+	args := m.Called(ctx, prefix)
+	return args.Get(0).([]repository.LinkIssue), args.Error(1)
+}
+func (m *MockAuditRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockAuditRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockAuditRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockAuditRepoFactory struct{ mock.Mock }
+
+func (m *MockAuditRepoFactory) New(tx *gorm.DB) repository.Repo {
+	return m.Called(tx).Get(0).(repository.Repo)
+}
+
+// --- Tests ---
+
+func TestService_Run(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuditRepo)
+	factory := new(MockAuditRepoFactory)
+	factory.On("New", (*gorm.DB)(nil)).Return(repo)
+
+	drift := repository.WalletDrift{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 100, TransactionsAvailable: 90}
+	repo.On("CountWallets", ctx, "ab").Return(int64(3), nil)
+	repo.On("GetWalletDrifts", ctx, "ab").Return([]repository.WalletDrift{drift}, nil)
+	repo.On("GetLinkIssues", ctx, "ab").Return([]repository.LinkIssue(nil), nil)
+	repo.On("RollBack").Return(nil)
+
+	report, err := audit.New(factory).Run(ctx, "ab")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), report.WalletsChecked)
+	assert.Equal(t, []repository.WalletDrift{drift}, report.Drifts)
+	assert.Empty(t, report.LinkIssues)
+	assert.False(t, report.Consistent())
+	repo.AssertExpectations(t)
+}
+
+func TestService_Run_Consistent(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuditRepo)
+	factory := new(MockAuditRepoFactory)
+	factory.On("New", (*gorm.DB)(nil)).Return(repo)
+
+	repo.On("CountWallets", ctx, "").Return(int64(0), nil)
+	repo.On("GetWalletDrifts", ctx, "").Return([]repository.WalletDrift(nil), nil)
+	repo.On("GetLinkIssues", ctx, "").Return([]repository.LinkIssue(nil), nil)
+	repo.On("RollBack").Return(nil)
+
+	report, err := audit.New(factory).Run(ctx, "")
+
+	assert.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.NotNil(t, report.Drifts)
+	assert.NotNil(t, report.LinkIssues)
+}
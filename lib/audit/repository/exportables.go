@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"wallet/lib/audit/repository/internal"
+
+	"gorm.io/gorm"
+)
+
+type WalletDrift = internal.WalletDrift
+type LinkIssue = internal.LinkIssue
+type LinkProblem = internal.LinkProblem
+
+const (
+	LinkMissing           = internal.LinkMissing
+	LinkWalletMismatch    = internal.LinkWalletMismatch
+	LinkReferenceMismatch = internal.LinkReferenceMismatch
+	LinkAmountMismatch    = internal.LinkAmountMismatch
+)
+
+type Repo interface {
+	CountWallets(ctx context.Context, prefix string) (int64, error)
+	GetWalletDrifts(ctx context.Context, prefix string) ([]WalletDrift, error)
+	GetLinkIssues(ctx context.Context, prefix string) ([]LinkIssue, error)
+
+	GetDBTransaction() *gorm.DB
+	Commit() error
+	RollBack() error
+}
+
+type RepoFactory interface {
+	New(tx *gorm.DB) Repo
+}
+
+func NewFactory(db *gorm.DB) RepoFactory {
+	return &repoFactory{
+		db: db,
+	}
+}
+
+type repoFactory struct {
+	db *gorm.DB
+}
+
+// New starts a read-only repeatable read transaction when tx is nil, so every
+// check of an audit sees the same snapshot while the wallet keeps running.
+func (rf *repoFactory) New(tx *gorm.DB) Repo {
+	if tx == nil {
+		tx = rf.db.Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	}
+	return internal.NewAuditRepo(tx)
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+)
+
+// WalletDrift is a wallet whose stored balances differ from the sum of its transactions.
+type WalletDrift struct {
+	UserID                uuid.UUID     `json:"user_id"`
+	Currency              core.Currency `json:"currency"`
+	AvailableBalance      int64         `json:"available_balance"`
+	BlockedBalance        int64         `json:"blocked_balance"`
+	TransactionsAvailable int64         `json:"transactions_available"`
+	TransactionsBlocked   int64         `json:"transactions_blocked"`
+}
+
+// LinkProblem tells what is wrong with a transaction linked from a deposit or withdrawal.
+type LinkProblem string
+
+const (
+	LinkMissing           = LinkProblem("missing")            // the transaction does not exist
+	LinkWalletMismatch    = LinkProblem("wallet_mismatch")    // it belongs to another wallet
+	LinkReferenceMismatch = LinkProblem("reference_mismatch") // it references another operation
+	LinkAmountMismatch    = LinkProblem("amount_mismatch")    // its amounts do not match the operation
+)
+
+// LinkIssue is a deposit or withdrawal whose linked transaction is inconsistent with it.
+type LinkIssue struct {
+	Source        string      `json:"source"`
+	SourceID      uuid.UUID   `json:"source_id"`
+	Field         string      `json:"field"`
+	TransactionID uint64      `json:"transaction_id"`
+	Problem       LinkProblem `json:"problem"`
+}
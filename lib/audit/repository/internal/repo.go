@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type auditRepo struct {
+	tx *gorm.DB
+}
+
+func NewAuditRepo(tx *gorm.DB) *auditRepo {
+	return &auditRepo{tx: tx}
+}
+
+// CountWallets counts the wallets whose owner ID starts with prefix.
+func (r *auditRepo) CountWallets(ctx context.Context, prefix string) (int64, error) {
+	var count int64
+	err := r.tx.WithContext(ctx).
+		Table("wallets").
+		Where("user_id::text LIKE ?", prefix+"%").
+		Count(&count).Error
+	return count, err
+}
+
+// GetWalletDrifts returns the wallets, with owner ID starting with prefix, whose
+// balances differ from the sums of their transactions.
+func (r *auditRepo) GetWalletDrifts(ctx context.Context, prefix string) ([]WalletDrift, error) {
+	var drifts []WalletDrift
+	err := r.tx.WithContext(ctx).Raw(`
+		SELECT w.user_id, w.currency, w.available_balance, w.blocked_balance,
+			COALESCE(t.amount, 0) AS transactions_available,
+			COALESCE(t.blocked_amount, 0) AS transactions_blocked
+		FROM wallets w
+		LEFT JOIN (
+			SELECT wallet_id, currency, SUM(amount) AS amount, SUM(blocked_amount) AS blocked_amount
+			FROM transactions
+			WHERE wallet_id::text LIKE @prefix
+			GROUP BY wallet_id, currency
+		) t ON t.wallet_id = w.user_id AND t.currency = w.currency
+		WHERE w.user_id::text LIKE @prefix
+			AND (w.available_balance <> COALESCE(t.amount, 0) OR w.blocked_balance <> COALESCE(t.blocked_amount, 0))
+		ORDER BY w.user_id, w.currency`,
+		map[string]any{"prefix": prefix + "%"},
+	).Scan(&drifts).Error
+	return drifts, err
+}
+
+// link describes a transaction ID column of an operation table and what the
+// linked transaction must look like. Expressions are over the operation row o,
+// reference defaults to o.id.
+type link struct {
+	source    string
+	table     string
+	owner     string
+	field     string
+	amount    string
+	blocked   string
+	reference string
+	required  bool
+}
+
+// appliedLegs is the part of a deposit already released by its legs.
+const appliedLegs = "(SELECT COALESCE(SUM(l.amount), 0) FROM deposit_legs l WHERE l.deposit_id = o.id AND l.status = 'applied')"
+
+var links = []link{
+	{source: "deposit", table: "deposits", owner: "user_id", field: "block_transaction_id", amount: "0", blocked: "o.amount", required: true},
+	{source: "deposit", table: "deposits", owner: "user_id", field: "apply_transaction_id", amount: "o.amount", blocked: "-o.amount"},
+	{source: "deposit", table: "deposits", owner: "user_id", field: "cancel_transaction_id", amount: "0", blocked: appliedLegs + " - o.amount"},
+	{source: "deposit_leg", table: "deposit_legs", owner: "user_id", field: "apply_transaction_id", amount: "o.amount", blocked: "-o.amount", reference: "o.deposit_id"},
+	{source: "withdrawal", table: "withdrawals", owner: "wallet_id", field: "block_transaction_id", amount: "-(o.amount + o.fee_total)", blocked: "o.amount + o.fee_total", required: true},
+	{source: "withdrawal", table: "withdrawals", owner: "wallet_id", field: "withdrawal_transaction_id", amount: "0", blocked: "-o.amount"},
+	{source: "withdrawal", table: "withdrawals", owner: "wallet_id", field: "fee_transaction_id", amount: "0", blocked: "-o.fee_total"},
+	{source: "withdrawal", table: "withdrawals", owner: "wallet_id", field: "reverser_transaction_id", amount: "o.amount + o.fee_total", blocked: "-(o.amount + o.fee_total)"},
+	{source: "hold", table: "holds", owner: "user_id", field: "block_transaction_id", amount: "-o.amount", blocked: "o.amount", required: true},
+	{source: "hold", table: "holds", owner: "user_id", field: "capture_transaction_id", amount: "o.amount - o.captured_amount", blocked: "-o.amount"},
+	{source: "hold", table: "holds", owner: "user_id", field: "release_transaction_id", amount: "o.amount", blocked: "-o.amount"},
+}
+
+// GetLinkIssues checks the transactions linked from deposits, deposit legs,
+// withdrawals and holds of wallets with owner ID starting with prefix.
+func (r *auditRepo) GetLinkIssues(ctx context.Context, prefix string) ([]LinkIssue, error) {
+	var issues []LinkIssue
+	for _, l := range links {
+		var found []LinkIssue
+		linked := fmt.Sprintf("COALESCE(o.%s, 0)", l.field)
+		filter := linked + " <> 0"
+		if l.required {
+			filter = "TRUE"
+		}
+		reference := l.reference
+		if reference == "" {
+			reference = "o.id"
+		}
+		query := fmt.Sprintf(`
+			SELECT * FROM (
+				SELECT @source AS source, o.id AS source_id, @field AS field, %[1]s AS transaction_id,
+					CASE
+						WHEN t.id IS NULL THEN @missing
+						WHEN t.wallet_id <> o.%[2]s OR t.currency <> o.currency THEN @wallet_mismatch
+						WHEN t.reference <> %[7]s THEN @reference_mismatch
+						WHEN t.amount <> %[3]s OR t.blocked_amount <> %[4]s THEN @amount_mismatch
+					END AS problem
+				FROM %[5]s o
+				LEFT JOIN transactions t ON t.id = %[1]s
+				WHERE o.%[2]s::text LIKE @prefix AND %[6]s
+			) checked
+			WHERE problem IS NOT NULL
+			ORDER BY source_id`,
+			linked, l.owner, l.amount, l.blocked, l.table, filter, reference)
+		err := r.tx.WithContext(ctx).Raw(query, map[string]any{
+			"source":             l.source,
+			"field":              l.field,
+			"prefix":             prefix + "%",
+			"missing":            LinkMissing,
+			"wallet_mismatch":    LinkWalletMismatch,
+			"reference_mismatch": LinkReferenceMismatch,
+			"amount_mismatch":    LinkAmountMismatch,
+		}).Scan(&found).Error
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+	return issues, nil
+}
+
+// GetDBTransaction returns the underlying gorm.DB (transaction).
+func (r *auditRepo) GetDBTransaction() *gorm.DB {
+	return r.tx
+}
+
+// Commit commits the transaction.
+func (r *auditRepo) Commit() error {
+	return r.tx.Commit().Error
+}
+
+// RollBack rolls back the transaction.
+func (r *auditRepo) RollBack() error {
+	return r.tx.Rollback().Error
+}
@@ -0,0 +1,13 @@
+package beneficiaries
+
+import (
+	"errors"
+	"wallet/lib/iban"
+)
+
+var ErrInvalidIban = iban.ErrInvalid
+var ErrDuplicate = errors.New("the iban is already saved as a beneficiary")
+var ErrNotFound = errors.New("beneficiary not found")
+var ErrCoolingOff = errors.New("beneficiary can not receive funds yet")
+var ErrNotSaved = errors.New("the iban is not a saved beneficiary")
+var ErrNotVerified = errors.New("beneficiary is not verified")
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"wallet/lib/beneficiaries/repository/internal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Beneficiary = internal.Beneficiary
+
+type Repo interface {
+	Create(context.Context, *Beneficiary) error
+	Update(context.Context, *Beneficiary) error
+	Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+	Get(ctx context.Context, id uuid.UUID) (*Beneficiary, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*Beneficiary, error)
+	GetByIban(ctx context.Context, userID uuid.UUID, iban string) (*Beneficiary, error)
+	List(ctx context.Context, userID uuid.UUID) ([]Beneficiary, error)
+
+	GetDBTransaction() *gorm.DB
+	Commit() error
+	RollBack() error
+}
+
+type RepoFactory interface {
+	New(tx *gorm.DB) Repo
+}
+
+func NewFactory(db *gorm.DB) RepoFactory {
+	return &repoFactory{
+		db: db,
+	}
+}
+
+type repoFactory struct {
+	db *gorm.DB
+}
+
+func (rf *repoFactory) New(tx *gorm.DB) Repo {
+	if tx == nil {
+		tx = rf.db.Begin()
+	}
+	return internal.NewBeneficiaryRepo(tx)
+}
@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Beneficiary is a payout destination saved by a wallet owner. Its IBAN never
+// changes, so a new destination always goes through the cooling-off period.
+type Beneficiary struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Iban        string     `gorm:"type:varchar(34);not null" json:"iban"`
+	BankCode    string     `gorm:"type:varchar(16)" json:"bank_code,omitempty"` // from the iban
+	HolderName  string     `gorm:"type:varchar(128)" json:"holder_name"`
+	Label       string     `gorm:"type:varchar(64)" json:"label"`
+	Verified    bool       `gorm:"not null;default:false" json:"verified"` // the holder was confirmed by an operator
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	VerifiedBy  string     `gorm:"type:varchar(64)" json:"verified_by,omitempty"` // the operator
+	AvailableAt time.Time  `gorm:"not null" json:"available_at"`                  // when withdrawals to it are allowed
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type beneficiaryRepo struct {
+	tx *gorm.DB
+}
+
+func NewBeneficiaryRepo(tx *gorm.DB) *beneficiaryRepo {
+	return &beneficiaryRepo{tx: tx}
+}
+
+// Create inserts a new beneficiary and fills in ID automatically. It returns
+// gorm.ErrDuplicatedKey if the user already saved the IBAN.
+func (r *beneficiaryRepo) Create(ctx context.Context, beneficiary *Beneficiary) error {
+	if beneficiary.ID == uuid.Nil {
+		beneficiary.ID = uuid.New()
+	}
+	result := r.tx.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(beneficiary)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+// Update updates an existing beneficiary by ID.
+func (r *beneficiaryRepo) Update(ctx context.Context, beneficiary *Beneficiary) error {
+	return r.tx.WithContext(ctx).Save(beneficiary).Error
+}
+
+// Delete removes a beneficiary of a user by ID.
+func (r *beneficiaryRepo) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	result := r.tx.WithContext(ctx).Delete(&Beneficiary{}, "id = ? AND user_id = ?", id, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Get fetches a beneficiary by ID.
+func (r *beneficiaryRepo) Get(ctx context.Context, id uuid.UUID) (*Beneficiary, error) {
+	var beneficiary Beneficiary
+	if err := r.tx.WithContext(ctx).First(&beneficiary, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &beneficiary, nil
+}
+
+// GetForUpdate fetches a beneficiary by ID with row-level locking.
+func (r *beneficiaryRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*Beneficiary, error) {
+	var beneficiary Beneficiary
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&beneficiary, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &beneficiary, nil
+}
+
+// GetByIban fetches the beneficiary a user saved with an IBAN in electronic format.
+func (r *beneficiaryRepo) GetByIban(ctx context.Context, userID uuid.UUID, iban string) (*Beneficiary, error) {
+	var beneficiary Beneficiary
+	if err := r.tx.WithContext(ctx).First(&beneficiary, "user_id = ? AND iban = ?", userID, iban).Error; err != nil {
+		return nil, err
+	}
+	return &beneficiary, nil
+}
+
+// List returns the beneficiaries of a user, oldest first.
+func (r *beneficiaryRepo) List(ctx context.Context, userID uuid.UUID) ([]Beneficiary, error) {
+	var beneficiaries []Beneficiary
+	if err := r.tx.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at, id").
+		Find(&beneficiaries).Error; err != nil {
+		return nil, err
+	}
+	return beneficiaries, nil
+}
+
+// GetDBTransaction returns the underlying gorm.DB (transaction).
+func (r *beneficiaryRepo) GetDBTransaction() *gorm.DB {
+	return r.tx
+}
+
+// Commit commits the transaction.
+func (r *beneficiaryRepo) Commit() error {
+	return r.tx.Commit().Error
+}
+
+// RollBack rolls back the transaction.
+func (r *beneficiaryRepo) RollBack() error {
+	return r.tx.Rollback().Error
+}
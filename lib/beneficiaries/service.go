@@ -0,0 +1,218 @@
+package beneficiaries
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wallet/lib/beneficiaries/repository"
+	"wallet/lib/iban"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Beneficiary = repository.Beneficiary
+
+type Config struct {
+	CoolingOff      time.Duration // before a new beneficiary can receive withdrawals, none when zero
+	RequireVerified bool          // only beneficiaries verified by an operator receive withdrawals
+}
+
+type Service interface {
+	// Create saves a payout destination of a user. It can receive
+	// withdrawals once the cooling-off period passed.
+	Create(context.Context, *Beneficiary) error
+	// Update changes the holder name and label of a beneficiary of userID
+	// where they are given. A new holder name needs to be verified again.
+	Update(ctx context.Context, id uuid.UUID, userID uuid.UUID, holderName *string, label *string) (*Beneficiary, error)
+	// Delete, Get and Update find the beneficiaries of userID only; those of
+	// other users get ErrNotFound.
+	Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+	Get(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*Beneficiary, error)
+	List(ctx context.Context, userID uuid.UUID) ([]Beneficiary, error)
+	// Verify marks the holder of a beneficiary as confirmed by actor.
+	Verify(ctx context.Context, id uuid.UUID, actor string) (*Beneficiary, error)
+	// Resolve returns a beneficiary of userID that can receive a withdrawal
+	// now, or ErrCoolingOff while it is in the cooling-off period and
+	// ErrNotVerified while it needs to be verified.
+	Resolve(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*Beneficiary, error)
+	// ResolveIban is Resolve for the beneficiary of userID saved with iban,
+	// in electronic format. While there is a cooling-off period or
+	// verification is required an iban that is not saved gets ErrNotSaved,
+	// so it can not be used to skip them; otherwise it gets nil.
+	ResolveIban(ctx context.Context, userID uuid.UUID, iban string) (*Beneficiary, error)
+}
+
+func New(repoFactory repository.RepoFactory, config Config) Service {
+	return &service{
+		repoFactory: repoFactory,
+		config:      config,
+	}
+}
+
+type service struct {
+	repoFactory repository.RepoFactory
+	config      Config
+}
+
+func (s *service) Create(ctx context.Context, beneficiary *Beneficiary) error {
+	account, err := iban.Parse(beneficiary.Iban)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	beneficiary.Iban = account.String()
+	beneficiary.BankCode = account.BankCode()
+	beneficiary.Verified = false
+	beneficiary.VerifiedAt = nil
+	beneficiary.VerifiedBy = ""
+	beneficiary.AvailableAt = now.Add(s.config.CoolingOff)
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	err = repo.Create(ctx, beneficiary)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	return repo.Commit()
+}
+
+func (s *service) Update(ctx context.Context, id uuid.UUID, userID uuid.UUID, holderName *string, label *string) (*Beneficiary, error) {
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	beneficiary, err := getForUpdate(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	if beneficiary.UserID != userID {
+		return nil, ErrNotFound
+	}
+	if holderName != nil && *holderName != beneficiary.HolderName {
+		beneficiary.HolderName = *holderName
+		beneficiary.Verified = false
+		beneficiary.VerifiedAt = nil
+		beneficiary.VerifiedBy = ""
+	}
+	if label != nil {
+		beneficiary.Label = *label
+	}
+	if err := repo.Update(ctx, beneficiary); err != nil {
+		return nil, err
+	}
+	return beneficiary, repo.Commit()
+}
+
+func (s *service) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	err := repo.Delete(ctx, id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return repo.Commit()
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*Beneficiary, error) {
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	beneficiary, err := repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the beneficiaries of other users do not exist for this one
+	if beneficiary.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return beneficiary, nil
+}
+
+func (s *service) List(ctx context.Context, userID uuid.UUID) ([]Beneficiary, error) {
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	return repo.List(ctx, userID)
+}
+
+func (s *service) Verify(ctx context.Context, id uuid.UUID, actor string) (*Beneficiary, error) {
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	beneficiary, err := getForUpdate(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	if beneficiary.Verified {
+		return beneficiary, nil
+	}
+	now := time.Now()
+	beneficiary.Verified = true
+	beneficiary.VerifiedAt = &now
+	beneficiary.VerifiedBy = actor
+	if err := repo.Update(ctx, beneficiary); err != nil {
+		return nil, err
+	}
+	return beneficiary, repo.Commit()
+}
+
+func (s *service) Resolve(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*Beneficiary, error) {
+	beneficiary, err := s.Get(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.available(beneficiary)
+}
+
+func (s *service) ResolveIban(ctx context.Context, userID uuid.UUID, iban string) (*Beneficiary, error) {
+	repo := s.repoFactory.New(nil)
+	defer func() {
+		_ = repo.RollBack()
+	}()
+	beneficiary, err := repo.GetByIban(ctx, userID, iban)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if s.config.CoolingOff != 0 || s.config.RequireVerified {
+			return nil, ErrNotSaved
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.available(beneficiary)
+}
+
+// available returns beneficiary if it can receive withdrawals now.
+func (s *service) available(beneficiary *Beneficiary) (*Beneficiary, error) {
+	if time.Now().Before(beneficiary.AvailableAt) {
+		return nil, ErrCoolingOff
+	}
+	if s.config.RequireVerified && !beneficiary.Verified {
+		return nil, ErrNotVerified
+	}
+	return beneficiary, nil
+}
+
+func getForUpdate(ctx context.Context, repo repository.Repo, id uuid.UUID) (*Beneficiary, error) {
+	beneficiary, err := repo.GetForUpdate(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return beneficiary, err
+}
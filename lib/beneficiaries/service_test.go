@@ -0,0 +1,264 @@
+package beneficiaries_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/beneficiaries"
+	"wallet/lib/beneficiaries/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// --- Mocks ---
+
+type MockBeneficiaryRepo struct {
+	mock.Mock
+	beneficiary *repository.Beneficiary
+}
+
+func (m *MockBeneficiaryRepo) Create(ctx context.Context, b *repository.Beneficiary) error {
+	b.ID = uuid.New()
+	m.beneficiary = b
+	return m.Called(ctx, b).Error(0)
+}
+func (m *MockBeneficiaryRepo) Update(ctx context.Context, b *repository.Beneficiary) error {
+	return m.Called(ctx, b).Error(0)
+}
+func (m *MockBeneficiaryRepo) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	return m.Called(ctx, id, userID).Error(0)
+}
+func (m *MockBeneficiaryRepo) Get(ctx context.Context, id uuid.UUID) (*repository.Beneficiary, error) {
+	found := *m.beneficiary
+	return &found, m.Called(ctx, id).Error(0)
+}
+func (m *MockBeneficiaryRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*repository.Beneficiary, error) {
+	// the lock returns a fresh copy of the row, like the database would
+	locked := *m.beneficiary
+	return &locked, m.Called(ctx, id).Error(0)
+}
+func (m *MockBeneficiaryRepo) GetByIban(ctx context.Context, userID uuid.UUID, iban string) (*repository.Beneficiary, error) {
+	if err := m.Called(ctx, userID, iban).Error(0); err != nil {
+		return nil, err
+	}
+	found := *m.beneficiary
+	return &found, nil
+}
+func (m *MockBeneficiaryRepo) List(ctx context.Context, userID uuid.UUID) ([]repository.Beneficiary, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]repository.Beneficiary), args.Error(1)
+}
+func (m *MockBeneficiaryRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockBeneficiaryRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockBeneficiaryRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockBeneficiaryRepoFactory struct{ mock.Mock }
+
+func (m *MockBeneficiaryRepoFactory) New(tx *gorm.DB) repository.Repo {
+	return m.Called(tx).Get(0).(repository.Repo)
+}
+
+// --- Helpers ---
+
+const testIban = "IR160120000000001234567890"
+
+func setup(coolingOff time.Duration) (beneficiaries.Service, *MockBeneficiaryRepo) {
+	repo := &MockBeneficiaryRepo{}
+	repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	repo.On("Get", mock.Anything, mock.Anything).Return(nil)
+	repo.On("GetForUpdate", mock.Anything, mock.Anything).Return(nil)
+	repo.On("Commit").Return(nil)
+	repo.On("RollBack").Return(nil)
+	factory := &MockBeneficiaryRepoFactory{}
+	factory.On("New", mock.Anything).Return(repo)
+	return beneficiaries.New(factory, beneficiaries.Config{CoolingOff: coolingOff}), repo
+}
+
+// --- Tests ---
+
+func TestService_Create(t *testing.T) {
+	service, repo := setup(24 * time.Hour)
+	beneficiary := &beneficiaries.Beneficiary{
+		UserID:     uuid.New(),
+		Iban:       "ir16 0120 0000 0000 1234 5678 90",
+		HolderName: "Sara Ahmadi",
+		Verified:   true, // only an operator verifies
+	}
+
+	err := service.Create(context.Background(), beneficiary)
+	assert.NoError(t, err)
+	assert.Equal(t, testIban, beneficiary.Iban)
+	assert.Equal(t, "012", beneficiary.BankCode)
+	assert.False(t, beneficiary.Verified)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), beneficiary.AvailableAt, time.Minute)
+	repo.AssertCalled(t, "Commit")
+}
+
+func TestService_CreateInvalid(t *testing.T) {
+	service, repo := setup(0)
+	err := service.Create(context.Background(), &beneficiaries.Beneficiary{UserID: uuid.New(), Iban: "IR120120000000001234567890"})
+	assert.ErrorIs(t, err, beneficiaries.ErrInvalidIban)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	repo = &MockBeneficiaryRepo{}
+	repo.On("Create", mock.Anything, mock.Anything).Return(gorm.ErrDuplicatedKey)
+	repo.On("RollBack").Return(nil)
+	factory := &MockBeneficiaryRepoFactory{}
+	factory.On("New", mock.Anything).Return(repo)
+	service = beneficiaries.New(factory, beneficiaries.Config{})
+	err = service.Create(context.Background(), &beneficiaries.Beneficiary{UserID: uuid.New(), Iban: testIban})
+	assert.ErrorIs(t, err, beneficiaries.ErrDuplicate)
+	repo.AssertNotCalled(t, "Commit")
+}
+
+func TestService_UpdateResetsVerification(t *testing.T) {
+	userID := uuid.New()
+	service, repo := setup(0)
+	assert.NoError(t, service.Create(context.Background(), &beneficiaries.Beneficiary{UserID: userID, Iban: testIban, HolderName: "Sara Ahmadi"}))
+	verified, err := service.Verify(context.Background(), repo.beneficiary.ID, "ops-alice")
+	assert.NoError(t, err)
+	assert.True(t, verified.Verified)
+	assert.NotNil(t, verified.VerifiedAt)
+	assert.Equal(t, "ops-alice", verified.VerifiedBy)
+	repo.beneficiary = verified
+
+	// a new label keeps the verification
+	label := "savings"
+	updated, err := service.Update(context.Background(), verified.ID, userID, nil, &label)
+	assert.NoError(t, err)
+	assert.Equal(t, "savings", updated.Label)
+	assert.True(t, updated.Verified)
+	repo.beneficiary = updated
+
+	// a new holder does not
+	holder := "Reza Karimi"
+	updated, err = service.Update(context.Background(), verified.ID, userID, &holder, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Reza Karimi", updated.HolderName)
+	assert.Equal(t, "savings", updated.Label)
+	assert.False(t, updated.Verified)
+	assert.Nil(t, updated.VerifiedAt)
+	assert.Empty(t, updated.VerifiedBy)
+}
+
+func TestService_OtherUsers(t *testing.T) {
+	owner := uuid.New()
+	service, repo := setup(0)
+	assert.NoError(t, service.Create(context.Background(), &beneficiaries.Beneficiary{UserID: owner, Iban: testIban, HolderName: "Sara Ahmadi"}))
+	id := repo.beneficiary.ID
+
+	beneficiary, err := service.Get(context.Background(), id, owner)
+	assert.NoError(t, err)
+	assert.Equal(t, id, beneficiary.ID)
+
+	// the beneficiaries of other users can not be read or changed
+	_, err = service.Get(context.Background(), id, uuid.New())
+	assert.ErrorIs(t, err, beneficiaries.ErrNotFound)
+	holder := "Reza Karimi"
+	_, err = service.Update(context.Background(), id, uuid.New(), &holder, nil)
+	assert.ErrorIs(t, err, beneficiaries.ErrNotFound)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestService_RequireVerified(t *testing.T) {
+	userID := uuid.New()
+	repo := &MockBeneficiaryRepo{}
+	repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	repo.On("Get", mock.Anything, mock.Anything).Return(nil)
+	repo.On("GetForUpdate", mock.Anything, mock.Anything).Return(nil)
+	repo.On("GetByIban", mock.Anything, userID, testIban).Return(nil)
+	repo.On("GetByIban", mock.Anything, mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+	repo.On("Commit").Return(nil)
+	repo.On("RollBack").Return(nil)
+	factory := &MockBeneficiaryRepoFactory{}
+	factory.On("New", mock.Anything).Return(repo)
+	service := beneficiaries.New(factory, beneficiaries.Config{RequireVerified: true})
+	assert.NoError(t, service.Create(context.Background(), &beneficiaries.Beneficiary{UserID: userID, Iban: testIban}))
+	id := repo.beneficiary.ID
+
+	_, err := service.Resolve(context.Background(), id, userID)
+	assert.ErrorIs(t, err, beneficiaries.ErrNotVerified)
+	_, err = service.ResolveIban(context.Background(), userID, testIban)
+	assert.ErrorIs(t, err, beneficiaries.ErrNotVerified)
+	// an iban that was never saved can not be verified either
+	_, err = service.ResolveIban(context.Background(), userID, "IR062960000000100324200001")
+	assert.ErrorIs(t, err, beneficiaries.ErrNotSaved)
+
+	verified, err := service.Verify(context.Background(), id, "ops-alice")
+	assert.NoError(t, err)
+	repo.beneficiary = verified
+	beneficiary, err := service.Resolve(context.Background(), id, userID)
+	assert.NoError(t, err)
+	assert.True(t, beneficiary.Verified)
+}
+
+func TestService_Resolve(t *testing.T) {
+	userID := uuid.New()
+	service, repo := setup(time.Hour)
+	assert.NoError(t, service.Create(context.Background(), &beneficiaries.Beneficiary{UserID: userID, Iban: testIban}))
+	id := repo.beneficiary.ID
+
+	_, err := service.Resolve(context.Background(), id, userID)
+	assert.ErrorIs(t, err, beneficiaries.ErrCoolingOff)
+
+	repo.beneficiary.AvailableAt = time.Now().Add(-time.Second)
+	beneficiary, err := service.Resolve(context.Background(), id, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, testIban, beneficiary.Iban)
+
+	// another user's beneficiary
+	_, err = service.Resolve(context.Background(), id, uuid.New())
+	assert.ErrorIs(t, err, beneficiaries.ErrNotFound)
+}
+
+func TestService_ResolveIban(t *testing.T) {
+	userID := uuid.New()
+	service, repo := setup(time.Hour)
+	assert.NoError(t, service.Create(context.Background(), &beneficiaries.Beneficiary{UserID: userID, Iban: testIban}))
+	repo.On("GetByIban", mock.Anything, userID, testIban).Return(nil)
+	repo.On("GetByIban", mock.Anything, mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+
+	// typing the iban in does not skip the cooling-off period
+	_, err := service.ResolveIban(context.Background(), userID, testIban)
+	assert.ErrorIs(t, err, beneficiaries.ErrCoolingOff)
+	repo.beneficiary.AvailableAt = time.Now().Add(-time.Second)
+	beneficiary, err := service.ResolveIban(context.Background(), userID, testIban)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.beneficiary.ID, beneficiary.ID)
+
+	// nor does an iban that was never saved
+	_, err = service.ResolveIban(context.Background(), userID, "IR062960000000100324200001")
+	assert.ErrorIs(t, err, beneficiaries.ErrNotSaved)
+	_, err = service.ResolveIban(context.Background(), uuid.New(), testIban)
+	assert.ErrorIs(t, err, beneficiaries.ErrNotSaved)
+
+	// which is fine without a cooling-off period
+	service, repo = setup(0)
+	repo.On("GetByIban", mock.Anything, mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+	beneficiary, err = service.ResolveIban(context.Background(), userID, testIban)
+	assert.NoError(t, err)
+	assert.Nil(t, beneficiary)
+}
+
+func TestService_NotFound(t *testing.T) {
+	repo := &MockBeneficiaryRepo{beneficiary: &repository.Beneficiary{}}
+	repo.On("Get", mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+	repo.On("GetForUpdate", mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+	repo.On("Delete", mock.Anything, mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+	repo.On("RollBack").Return(nil)
+	factory := &MockBeneficiaryRepoFactory{}
+	factory.On("New", mock.Anything).Return(repo)
+	service := beneficiaries.New(factory, beneficiaries.Config{})
+
+	_, err := service.Get(context.Background(), uuid.New(), uuid.New())
+	assert.ErrorIs(t, err, beneficiaries.ErrNotFound)
+	_, err = service.Verify(context.Background(), uuid.New(), "ops-alice")
+	assert.ErrorIs(t, err, beneficiaries.ErrNotFound)
+	err = service.Delete(context.Background(), uuid.New(), uuid.New())
+	assert.ErrorIs(t, err, beneficiaries.ErrNotFound)
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/spf13/viper"
 )
 
-func Load(configGroup any) { load("", configGroup) }
+func Load(configGroup any) {
+	readConfigFile()
+	load("", configGroup)
+}
 
 func load(prefix string, configGroup any) {
 	v := reflect.ValueOf(configGroup)
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"sync"
 
 	"github.com/spf13/viper"
 )
@@ -28,7 +29,6 @@ var ConfigFile string
 
 func init() {
 	initLogDir()
-	initConfigFile()
 }
 
 func initLogDir() {
@@ -39,11 +39,17 @@ func initLogDir() {
 	}
 }
 
-func initConfigFile() {
-	ConfigFile = os.Getenv("CONFIG_PATH")
-	if ConfigFile == "" {
-		panic("no config file")
-	}
-	viper.SetConfigFile(ConfigFile)
-	viper.ReadInConfig()
+var readConfigFileOnce sync.Once
+
+// readConfigFile reads the file at CONFIG_PATH into viper the first time a
+// config group is loaded, so importing the package does not need one.
+func readConfigFile() {
+	readConfigFileOnce.Do(func() {
+		ConfigFile = os.Getenv("CONFIG_PATH")
+		if ConfigFile == "" {
+			panic("no config file")
+		}
+		viper.SetConfigFile(ConfigFile)
+		viper.ReadInConfig()
+	})
 }
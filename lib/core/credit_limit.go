@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCreditLimit = errors.New("credit limit must not be negative")
+
+// ErrMissingActor is returned by operator actions without an actor or a reason.
+var ErrMissingActor = errors.New("admin changes need an actor and a reason")
+
+// SetCreditLimit sets the credit limit of a wallet under its row lock and
+// records the change with who made it and why. Lowering the limit below what
+// the wallet already uses fails with ErrNegativeBalance.
+func SetCreditLimit(ctx context.Context, repo Repo, userID uuid.UUID, currency Currency, limit int64, actor, reason string) (*Wallet, error) {
+	if limit < 0 {
+		return nil, ErrInvalidCreditLimit
+	}
+	if actor == "" || reason == "" {
+		return nil, ErrMissingActor
+	}
+	wallet, err := repo.Wallet().GetOrCreateForUpdate(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	change := &CreditLimitChange{
+		UserID:   userID,
+		Currency: currency,
+		OldLimit: wallet.CreditLimit,
+		NewLimit: limit,
+		Actor:    actor,
+		Reason:   reason,
+	}
+	wallet.CreditLimit = limit
+	if err := repo.Wallet().UpdateCreditLimit(ctx, wallet); err != nil {
+		return nil, err
+	}
+	if err := repo.CreditLimitChange().Create(ctx, change); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
@@ -0,0 +1,17 @@
+package core
+
+import "wallet/lib/core/internal"
+
+const (
+	IRR = internal.IRR
+	USD = internal.USD
+	EUR = internal.EUR
+	AED = internal.AED
+)
+
+// DefaultCurrency is used when a client does not specify a currency.
+const DefaultCurrency = IRR
+
+var ErrUnsupportedCurrency = internal.ErrUnsupportedCurrency
+
+var ParseCurrency = internal.ParseCurrency
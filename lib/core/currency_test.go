@@ -0,0 +1,24 @@
+package core_test
+
+import (
+	"testing"
+	"wallet/lib/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCurrency(t *testing.T) {
+	currency, err := core.ParseCurrency(" usd")
+	assert.NoError(t, err)
+	assert.Equal(t, core.USD, currency)
+
+	_, err = core.ParseCurrency("XYZ")
+	assert.ErrorIs(t, err, core.ErrUnsupportedCurrency)
+}
+
+func TestCurrency_FormatAmount(t *testing.T) {
+	assert.Equal(t, "12.34", core.USD.FormatAmount(1234))
+	assert.Equal(t, "0.05", core.USD.FormatAmount(5))
+	assert.Equal(t, "-1.00", core.EUR.FormatAmount(-100))
+	assert.Equal(t, "1500", core.IRR.FormatAmount(1500))
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ExportFormat is the file format of a transaction export.
+type ExportFormat string
+
+const (
+	ExportCSV   = ExportFormat("csv")
+	ExportJSONL = ExportFormat("jsonl")
+)
+
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+// exportBatchSize is the number of transactions held in memory while exporting.
+const exportBatchSize = 500
+
+// exportColumns is the column order of CSV exports and the field order of JSON Lines exports.
+var exportColumns = []string{"id", "created_at", "kind", "currency", "amount", "blocked_amount", "reference", "description"}
+
+func ParseExportFormat(format string) (ExportFormat, error) {
+	switch ExportFormat(format) {
+	case ExportCSV:
+		return ExportCSV, nil
+	case ExportJSONL:
+		return ExportJSONL, nil
+	}
+	return "", ErrUnknownExportFormat
+}
+
+// ContentType is the MIME type of the format.
+func (f ExportFormat) ContentType() string {
+	if f == ExportJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// exportRecord is one exported transaction. Amounts are in major units.
+type exportRecord struct {
+	ID            string `json:"id"`
+	CreatedAt     string `json:"created_at"`
+	Kind          string `json:"kind"`
+	Currency      string `json:"currency"`
+	Amount        string `json:"amount"`
+	BlockedAmount string `json:"blocked_amount"`
+	Reference     string `json:"reference"`
+	Description   string `json:"description"`
+}
+
+func newExportRecord(trx *Transaction) exportRecord {
+	return exportRecord{
+		ID:            strconv.FormatUint(trx.ID, 10),
+		CreatedAt:     trx.CreatedAt.UTC().Format(time.RFC3339),
+		Kind:          string(trx.Kind),
+		Currency:      string(trx.Currency),
+		Amount:        trx.Currency.FormatAmount(trx.Amount),
+		BlockedAmount: trx.Currency.FormatAmount(trx.BlockedAmount),
+		Reference:     trx.Reference.String(),
+		Description:   trx.Description,
+	}
+}
+
+func (r exportRecord) row() []string {
+	return []string{r.ID, r.CreatedAt, r.Kind, r.Currency, r.Amount, r.BlockedAmount, r.Reference, r.Description}
+}
+
+// ExportTransactions writes the transactions matching filter to w, newest
+// first, reading them in batches so the history is never loaded at once.
+// w is flushed after every batch when it implements Flush().
+func ExportTransactions(ctx context.Context, repo Repo, filter TransactionFilter, format ExportFormat, w io.Writer) error {
+	switch format {
+	case ExportCSV:
+		csvWriter := csv.NewWriter(w)
+		if err := csvWriter.Write(exportColumns); err != nil {
+			return err
+		}
+		write := func(record exportRecord) error {
+			return csvWriter.Write(record.row())
+		}
+		return exportBatches(ctx, repo, filter, write, func() error {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+			flush(w)
+			return nil
+		})
+	case ExportJSONL:
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		write := func(record exportRecord) error {
+			return encoder.Encode(record)
+		}
+		return exportBatches(ctx, repo, filter, write, func() error {
+			if err := buffered.Flush(); err != nil {
+				return err
+			}
+			flush(w)
+			return nil
+		})
+	}
+	return ErrUnknownExportFormat
+}
+
+func exportBatches(ctx context.Context, repo Repo, filter TransactionFilter, write func(exportRecord) error, flushBatch func() error) error {
+	var cursor uint64
+	for {
+		transactions, nextCursor, err := repo.Transaction().List(ctx, filter, cursor, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range transactions {
+			if err := write(newExportRecord(&transactions[i])); err != nil {
+				return err
+			}
+		}
+		if err := flushBatch(); err != nil {
+			return err
+		}
+		if nextCursor == 0 {
+			return nil
+		}
+		cursor = nextCursor
+	}
+}
+
+// flush pushes written data to the client when w is a streaming response.
+func flush(w io.Writer) {
+	if flusher, ok := w.(interface{ Flush() }); ok {
+		flusher.Flush()
+	}
+}
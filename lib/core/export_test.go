@@ -0,0 +1,71 @@
+package core_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedTransactionRepo serves its transactions two per page, like a keyset List.
+type pagedTransactionRepo struct {
+	core.TransactionRepo
+	transactions []core.Transaction
+}
+
+func (r *pagedTransactionRepo) List(ctx context.Context, filter core.TransactionFilter, cursor uint64, limit int) ([]core.Transaction, uint64, error) {
+	start := int(cursor)
+	end := min(start+2, len(r.transactions))
+	if end == len(r.transactions) {
+		return r.transactions[start:end], 0, nil
+	}
+	return r.transactions[start:end], uint64(end), nil
+}
+
+type exportRepo struct {
+	core.Repo
+	transactions *pagedTransactionRepo
+}
+
+func (r *exportRepo) Transaction() core.TransactionRepo {
+	return r.transactions
+}
+
+func newExportRepo() *exportRepo {
+	reference := uuid.MustParse("7d3c1f0e-1111-4a4a-9b9b-000000000001")
+	createdAt := time.Date(2025, 9, 1, 12, 30, 0, 0, time.FixedZone("IRST", 12600))
+	return &exportRepo{transactions: &pagedTransactionRepo{transactions: []core.Transaction{
+		{ID: 3, Currency: core.USD, Kind: core.KindApply, Amount: 1234, BlockedAmount: -1234, Reference: reference, CreatedAt: createdAt},
+		{ID: 2, Currency: core.USD, Kind: core.KindBlock, BlockedAmount: 1234, Reference: reference, Description: "top up, card", CreatedAt: createdAt},
+		{ID: 1, Currency: core.USD, Kind: core.KindWithdraw, BlockedAmount: -5, Reference: reference, CreatedAt: createdAt},
+	}}}
+}
+
+func TestExportTransactions_CSV(t *testing.T) {
+	var out bytes.Buffer
+	err := core.ExportTransactions(context.Background(), newExportRepo(), core.TransactionFilter{}, core.ExportCSV, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,created_at,kind,currency,amount,blocked_amount,reference,description\n"+
+		"3,2025-09-01T09:00:00Z,apply,USD,12.34,-12.34,7d3c1f0e-1111-4a4a-9b9b-000000000001,\n"+
+		"2,2025-09-01T09:00:00Z,block,USD,0.00,12.34,7d3c1f0e-1111-4a4a-9b9b-000000000001,\"top up, card\"\n"+
+		"1,2025-09-01T09:00:00Z,withdraw,USD,0.00,-0.05,7d3c1f0e-1111-4a4a-9b9b-000000000001,\n",
+		out.String())
+}
+
+func TestExportTransactions_JSONL(t *testing.T) {
+	var out bytes.Buffer
+	err := core.ExportTransactions(context.Background(), newExportRepo(), core.TransactionFilter{}, core.ExportJSONL, &out)
+	assert.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 3)
+	assert.Equal(t, `{"id":"3","created_at":"2025-09-01T09:00:00Z","kind":"apply","currency":"USD","amount":"12.34","blocked_amount":"-12.34","reference":"7d3c1f0e-1111-4a4a-9b9b-000000000001","description":""}`, string(lines[0]))
+}
+
+func TestParseExportFormat(t *testing.T) {
+	_, err := core.ParseExportFormat("pdf")
+	assert.ErrorIs(t, err, core.ErrUnknownExportFormat)
+}
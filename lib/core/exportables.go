@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"time"
 	"wallet/lib/core/internal"
 
 	"github.com/google/uuid"
@@ -9,11 +10,22 @@ import (
 )
 
 type Wallet = internal.Wallet
+type WalletStatus = internal.WalletStatus
 type Transaction = internal.Transaction
+type TransactionKind = internal.TransactionKind
+type TransactionFilter = internal.TransactionFilter
+type Account = internal.Account
+type Currency = internal.Currency
+type LedgerEntry = internal.LedgerEntry
+type AccountBalance = internal.AccountBalance
+type Balance = internal.Balance
+type CreditLimitChange = internal.CreditLimitChange
 
 type Repo interface {
 	Wallet() WalletRepo
 	Transaction() TransactionRepo
+	Ledger() LedgerRepo
+	CreditLimitChange() CreditLimitChangeRepo
 
 	GetDBTransaction() *gorm.DB
 	Commit() error
@@ -25,16 +37,31 @@ type RepoFactory interface {
 }
 
 type WalletRepo interface {
-	GetOrCreate(ctx context.Context, userID uuid.UUID) (*Wallet, error)
-	GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID) (*Wallet, error)
+	GetOrCreate(ctx context.Context, userID uuid.UUID, currency Currency) (*Wallet, error)
+	GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID, currency Currency) (*Wallet, error)
+	List(ctx context.Context, userID uuid.UUID) ([]Wallet, error)
 	Update(ctx context.Context, wallet *Wallet) error
+	UpdateCreditLimit(ctx context.Context, wallet *Wallet) error
+	UpdateStatus(ctx context.Context, wallet *Wallet) error
 }
 
 type TransactionRepo interface {
-	Get(ctx context.Context, userID uuid.UUID, pageNumber int, pageSize int) (transactions []Transaction, hasMore bool, err error)
+	Get(ctx context.Context, filter TransactionFilter, pageNumber int, pageSize int) (transactions []Transaction, hasMore bool, err error)
+	List(ctx context.Context, filter TransactionFilter, cursor uint64, limit int) (transactions []Transaction, nextCursor uint64, err error)
+	GetBalanceAt(ctx context.Context, userID uuid.UUID, currency Currency, at time.Time) (*Balance, error)
 	Create(ctx context.Context, trx *Transaction) error
 }
 
+type CreditLimitChangeRepo interface {
+	Create(ctx context.Context, change *CreditLimitChange) error
+	List(ctx context.Context, userID uuid.UUID, currency Currency) ([]CreditLimitChange, error)
+}
+
+type LedgerRepo interface {
+	Post(ctx context.Context, entries []LedgerEntry) error
+	GetBalance(ctx context.Context, account Account, currency Currency) (*AccountBalance, error)
+}
+
 func NewFactory(db *gorm.DB) RepoFactory {
 	return &repoFactory{
 		db: db,
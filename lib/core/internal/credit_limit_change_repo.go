@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type creditLimitChangeRepo struct {
+	tx *gorm.DB
+}
+
+func NewCreditLimitChangeRepo(tx *gorm.DB) *creditLimitChangeRepo {
+	return &creditLimitChangeRepo{tx: tx}
+}
+
+func (r *creditLimitChangeRepo) Create(ctx context.Context, change *CreditLimitChange) error {
+	return r.tx.WithContext(ctx).Create(change).Error
+}
+
+// List returns the credit limit changes of a wallet, newest first.
+func (r *creditLimitChangeRepo) List(ctx context.Context, userID uuid.UUID, currency Currency) ([]CreditLimitChange, error) {
+	var changes []CreditLimitChange
+	if err := r.tx.WithContext(ctx).
+		Where("user_id = ? AND currency = ?", userID, currency).
+		Order("id DESC").
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// Currency is an ISO 4217 currency code. All amounts are stored as int64 in
+// the minor unit of their currency.
+type Currency string
+
+const IRR = Currency("IRR")
+const USD = Currency("USD")
+const EUR = Currency("EUR")
+const AED = Currency("AED")
+
+// minorUnits maps supported currencies to their number of decimal places.
+// IRR has no minor unit in practice, so amounts are whole rials.
+var minorUnits = map[Currency]int{
+	IRR: 0,
+	USD: 2,
+	EUR: 2,
+	AED: 2,
+}
+
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if !currency.IsSupported() {
+		return "", ErrUnsupportedCurrency
+	}
+	return currency, nil
+}
+
+func (c Currency) IsSupported() bool {
+	_, ok := minorUnits[c]
+	return ok
+}
+
+// MinorUnits returns the number of decimal places of the currency.
+func (c Currency) MinorUnits() int {
+	return minorUnits[c]
+}
+
+// FormatAmount renders an amount of minor units in major units, e.g. 1234 USD -> "12.34".
+func (c Currency) FormatAmount(amount int64) string {
+	units := c.MinorUnits()
+	if units == 0 {
+		return strconv.FormatInt(amount, 10)
+	}
+	sign := ""
+	abs := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		abs = uint64(-amount)
+	}
+	digits := strconv.FormatUint(abs, 10)
+	if len(digits) <= units {
+		digits = strings.Repeat("0", units-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-units] + "." + digits[len(digits)-units:]
+}
@@ -6,20 +6,119 @@ import (
 	"github.com/google/uuid"
 )
 
+// Wallet holds the balances of a user in one currency. CreditLimit is how far
+// below zero the available balance may go; blocked balance never goes negative.
 type Wallet struct {
 	UserID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Currency         Currency  `gorm:"type:varchar(3);primaryKey" json:"currency"`
 	AvailableBalance int64     `gorm:"not null;default:0" json:"available_balance"`
 	BlockedBalance   int64     `gorm:"not null;default:0" json:"blocked_balance"`
+	CreditLimit      int64     `gorm:"not null;default:0" json:"credit_limit"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
+
+	Status          WalletStatus `gorm:"type:varchar(16);not null;default:active" json:"status"`
+	StatusReason    string       `gorm:"size:255" json:"status_reason,omitempty"`
+	StatusChangedBy string       `gorm:"size:64" json:"status_changed_by,omitempty"`
+	StatusChangedAt *time.Time   `json:"status_changed_at,omitempty"`
+}
+
+// WalletStatus restricts which movements a wallet accepts, without touching its balances.
+type WalletStatus string
+
+const (
+	WalletActive      = WalletStatus("active")       // everything allowed
+	WalletDebitFrozen = WalletStatus("debit_frozen") // funds can come in but not leave
+	WalletFullyFrozen = WalletStatus("fully_frozen") // no funds in or out
+	WalletClosed      = WalletStatus("closed")       // final, the wallet is empty and unused
+)
+
+// CreditLimitChange records who changed the credit limit of a wallet, when and why.
+type CreditLimitChange struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index:idx_credit_limit_changes_wallet" json:"user_id"`
+	Currency  Currency  `gorm:"type:varchar(3);not null;index:idx_credit_limit_changes_wallet" json:"currency"`
+	OldLimit  int64     `gorm:"not null" json:"old_limit"`
+	NewLimit  int64     `gorm:"not null" json:"new_limit"`
+	Actor     string    `gorm:"size:64;not null" json:"actor"`
+	Reason    string    `gorm:"size:255;not null" json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (CreditLimitChange) TableName() string {
+	return "wallet_credit_limit_changes"
 }
 
+// TransactionKind tells which operation produced a transaction.
+type TransactionKind string
+
+const (
+	KindBlock      = TransactionKind("block")    // funds reserved in blocked balance
+	KindApply      = TransactionKind("apply")    // blocked funds released to available balance
+	KindWithdraw   = TransactionKind("withdraw") // blocked funds paid out
+	KindReversal   = TransactionKind("reversal") // a block undone
+	KindTransfer   = TransactionKind("transfer")
+	KindExchange   = TransactionKind("exchange")
+	KindAdjustment = TransactionKind("adjustment") // manual correction approved by operators
+	KindCapture    = TransactionKind("capture")    // held funds paid out, the uncaptured rest released
+	KindFee        = TransactionKind("fee")        // blocked funds charged as a fee
+)
+
 type Transaction struct {
+	ID            uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	WalletID      uuid.UUID       `gorm:"type:uuid;not null;index" json:"wallet_id"`
+	Currency      Currency        `gorm:"type:varchar(3);not null" json:"currency"`
+	Kind          TransactionKind `gorm:"type:varchar(16);not null;index" json:"kind"`
+	Amount        int64           `gorm:"not null;default:0" json:"amount"`
+	BlockedAmount int64           `gorm:"not null;default:0" json:"blocked_amount"`
+	Reference     uuid.UUID       `gorm:"type:uuid;index;not null" json:"reference"`
+	Description   string          `gorm:"size:255" json:"description"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// TransactionFilter narrows the transactions of a wallet. Zero fields match everything.
+type TransactionFilter struct {
+	UserID    uuid.UUID
+	Currency  Currency
+	From      *time.Time // inclusive
+	To        *time.Time // exclusive
+	Reference *uuid.UUID
+	Kinds     []TransactionKind
+	Sign      int // 1: only net credits, -1: only net debits, by amount + blocked_amount
+}
+
+// Account names a ledger account. Wallet accounts are derived from the
+// wallet owner, system accounts are fixed names.
+type Account string
+
+// LedgerEntry is a single debit or credit leg of a journal. For every
+// currency, the entries sharing a JournalID sum up to zero (total debit == total credit).
+type LedgerEntry struct {
 	ID            uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
-	WalletID      uuid.UUID `gorm:"type:uuid;not null;index" json:"wallet_id"`
-	Amount        int64     `gorm:"not null;default:0" json:"amount"`
-	BlockedAmount int64     `gorm:"not null;default:0" json:"blocked_amount"`
+	JournalID     uuid.UUID `gorm:"type:uuid;not null;index" json:"journal_id"`
+	Account       Account   `gorm:"type:varchar(64);not null;index" json:"account"`
+	Currency      Currency  `gorm:"type:varchar(3);not null" json:"currency"`
+	Debit         int64     `gorm:"not null;default:0" json:"debit"`
+	Credit        int64     `gorm:"not null;default:0" json:"credit"`
+	TransactionID uint64    `gorm:"index" json:"transaction_id"`
 	Reference     uuid.UUID `gorm:"type:uuid;index;not null" json:"reference"`
 	Description   string    `gorm:"size:255" json:"description"`
 	CreatedAt     time.Time `json:"created_at"`
 }
+
+// AccountBalance holds the total debit and credit posted to an account in one currency.
+type AccountBalance struct {
+	Account  Account  `json:"account"`
+	Currency Currency `json:"currency"`
+	Debit    int64    `json:"debit"`
+	Credit   int64    `json:"credit"`
+}
+
+// Balance is the balance of a wallet at a point in time, as derived from its transactions.
+type Balance struct {
+	UserID           uuid.UUID `json:"user_id"`
+	Currency         Currency  `json:"currency"`
+	At               time.Time `json:"at"`
+	AvailableBalance int64     `json:"available_balance"`
+	BlockedBalance   int64     `json:"blocked_balance"`
+}
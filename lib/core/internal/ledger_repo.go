@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var ErrUnbalancedJournal = errors.New("journal debit and credit are not balanced")
+
+type ledgerRepo struct {
+	tx *gorm.DB
+}
+
+func NewLedgerRepo(tx *gorm.DB) *ledgerRepo {
+	return &ledgerRepo{tx: tx}
+}
+
+// Post inserts the entries of a single journal after checking that they are
+// balanced in every currency, so no posting can implicitly convert money.
+func (r *ledgerRepo) Post(ctx context.Context, entries []LedgerEntry) error {
+	net := make(map[Currency]int64)
+	for _, e := range entries {
+		if e.Debit < 0 || e.Credit < 0 || !e.Currency.IsSupported() {
+			return ErrUnbalancedJournal
+		}
+		net[e.Currency] += e.Debit - e.Credit
+	}
+	for _, n := range net {
+		if n != 0 {
+			return ErrUnbalancedJournal
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.tx.WithContext(ctx).Create(&entries).Error
+}
+
+// GetBalance sums all debits and credits posted to the given account in the given currency.
+func (r *ledgerRepo) GetBalance(ctx context.Context, account Account, currency Currency) (*AccountBalance, error) {
+	var balance AccountBalance
+	err := r.tx.WithContext(ctx).
+		Model(&LedgerEntry{}).
+		Select("COALESCE(SUM(debit), 0) AS debit, COALESCE(SUM(credit), 0) AS credit").
+		Where("account = ? AND currency = ?", account, currency).
+		Scan(&balance).Error
+	if err != nil {
+		return nil, err
+	}
+	balance.Account = account
+	balance.Currency = currency
+	return &balance, nil
+}
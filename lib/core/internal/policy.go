@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+var ErrNegativeBalance = errors.New("balance would go below its limit")
+var ErrWalletFrozen = errors.New("wallet is frozen")
+var ErrWalletClosed = errors.New("wallet is closed")
+
+// BalanceError is returned when a wallet mutation breaks the balance policy.
+// It matches ErrNegativeBalance with errors.Is.
+type BalanceError struct {
+	UserID           uuid.UUID
+	Currency         Currency
+	AvailableBalance int64
+	BlockedBalance   int64
+	CreditLimit      int64
+}
+
+func (e *BalanceError) Error() string {
+	return fmt.Sprintf("wallet %s/%s: available %d, blocked %d, credit limit %d: %s",
+		e.UserID, e.Currency, e.AvailableBalance, e.BlockedBalance, e.CreditLimit, ErrNegativeBalance)
+}
+
+func (e *BalanceError) Unwrap() error {
+	return ErrNegativeBalance
+}
+
+// Validate checks the wallet against the balance policy: the available balance
+// may not go below -CreditLimit and the blocked balance may not go below zero.
+func (w *Wallet) Validate() error {
+	if w.AvailableBalance >= -w.CreditLimit && w.BlockedBalance >= 0 {
+		return nil
+	}
+	return &BalanceError{
+		UserID:           w.UserID,
+		Currency:         w.Currency,
+		AvailableBalance: w.AvailableBalance,
+		BlockedBalance:   w.BlockedBalance,
+		CreditLimit:      w.CreditLimit,
+	}
+}
+
+// Spendable is how much can be debited from the available balance, credit included.
+func (w *Wallet) Spendable() int64 {
+	return w.AvailableBalance + w.CreditLimit
+}
+
+// CreditUsed is how much of the credit limit the wallet currently uses.
+func (w *Wallet) CreditUsed() int64 {
+	if w.AvailableBalance >= 0 {
+		return 0
+	}
+	return -w.AvailableBalance
+}
+
+// CanDebit tells whether funds may leave the wallet, e.g. by withdrawal or transfer.
+func (w *Wallet) CanDebit() error {
+	switch w.Status {
+	case WalletDebitFrozen, WalletFullyFrozen:
+		return ErrWalletFrozen
+	case WalletClosed:
+		return ErrWalletClosed
+	}
+	return nil
+}
+
+// CanCredit tells whether funds may come into the wallet, e.g. by deposit or transfer.
+// Returning funds of the wallet's own operations (reversals) is always allowed.
+func (w *Wallet) CanCredit() error {
+	switch w.Status {
+	case WalletFullyFrozen:
+		return ErrWalletFrozen
+	case WalletClosed:
+		return ErrWalletClosed
+	}
+	return nil
+}
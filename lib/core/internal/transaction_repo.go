@@ -2,8 +2,10 @@ package internal
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+
 	"gorm.io/gorm"
 )
 
@@ -15,35 +17,24 @@ func NewTransactionRepo(tx *gorm.DB) *transactionRepo {
 	return &transactionRepo{tx: tx}
 }
 
-// Get returns transactions for a given userID with pagination.
+// Get returns transactions matching filter with offset pagination.
 // It also returns whether there are more records beyond this page.
-func (r *transactionRepo) Get(ctx context.Context, userID uuid.UUID, pageNumber int, pageSize int) ([]Transaction, bool, error) {
+func (r *transactionRepo) Get(ctx context.Context, filter TransactionFilter, pageNumber int, pageSize int) ([]Transaction, bool, error) {
 	var (
 		transactions []Transaction
 		count        int64
 	)
 
-	// first resolve wallet ID for the given user
-	var wallet Wallet
-	if err := r.tx.WithContext(ctx).
-		Select("id").
-		Where("user_id = ?", userID).
-		First(&wallet).Error; err != nil {
-		return nil, false, err
-	}
-
 	// count total transactions for pagination
-	if err := r.tx.WithContext(ctx).
+	if err := r.filter(ctx, filter).
 		Model(&Transaction{}).
-		Where("wallet_id = ?", wallet.UserID).
 		Count(&count).Error; err != nil {
 		return nil, false, err
 	}
 
 	// fetch transactions with offset/limit
 	offset := (pageNumber - 1) * pageSize
-	if err := r.tx.WithContext(ctx).
-		Where("wallet_id = ?", wallet.UserID).
+	if err := r.filter(ctx, filter).
 		Order("id DESC").
 		Offset(offset).
 		Limit(pageSize).
@@ -55,6 +46,73 @@ func (r *transactionRepo) Get(ctx context.Context, userID uuid.UUID, pageNumber
 	return transactions, hasMore, nil
 }
 
+// List returns up to limit transactions matching filter with an ID lower than
+// cursor (newest first, cursor 0 starts from the newest). It also returns the
+// cursor of the next page, which is 0 when there are no more records.
+func (r *transactionRepo) List(ctx context.Context, filter TransactionFilter, cursor uint64, limit int) ([]Transaction, uint64, error) {
+	var transactions []Transaction
+
+	query := r.filter(ctx, filter)
+	if cursor != 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	// fetch one extra row to know whether there is a next page
+	if err := query.
+		Order("id DESC").
+		Limit(limit + 1).
+		Find(&transactions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if len(transactions) <= limit {
+		return transactions, 0, nil
+	}
+	transactions = transactions[:limit]
+	return transactions, transactions[limit-1].ID, nil
+}
+
+// GetBalanceAt sums the transactions of a wallet created before at.
+func (r *transactionRepo) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency Currency, at time.Time) (*Balance, error) {
+	balance := Balance{
+		UserID:   userID,
+		Currency: currency,
+		At:       at,
+	}
+	err := r.tx.WithContext(ctx).
+		Model(&Transaction{}).
+		Select("COALESCE(SUM(amount), 0) AS available_balance, COALESCE(SUM(blocked_amount), 0) AS blocked_balance").
+		Where("wallet_id = ? AND currency = ? AND created_at < ?", userID, currency, at).
+		Scan(&balance).Error
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+func (r *transactionRepo) filter(ctx context.Context, filter TransactionFilter) *gorm.DB {
+	query := r.tx.WithContext(ctx).
+		Where("wallet_id = ? AND currency = ?", filter.UserID, filter.Currency)
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.Reference != nil {
+		query = query.Where("reference = ?", *filter.Reference)
+	}
+	if len(filter.Kinds) != 0 {
+		query = query.Where("kind IN ?", filter.Kinds)
+	}
+	if filter.Sign > 0 {
+		query = query.Where("amount + blocked_amount > 0")
+	}
+	if filter.Sign < 0 {
+		query = query.Where("amount + blocked_amount < 0")
+	}
+	return query
+}
+
 func (r *transactionRepo) Create(ctx context.Context, trx *Transaction) error {
 	return r.tx.WithContext(ctx).Create(trx).Error
 }
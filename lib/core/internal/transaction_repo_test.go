@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"wallet/lib/utils/db/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRecordingRepo answers every query with the transactions of ids, newest
+// first, as the database would after applying the filter and the limit.
+func newRecordingRepo(t *testing.T, ids ...uint64) (*transactionRepo, *dbtest.Recorder) {
+	recorder := &dbtest.Recorder{Columns: []string{"id"}}
+	for _, id := range ids {
+		recorder.Rows = append(recorder.Rows, []driver.Value{int64(id)})
+	}
+	return NewTransactionRepo(dbtest.Open(t, recorder)), recorder
+}
+
+func transactionIDs(transactions []Transaction) []uint64 {
+	ids := make([]uint64, 0, len(transactions))
+	for _, trx := range transactions {
+		ids = append(ids, trx.ID)
+	}
+	return ids
+}
+
+func TestTransactionRepo_ListFirstPage(t *testing.T) {
+	// limit + 1 rows come back, so there is a next page starting below the last one kept
+	repo, recorder := newRecordingRepo(t, 9, 8, 7)
+	userID := uuid.New()
+
+	transactions, next, err := repo.List(context.Background(), TransactionFilter{UserID: userID, Currency: USD}, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{9, 8}, transactionIDs(transactions))
+	assert.Equal(t, uint64(8), next)
+	assert.Len(t, recorder.Statements, 1)
+	// one more row than the page is asked for
+	assert.Equal(t, []any{userID.String(), "USD", int64(3)}, recorder.Statements[0].Args)
+}
+
+func TestTransactionRepo_ListLastPage(t *testing.T) {
+	repo, recorder := newRecordingRepo(t, 7, 6)
+	userID := uuid.New()
+	filter := TransactionFilter{UserID: userID, Currency: USD, Kinds: []TransactionKind{KindBlock, KindApply}, Sign: -1}
+
+	transactions, next, err := repo.List(context.Background(), filter, 8, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{7, 6}, transactionIDs(transactions))
+	assert.Zero(t, next)
+	// the page continues below the cursor
+	assert.Equal(t, []any{userID.String(), "USD", "block", "apply", int64(8), int64(3)}, recorder.Statements[0].Args)
+}
+
+func TestTransactionRepo_ListEmpty(t *testing.T) {
+	repo, _ := newRecordingRepo(t)
+
+	transactions, next, err := repo.List(context.Background(), TransactionFilter{UserID: uuid.New(), Currency: USD}, 0, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+	assert.Zero(t, next)
+}
@@ -18,16 +18,21 @@ func NewWalletRepo(tx *gorm.DB) *walletRepo {
 	return &walletRepo{tx: tx}
 }
 
-// GetOrCreate fetches wallet by userID, creates if not exists.
-func (r *walletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID) (*Wallet, error) {
+// GetOrCreate fetches wallet by userID and currency, creates if not exists.
+func (r *walletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID, currency Currency) (*Wallet, error) {
+	if !currency.IsSupported() {
+		return nil, ErrUnsupportedCurrency
+	}
 	var wallet Wallet
 
-	err := r.tx.WithContext(ctx).First(&wallet, "user_id = ?", userID).Error
+	err := r.tx.WithContext(ctx).First(&wallet, "user_id = ? AND currency = ?", userID, currency).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		wallet = Wallet{
 			UserID:           userID,
+			Currency:         currency,
 			AvailableBalance: 0,
 			BlockedBalance:   0,
+			Status:           WalletActive,
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
 		}
@@ -44,18 +49,23 @@ func (r *walletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID) (*Wallet
 }
 
 // GetOrCreateForUpdate fetches wallet with row-level locking.
-func (r *walletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID) (*Wallet, error) {
+func (r *walletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID, currency Currency) (*Wallet, error) {
+	if !currency.IsSupported() {
+		return nil, ErrUnsupportedCurrency
+	}
 	var wallet Wallet
 
 	err := r.tx.WithContext(ctx).
 		Clauses(clause.Locking{Strength: "UPDATE"}).
-		First(&wallet, "user_id = ?", userID).Error
+		First(&wallet, "user_id = ? AND currency = ?", userID, currency).Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		wallet = Wallet{
 			UserID:           userID,
+			Currency:         currency,
 			AvailableBalance: 0,
 			BlockedBalance:   0,
+			Status:           WalletActive,
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
 		}
@@ -72,15 +82,62 @@ func (r *walletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID)
 	return &wallet, nil
 }
 
-// Update updates balances and updated_at
+// List returns all wallets of a user, one per currency.
+func (r *walletRepo) List(ctx context.Context, userID uuid.UUID) ([]Wallet, error) {
+	var wallets []Wallet
+	if err := r.tx.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("currency").
+		Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// Update updates balances and updated_at. Balances that break the
+// balance policy are rejected before reaching the database.
 func (r *walletRepo) Update(ctx context.Context, wallet *Wallet) error {
+	if err := wallet.Validate(); err != nil {
+		return err
+	}
 	wallet.UpdatedAt = time.Now()
 	return r.tx.WithContext(ctx).
 		Model(&Wallet{}).
-		Where("user_id = ?", wallet.UserID).
+		Where("user_id = ? AND currency = ?", wallet.UserID, wallet.Currency).
 		Updates(map[string]any{
 			"available_balance": wallet.AvailableBalance,
 			"blocked_balance":   wallet.BlockedBalance,
 			"updated_at":        wallet.UpdatedAt,
 		}).Error
 }
+
+// UpdateCreditLimit updates the credit limit and updated_at. A limit the
+// current balance already exceeds is rejected like any other policy violation.
+func (r *walletRepo) UpdateCreditLimit(ctx context.Context, wallet *Wallet) error {
+	if err := wallet.Validate(); err != nil {
+		return err
+	}
+	wallet.UpdatedAt = time.Now()
+	return r.tx.WithContext(ctx).
+		Model(&Wallet{}).
+		Where("user_id = ? AND currency = ?", wallet.UserID, wallet.Currency).
+		Updates(map[string]any{
+			"credit_limit": wallet.CreditLimit,
+			"updated_at":   wallet.UpdatedAt,
+		}).Error
+}
+
+// UpdateStatus updates the status, its reason and who changed it.
+func (r *walletRepo) UpdateStatus(ctx context.Context, wallet *Wallet) error {
+	wallet.UpdatedAt = time.Now()
+	return r.tx.WithContext(ctx).
+		Model(&Wallet{}).
+		Where("user_id = ? AND currency = ?", wallet.UserID, wallet.Currency).
+		Updates(map[string]any{
+			"status":            wallet.Status,
+			"status_reason":     wallet.StatusReason,
+			"status_changed_by": wallet.StatusChangedBy,
+			"status_changed_at": wallet.StatusChangedAt,
+			"updated_at":        wallet.UpdatedAt,
+		}).Error
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"wallet/lib/core/internal"
+
+	"github.com/google/uuid"
+)
+
+// System accounts used as the counter side of wallet postings.
+const (
+	BankClearing  = Account("system:bank_clearing")
+	PSPReceivable = Account("system:psp_receivable")
+	Fees          = Account("system:fees")
+	Suspense      = Account("system:suspense")
+	FXPosition    = Account("system:fx_position")
+	Settlement    = Account("system:settlement") // captured holds owed to merchants
+)
+
+var ErrUnbalancedJournal = internal.ErrUnbalancedJournal
+
+// WalletAvailableAccount is the liability account holding the available balance of a wallet.
+func WalletAvailableAccount(userID uuid.UUID) Account {
+	return Account(fmt.Sprintf("wallet:%s:available", userID))
+}
+
+// WalletBlockedAccount is the liability account holding the blocked balance of a wallet.
+func WalletBlockedAccount(userID uuid.UUID) Account {
+	return Account(fmt.Sprintf("wallet:%s:blocked", userID))
+}
+
+// Post creates the given wallet transactions and records one balanced journal
+// for them. Wallet accounts are liabilities, so an increase of a wallet balance
+// is a credit. Whatever the transactions add to (or remove from) the total of
+// wallet balances is booked against the counter account, per currency.
+func Post(ctx context.Context, repo Repo, counter Account, trxs ...*Transaction) error {
+	journalID := uuid.New()
+	entries := make([]LedgerEntry, 0, 2*len(trxs)+1)
+	net := make(map[Currency]int64)
+	currencies := make([]Currency, 0, 1)
+	for _, trx := range trxs {
+		if err := repo.Transaction().Create(ctx, trx); err != nil {
+			return err
+		}
+		entries = appendLeg(entries, journalID, WalletAvailableAccount(trx.WalletID), -trx.Amount, trx)
+		entries = appendLeg(entries, journalID, WalletBlockedAccount(trx.WalletID), -trx.BlockedAmount, trx)
+		if _, ok := net[trx.Currency]; !ok {
+			currencies = append(currencies, trx.Currency)
+		}
+		net[trx.Currency] += trx.Amount + trx.BlockedAmount
+	}
+	for _, currency := range currencies {
+		entries = appendLeg(entries, journalID, counter, net[currency], &Transaction{
+			Currency:    currency,
+			Reference:   trxs[0].Reference,
+			Description: trxs[0].Description,
+		})
+	}
+	return repo.Ledger().Post(ctx, entries)
+}
+
+// appendLeg appends a leg moving amount on account; positive amounts are debits.
+func appendLeg(entries []LedgerEntry, journalID uuid.UUID, account Account, amount int64, trx *Transaction) []LedgerEntry {
+	if amount == 0 {
+		return entries
+	}
+	entry := LedgerEntry{
+		JournalID:     journalID,
+		Account:       account,
+		Currency:      trx.Currency,
+		TransactionID: trx.ID,
+		Reference:     trx.Reference,
+		Description:   trx.Description,
+	}
+	if amount > 0 {
+		entry.Debit = amount
+	} else {
+		entry.Credit = -amount
+	}
+	return append(entries, entry)
+}
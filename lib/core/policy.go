@@ -0,0 +1,9 @@
+package core
+
+import "wallet/lib/core/internal"
+
+// ErrNegativeBalance is matched by the BalanceError that WalletRepo.Update
+// returns for balances breaking the policy of Wallet.Validate.
+var ErrNegativeBalance = internal.ErrNegativeBalance
+
+type BalanceError = internal.BalanceError
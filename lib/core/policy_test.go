@@ -0,0 +1,50 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWallet_Validate(t *testing.T) {
+	wallet := core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 0, BlockedBalance: 0}
+	assert.NoError(t, wallet.Validate())
+
+	wallet.AvailableBalance = -1
+	err := wallet.Validate()
+	assert.ErrorIs(t, err, core.ErrNegativeBalance)
+	var balanceErr *core.BalanceError
+	assert.True(t, errors.As(err, &balanceErr))
+	assert.Equal(t, int64(-1), balanceErr.AvailableBalance)
+
+	wallet.CreditLimit = 100
+	wallet.AvailableBalance = -100
+	assert.NoError(t, wallet.Validate())
+	wallet.AvailableBalance = -101
+	assert.ErrorIs(t, wallet.Validate(), core.ErrNegativeBalance)
+
+	// credit never extends to the blocked balance
+	wallet.AvailableBalance = 0
+	wallet.BlockedBalance = -1
+	assert.ErrorIs(t, wallet.Validate(), core.ErrNegativeBalance)
+}
+
+func TestWallet_CanDebitCanCredit(t *testing.T) {
+	cases := []struct {
+		status        core.WalletStatus
+		debit, credit error
+	}{
+		{core.WalletActive, nil, nil},
+		{core.WalletDebitFrozen, core.ErrWalletFrozen, nil},
+		{core.WalletFullyFrozen, core.ErrWalletFrozen, core.ErrWalletFrozen},
+		{core.WalletClosed, core.ErrWalletClosed, core.ErrWalletClosed},
+	}
+	for _, c := range cases {
+		wallet := core.Wallet{Status: c.status}
+		assert.Equal(t, c.debit, wallet.CanDebit(), c.status)
+		assert.Equal(t, c.credit, wallet.CanCredit(), c.status)
+	}
+}
@@ -7,9 +7,11 @@ import (
 )
 
 type repo struct {
-	tx              *gorm.DB
-	walletRepo      WalletRepo
-	transactionRepo TransactionRepo
+	tx                    *gorm.DB
+	walletRepo            WalletRepo
+	transactionRepo       TransactionRepo
+	ledgerRepo            LedgerRepo
+	creditLimitChangeRepo CreditLimitChangeRepo
 }
 
 func (r *repo) Wallet() WalletRepo {
@@ -18,6 +20,12 @@ func (r *repo) Wallet() WalletRepo {
 func (r *repo) Transaction() TransactionRepo {
 	return r.transactionRepo
 }
+func (r *repo) Ledger() LedgerRepo {
+	return r.ledgerRepo
+}
+func (r *repo) CreditLimitChange() CreditLimitChangeRepo {
+	return r.creditLimitChangeRepo
+}
 func (r *repo) GetDBTransaction() *gorm.DB {
 	return r.tx
 }
@@ -37,8 +45,10 @@ func (rf *repoFactory) New(tx *gorm.DB) Repo {
 		tx = rf.db.Begin()
 	}
 	return &repo{
-		tx:              tx,
-		walletRepo:      internal.NewWalletRepo(tx),
-		transactionRepo: internal.NewTransactionRepo(tx),
+		tx:                    tx,
+		walletRepo:            internal.NewWalletRepo(tx),
+		transactionRepo:       internal.NewTransactionRepo(tx),
+		ledgerRepo:            internal.NewLedgerRepo(tx),
+		creditLimitChangeRepo: internal.NewCreditLimitChangeRepo(tx),
 	}
 }
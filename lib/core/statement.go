@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// Statement lists the postings of a wallet in [From, To) with the balances around them.
+// Transactions holds one page of the postings; NextCursor continues it and is
+// zero on the last page.
+type Statement struct {
+	UserID       uuid.UUID     `json:"user_id"`
+	Currency     Currency      `json:"currency"`
+	From         time.Time     `json:"from"`
+	To           time.Time     `json:"to"`
+	Opening      Balance       `json:"opening"`
+	Closing      Balance       `json:"closing"`
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   uint64        `json:"-"`
+}
+
+// GetStatement builds the statement of a wallet for [from, to). The opening
+// balance is the sum of all transactions before from and the closing balance
+// the sum of all transactions before to, so both are computed by the database
+// and cover the whole period whatever page is asked for. The postings are
+// listed newest first, limit at a time starting after cursor, like List.
+func GetStatement(ctx context.Context, repo Repo, userID uuid.UUID, currency Currency, from, to time.Time, cursor uint64, limit int) (*Statement, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidPeriod
+	}
+	opening, err := repo.Transaction().GetBalanceAt(ctx, userID, currency, from)
+	if err != nil {
+		return nil, err
+	}
+	closing, err := repo.Transaction().GetBalanceAt(ctx, userID, currency, to)
+	if err != nil {
+		return nil, err
+	}
+	filter := TransactionFilter{
+		UserID:   userID,
+		Currency: currency,
+		From:     &from,
+		To:       &to,
+	}
+	transactions, nextCursor, err := repo.Transaction().List(ctx, filter, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	if transactions == nil {
+		transactions = []Transaction{}
+	}
+	return &Statement{
+		UserID:       userID,
+		Currency:     currency,
+		From:         from,
+		To:           to,
+		Opening:      *opening,
+		Closing:      *closing,
+		Transactions: transactions,
+		NextCursor:   nextCursor,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// ledgerTransactionRepo keeps the transactions of one wallet, newest first,
+// and answers balances and pages from them like the database would.
+type ledgerTransactionRepo struct {
+	core.TransactionRepo
+	transactions []core.Transaction
+	balanceCalls int
+}
+
+func (r *ledgerTransactionRepo) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency core.Currency, at time.Time) (*core.Balance, error) {
+	r.balanceCalls++
+	balance := core.Balance{UserID: userID, Currency: currency, At: at}
+	for _, trx := range r.transactions {
+		if trx.CreatedAt.Before(at) {
+			balance.AvailableBalance += trx.Amount
+			balance.BlockedBalance += trx.BlockedAmount
+		}
+	}
+	return &balance, nil
+}
+
+// List pages the transactions of the filter's period; the cursor is the id to continue below.
+func (r *ledgerTransactionRepo) List(ctx context.Context, filter core.TransactionFilter, cursor uint64, limit int) ([]core.Transaction, uint64, error) {
+	var page []core.Transaction
+	for _, trx := range r.transactions {
+		if trx.CreatedAt.Before(*filter.From) || !trx.CreatedAt.Before(*filter.To) || (cursor != 0 && trx.ID >= cursor) {
+			continue
+		}
+		if len(page) == limit {
+			return page, page[limit-1].ID, nil
+		}
+		page = append(page, trx)
+	}
+	return page, 0, nil
+}
+
+type statementRepo struct {
+	core.Repo
+	transactions *ledgerTransactionRepo
+}
+
+func (r *statementRepo) Transaction() core.TransactionRepo {
+	return r.transactions
+}
+
+var (
+	statementFrom = time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	statementTo   = time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// newStatementRepo has two postings before September, three in it and one after.
+func newStatementRepo() *statementRepo {
+	return &statementRepo{transactions: &ledgerTransactionRepo{transactions: []core.Transaction{
+		{ID: 6, Kind: core.KindApply, Amount: 9000, CreatedAt: statementTo},
+		{ID: 5, Kind: core.KindWithdraw, BlockedAmount: -300, CreatedAt: statementTo.Add(-time.Nanosecond)},
+		{ID: 4, Kind: core.KindBlock, Amount: -300, BlockedAmount: 300, CreatedAt: statementFrom.Add(10 * 24 * time.Hour)},
+		{ID: 3, Kind: core.KindApply, Amount: 500, CreatedAt: statementFrom},
+		{ID: 2, Kind: core.KindBlock, Amount: -200, BlockedAmount: 200, CreatedAt: statementFrom.Add(-time.Hour)},
+		{ID: 1, Kind: core.KindApply, Amount: 1000, CreatedAt: statementFrom.Add(-48 * time.Hour)},
+	}}}
+}
+
+func TestGetStatement(t *testing.T) {
+	repo := newStatementRepo()
+
+	statement, err := core.GetStatement(context.Background(), repo, uuid.New(), core.IRR, statementFrom, statementTo, 0, 100)
+	assert.NoError(t, err)
+
+	// before from: 1000 - 200 available and 200 blocked
+	assert.Equal(t, int64(800), statement.Opening.AvailableBalance)
+	assert.Equal(t, int64(200), statement.Opening.BlockedBalance)
+	assert.Equal(t, statementFrom, statement.Opening.At)
+	// the period adds 500 - 300 available and 300 - 300 blocked; the posting at to is left out
+	assert.Equal(t, int64(1000), statement.Closing.AvailableBalance)
+	assert.Equal(t, int64(200), statement.Closing.BlockedBalance)
+	assert.Equal(t, statementTo, statement.Closing.At)
+
+	assert.Equal(t, []uint64{5, 4, 3}, statementIDs(statement.Transactions))
+	assert.Zero(t, statement.NextCursor)
+}
+
+func TestGetStatement_Pages(t *testing.T) {
+	repo := newStatementRepo()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	first, err := core.GetStatement(ctx, repo, userID, core.IRR, statementFrom, statementTo, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{5, 4}, statementIDs(first.Transactions))
+	assert.Equal(t, uint64(4), first.NextCursor)
+
+	last, err := core.GetStatement(ctx, repo, userID, core.IRR, statementFrom, statementTo, first.NextCursor, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3}, statementIDs(last.Transactions))
+	assert.Zero(t, last.NextCursor)
+
+	// every page carries the balances of the whole period
+	assert.Equal(t, first.Opening, last.Opening)
+	assert.Equal(t, first.Closing, last.Closing)
+	var available, blocked int64
+	for _, trx := range append(first.Transactions, last.Transactions...) {
+		available += trx.Amount
+		blocked += trx.BlockedAmount
+	}
+	assert.Equal(t, last.Closing.AvailableBalance, last.Opening.AvailableBalance+available)
+	assert.Equal(t, last.Closing.BlockedBalance, last.Opening.BlockedBalance+blocked)
+}
+
+func TestGetStatement_Empty(t *testing.T) {
+	repo := newStatementRepo()
+	from := statementTo.Add(24 * time.Hour)
+
+	statement, err := core.GetStatement(context.Background(), repo, uuid.New(), core.IRR, from, from.Add(24*time.Hour), 0, 100)
+	assert.NoError(t, err)
+	assert.NotNil(t, statement.Transactions)
+	assert.Empty(t, statement.Transactions)
+	assert.Equal(t, statement.Opening.AvailableBalance, statement.Closing.AvailableBalance)
+	assert.Equal(t, int64(10000), statement.Closing.AvailableBalance)
+}
+
+func TestGetStatement_InvalidPeriod(t *testing.T) {
+	repo := newStatementRepo()
+
+	_, err := core.GetStatement(context.Background(), repo, uuid.New(), core.IRR, statementTo, statementTo, 0, 100)
+	assert.ErrorIs(t, err, core.ErrInvalidPeriod)
+	assert.Zero(t, repo.transactions.balanceCalls)
+}
+
+func statementIDs(transactions []core.Transaction) []uint64 {
+	ids := make([]uint64, 0, len(transactions))
+	for _, trx := range transactions {
+		ids = append(ids, trx.ID)
+	}
+	return ids
+}
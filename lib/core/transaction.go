@@ -0,0 +1,41 @@
+package core
+
+import (
+	"errors"
+	"wallet/lib/core/internal"
+)
+
+const (
+	KindBlock      = internal.KindBlock
+	KindApply      = internal.KindApply
+	KindWithdraw   = internal.KindWithdraw
+	KindReversal   = internal.KindReversal
+	KindTransfer   = internal.KindTransfer
+	KindExchange   = internal.KindExchange
+	KindAdjustment = internal.KindAdjustment
+	KindCapture    = internal.KindCapture
+	KindFee        = internal.KindFee
+)
+
+var ErrUnknownTransactionKind = errors.New("unknown transaction kind")
+
+var transactionKinds = []TransactionKind{
+	KindBlock,
+	KindApply,
+	KindWithdraw,
+	KindReversal,
+	KindTransfer,
+	KindExchange,
+	KindAdjustment,
+	KindCapture,
+	KindFee,
+}
+
+func ParseTransactionKind(kind string) (TransactionKind, error) {
+	for _, k := range transactionKinds {
+		if string(k) == kind {
+			return k, nil
+		}
+	}
+	return "", ErrUnknownTransactionKind
+}
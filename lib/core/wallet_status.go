@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wallet/lib/core/internal"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WalletActive      = internal.WalletActive
+	WalletDebitFrozen = internal.WalletDebitFrozen
+	WalletFullyFrozen = internal.WalletFullyFrozen
+	WalletClosed      = internal.WalletClosed
+)
+
+var ErrWalletFrozen = internal.ErrWalletFrozen
+var ErrWalletClosed = internal.ErrWalletClosed
+var ErrUnknownWalletStatus = errors.New("unknown wallet status")
+var ErrWalletNotEmpty = errors.New("only an empty wallet can be closed")
+
+var walletStatuses = []WalletStatus{
+	WalletActive,
+	WalletDebitFrozen,
+	WalletFullyFrozen,
+	WalletClosed,
+}
+
+func ParseWalletStatus(status string) (WalletStatus, error) {
+	for _, s := range walletStatuses {
+		if string(s) == status {
+			return s, nil
+		}
+	}
+	return "", ErrUnknownWalletStatus
+}
+
+// SetWalletStatus changes the status of a wallet under its row lock, recording
+// the reason and who changed it. Closing is final and needs an empty wallet.
+func SetWalletStatus(ctx context.Context, repo Repo, userID uuid.UUID, currency Currency, status WalletStatus, actor, reason string) (*Wallet, error) {
+	if _, err := ParseWalletStatus(string(status)); err != nil {
+		return nil, err
+	}
+	if actor == "" || reason == "" {
+		return nil, ErrMissingActor
+	}
+	wallet, err := repo.Wallet().GetOrCreateForUpdate(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Status == WalletClosed {
+		return nil, ErrWalletClosed
+	}
+	if status == WalletClosed && (wallet.AvailableBalance != 0 || wallet.BlockedBalance != 0) {
+		return nil, ErrWalletNotEmpty
+	}
+	now := time.Now()
+	wallet.Status = status
+	wallet.StatusReason = reason
+	wallet.StatusChangedBy = actor
+	wallet.StatusChangedAt = &now
+	if err := repo.Wallet().UpdateStatus(ctx, wallet); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
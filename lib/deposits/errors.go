@@ -0,0 +1,14 @@
+package deposits
+
+import "errors"
+
+var ErrNotFound = errors.New("deposit not found")
+var ErrAlreadyApplied = errors.New("deposit is already applied")
+var ErrAlreadyCancelled = errors.New("deposit is cancelled")
+var ErrInvalidApplyAt = errors.New("deposit can only be rescheduled to the future")
+var ErrNotDue = errors.New("deposit is not due yet")
+var ErrFailed = errors.New("deposit has failed")
+var ErrNotFailed = errors.New("only failed deposits can be retried")
+var ErrBlockedBalanceShort = errors.New("blocked balance does not cover the deposit")
+var ErrInvalidSchedule = errors.New("deposit legs must be positive, have an apply time and add up to the amount")
+var ErrDuplicateReference = errors.New("external reference is already used by another deposit of the source")
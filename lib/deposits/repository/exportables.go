@@ -4,14 +4,38 @@ import (
 	"context"
 	"wallet/lib/deposits/repository/internal"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type Deposit = internal.Deposit
+type Leg = internal.Leg
+type Status = internal.Status
+type Filter = internal.Filter
+type SourceType = internal.SourceType
+type Metadata = internal.Metadata
+
+const (
+	Pending   = internal.Pending
+	Applied   = internal.Applied
+	Cancelled = internal.Cancelled
+	Failed    = internal.Failed
+)
+
+const (
+	SourcePSP          = internal.SourcePSP
+	SourceOrder        = internal.SourceOrder
+	SourceBankTransfer = internal.SourceBankTransfer
+	SourceOther        = internal.SourceOther
+)
 
 type Repo interface {
 	Create(context.Context, *Deposit) error
 	Update(context.Context, *Deposit) error
+	UpdateLeg(context.Context, *Leg) error
+	Get(ctx context.Context, id uuid.UUID) (*Deposit, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*Deposit, error)
+	List(ctx context.Context, filter Filter, pageNumber int, pageSize int) ([]Deposit, bool, error)
 	GetApplicableDeposits(ctx context.Context, IDPrefix string) ([]Deposit, error)
 
 	GetDBTransaction() *gorm.DB
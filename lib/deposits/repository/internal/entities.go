@@ -1,18 +1,110 @@
 package internal
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
+	"wallet/lib/core"
 
 	"github.com/google/uuid"
 )
 
+type Status string
+
+const (
+	Pending   = Status("pending")   // blocked, waiting for apply_at
+	Applied   = Status("applied")   // released to the available balance
+	Cancelled = Status("cancelled") // block reversed before apply
+	Failed    = Status("failed")    // could not be applied, needs an operator
+)
+
+// SourceType is where a deposit comes from; external references are unique
+// per source.
+type SourceType string
+
+const (
+	SourcePSP          = SourceType("psp")           // a payment gateway payment
+	SourceOrder        = SourceType("order")         // an upstream marketplace order
+	SourceBankTransfer = SourceType("bank_transfer") // a transfer into our bank account
+	SourceOther        = SourceType("other")
+)
+
+// Filter selects deposits; zero fields match everything.
+type Filter struct {
+	UserID            uuid.UUID
+	Status            Status
+	SourceType        SourceType
+	ExternalReference string
+}
+
 type Deposit struct {
-	ID                 uuid.UUID `gorm:"primaryKey" json:"id"`
-	UserID             uuid.UUID `gorm:"index" json:"user_id"`
-	CreatedAt          time.Time `gorm:"index" json:"created_at"`
-	ApplyAt            time.Time `gorm:"index" json:"apply_at"`
-	Amount             int64     `json:"amount"`
-	Description        string    `gorm:"size:255" json:"description"`
-	BlockTransactionID uint64    `gorm:"index" json:"block_transaction_id"`
-	ApplyTransactionID uint64    `gorm:"index" json:"apply_transaction_id"`
+	ID                  uuid.UUID     `gorm:"primaryKey" json:"id"`
+	UserID              uuid.UUID     `gorm:"index" json:"user_id"`
+	Currency            core.Currency `gorm:"type:varchar(3);not null" json:"currency"`
+	CreatedAt           time.Time     `gorm:"index" json:"created_at"`
+	ApplyAt             time.Time     `gorm:"index" json:"apply_at"` // when the last leg is released
+	Amount              int64         `json:"amount"`
+	Description         string        `gorm:"size:255" json:"description"`
+	SourceType          SourceType    `gorm:"type:varchar(32);not null;uniqueIndex:idx_deposits_external_reference_source_type,priority:2" json:"source_type"`
+	ExternalReference   *string       `gorm:"size:255;uniqueIndex:idx_deposits_external_reference_source_type,priority:1" json:"external_reference,omitempty"` // e.g. the PSP payment ID
+	Metadata            Metadata      `gorm:"type:jsonb" json:"metadata,omitempty"`
+	Status              Status        `gorm:"type:varchar(16);not null;index" json:"status"`
+	BlockedAmount       int64         `gorm:"not null" json:"blocked_amount"` // part of amount still blocked
+	BlockTransactionID  uint64        `gorm:"index" json:"block_transaction_id"`
+	ApplyTransactionID  uint64        `gorm:"index" json:"apply_transaction_id"` // deposits applied before legs, legs carry their own
+	CancelTransactionID uint64        `gorm:"index" json:"cancel_transaction_id"`
+	Legs                []Leg         `gorm:"foreignKey:DepositID" json:"legs"`
+}
+
+// Leg is a tranche of a deposit released to the available balance at its own
+// time. Every deposit has at least one leg and the legs add up to its amount.
+type Leg struct {
+	ID                 uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	DepositID          uuid.UUID     `gorm:"type:uuid;not null;index" json:"-"`
+	UserID             uuid.UUID     `gorm:"type:uuid;not null" json:"-"`
+	Currency           core.Currency `gorm:"type:varchar(3);not null" json:"-"`
+	Amount             int64         `gorm:"not null" json:"amount"`
+	ApplyAt            time.Time     `gorm:"not null;index" json:"apply_at"`
+	Status             Status        `gorm:"type:varchar(16);not null" json:"status"` // pending, applied or cancelled
+	ApplyTransactionID uint64        `gorm:"index" json:"apply_transaction_id"`
+}
+
+func (Leg) TableName() string {
+	return "deposit_legs"
+}
+
+// Metadata is free-form JSON from the deposit source, stored as jsonb.
+type Metadata json.RawMessage
+
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	if len(m) == 0 {
+		return []byte("null"), nil
+	}
+	return json.RawMessage(m).MarshalJSON()
+}
+
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	return (*json.RawMessage)(m).UnmarshalJSON(data)
+}
+
+func (m Metadata) Value() (driver.Value, error) {
+	if len(m) == 0 || string(m) == "null" {
+		return nil, nil
+	}
+	return string(m), nil
+}
+
+func (m *Metadata) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+	case []byte:
+		*m = append(Metadata(nil), v...)
+	case string:
+		*m = Metadata(v)
+	default:
+		return fmt.Errorf("cant scan %T into deposit metadata", value)
+	}
+	return nil
 }
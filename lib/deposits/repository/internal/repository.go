@@ -16,22 +16,58 @@ func NewDepositRepo(tx *gorm.DB) *depositRepo {
 	return &depositRepo{tx: tx}
 }
 
-// Create inserts a new deposit and fills in ID automatically.
+// Create inserts a new deposit with its legs and fills in IDs automatically.
+// It returns gorm.ErrDuplicatedKey if the external reference is already used
+// for the same source.
 func (r *depositRepo) Create(ctx context.Context, dep *Deposit) error {
 	if dep.ID == uuid.Nil {
 		dep.ID = uuid.New()
 	}
-	return r.tx.WithContext(ctx).Create(dep).Error
+	for i := range dep.Legs {
+		if dep.Legs[i].ID == uuid.Nil {
+			dep.Legs[i].ID = uuid.New()
+		}
+		dep.Legs[i].DepositID = dep.ID
+	}
+	result := r.tx.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "source_type"}, {Name: "external_reference"}}, DoNothing: true}).
+		Omit("Legs").
+		Create(dep)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	if len(dep.Legs) == 0 {
+		return nil
+	}
+	return r.tx.WithContext(ctx).Create(&dep.Legs).Error
 }
 
-// Update updates an existing deposit by ID.
+// Update updates all fields of an existing deposit by ID, legs are updated
+// with UpdateLeg.
 func (r *depositRepo) Update(ctx context.Context, dep *Deposit) error {
 	return r.tx.WithContext(ctx).
 		Model(&Deposit{}).
 		Where("id = ?", dep.ID).
+		Select("*").
+		Omit("Legs").
 		Updates(dep).Error
 }
 
+// UpdateLeg updates an existing deposit leg by ID.
+func (r *depositRepo) UpdateLeg(ctx context.Context, leg *Leg) error {
+	return r.tx.WithContext(ctx).
+		Model(&Leg{}).
+		Where("id = ?", leg.ID).
+		Updates(leg).Error
+}
+
+func orderLegs(db *gorm.DB) *gorm.DB {
+	return db.Order("apply_at, id")
+}
+
 // GetDBTransaction returns the underlying gorm.DB (transaction).
 func (r *depositRepo) GetDBTransaction() *gorm.DB {
 	return r.tx
@@ -47,18 +83,75 @@ func (r *depositRepo) RollBack() error {
 	return r.tx.Rollback().Error
 }
 
+// Get fetches a deposit by ID.
+func (r *depositRepo) Get(ctx context.Context, id uuid.UUID) (*Deposit, error) {
+	var dep Deposit
+	if err := r.tx.WithContext(ctx).Preload("Legs", orderLegs).First(&dep, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &dep, nil
+}
+
+// List returns a page of deposits matching filter, newest first, and whether
+// there are more.
+func (r *depositRepo) List(ctx context.Context, filter Filter, pageNumber int, pageSize int) ([]Deposit, bool, error) {
+	var deposits []Deposit
+	query := r.tx.WithContext(ctx)
+	if filter.UserID != uuid.Nil {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.SourceType != "" {
+		query = query.Where("source_type = ?", filter.SourceType)
+	}
+	if filter.ExternalReference != "" {
+		query = query.Where("external_reference = ?", filter.ExternalReference)
+	}
+	// one extra row tells whether there is a next page
+	if err := query.
+		Preload("Legs", orderLegs).
+		Order("created_at DESC, id").
+		Offset((pageNumber - 1) * pageSize).
+		Limit(pageSize + 1).
+		Find(&deposits).Error; err != nil {
+		return nil, false, err
+	}
+	if len(deposits) > pageSize {
+		return deposits[:pageSize], true, nil
+	}
+	return deposits, false, nil
+}
+
+// GetForUpdate fetches a deposit by ID with row-level locking.
+func (r *depositRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*Deposit, error) {
+	var dep Deposit
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Legs", orderLegs).
+		First(&dep, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &dep, nil
+}
+
 // GetApplicableDeposits fetches deposits that:
 // - ID starts with IDPrefix
-// - ApplyAt is before now
-// - ApplyTransactionID = 0
+// - are pending
+// - have a pending leg with ApplyAt before now
+// Rows are not locked here, Apply locks each deposit it applies.
 func (r *depositRepo) GetApplicableDeposits(ctx context.Context, IDPrefix string) ([]Deposit, error) {
 	var deposits []Deposit
 
 	if err := r.tx.WithContext(ctx).
-		Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("id::text LIKE ?", IDPrefix+"%").
-		Where("apply_at < NOW()").
-		Where("apply_transaction_id = 0").
+		Where("status = ?", Pending).
+		Where("EXISTS (?)", r.tx.Model(&Leg{}).Select("1").
+			Where("deposit_legs.deposit_id = deposits.id").
+			Where("deposit_legs.status = ?", Pending).
+			Where("deposit_legs.apply_at < NOW()"),
+		).
 		Find(&deposits).Error; err != nil {
 		return nil, err
 	}
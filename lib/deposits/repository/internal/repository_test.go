@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/utils/db/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newSourcedDeposit() *Deposit {
+	reference := "pay_8f2c"
+	return &Deposit{
+		UserID:            uuid.New(),
+		Currency:          core.IRR,
+		Amount:            100,
+		ApplyAt:           time.Now(),
+		Status:            Pending,
+		SourceType:        SourcePSP,
+		ExternalReference: &reference,
+		Legs:              []Leg{{Amount: 100, ApplyAt: time.Now(), Status: Pending}},
+	}
+}
+
+func TestDepositRepo_Create(t *testing.T) {
+	recorder := &dbtest.Recorder{Affected: 1}
+	repo := NewDepositRepo(dbtest.Open(t, recorder))
+
+	deposit := newSourcedDeposit()
+	assert.NoError(t, repo.Create(context.Background(), deposit))
+	assert.NotEqual(t, uuid.Nil, deposit.ID)
+	assert.Equal(t, deposit.ID, deposit.Legs[0].DepositID)
+	// the deposit and then its legs
+	assert.Len(t, recorder.Statements, 2)
+	// only a conflict on the source and external reference is skipped,
+	// others, e.g. on the id, still fail the insert
+	insert, _, ok := recorder.Find("ON CONFLICT")
+	assert.True(t, ok)
+	onConflict := insert.SQL[strings.Index(insert.SQL, "ON CONFLICT"):]
+	assert.Contains(t, onConflict, "source_type")
+	assert.Contains(t, onConflict, "external_reference")
+	assert.Contains(t, onConflict, "DO NOTHING")
+}
+
+func TestDepositRepo_CreateDuplicateReference(t *testing.T) {
+	recorder := &dbtest.Recorder{Affected: 0}
+	repo := NewDepositRepo(dbtest.Open(t, recorder))
+
+	err := repo.Create(context.Background(), newSourcedDeposit())
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	// the legs of the first deposit are kept
+	assert.Len(t, recorder.Statements, 1)
+}
@@ -2,31 +2,60 @@ package deposits
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"time"
 	"wallet/lib/core"
 	"wallet/lib/deposits/repository"
+	"wallet/lib/idempotency"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Deposit = repository.Deposit
+type Leg = repository.Leg
 
 type Service interface {
+	// Create blocks the deposit amount. If deposit.Legs is set the amount is
+	// released in those parts, otherwise all of it is released at ApplyAt.
 	Create(context.Context, *Deposit) error
+	CreateIdempotent(context.Context, *Deposit, idempotency.Key) (replayed bool, err error)
+	// Apply releases the legs of the deposit that are due.
 	Apply(context.Context, *Deposit) error
+	// Cancel reverses the block of a deposit none of whose funds were applied
+	// yet, e.g. on a chargeback within the settlement window. Once a leg is
+	// applied it returns ErrAlreadyApplied.
+	Cancel(ctx context.Context, id uuid.UUID) (*Deposit, error)
+	// Reschedule moves the next pending leg of a deposit to applyAt, the
+	// legs after it keep their distance to it.
+	Reschedule(ctx context.Context, id uuid.UUID, applyAt time.Time) (*Deposit, error)
+	// Retry puts a failed deposit back to pending, so the applier releases its
+	// due legs again. The blocked balance of the wallet must cover the part of
+	// the deposit still blocked, e.g. after an operator restored it with an
+	// adjustment.
+	Retry(ctx context.Context, id uuid.UUID) (*Deposit, error)
+	Get(ctx context.Context, id uuid.UUID) (*Deposit, error)
+	List(ctx context.Context, filter repository.Filter, pageNumber int, pageSize int) ([]Deposit, bool, error)
 	GetApplicableDeposits(ctx context.Context, IDPrefix string) ([]Deposit, error)
 }
 
 func New(
 	coreRepoFactory core.RepoFactory,
 	repoFactory repository.RepoFactory,
+	idempotencyRepoFactory idempotency.RepoFactory,
 ) Service {
 	return &service{
-		coreRepoFactory: coreRepoFactory,
-		repoFactory:     repoFactory,
+		coreRepoFactory:        coreRepoFactory,
+		repoFactory:            repoFactory,
+		idempotencyRepoFactory: idempotencyRepoFactory,
 	}
 }
 
 type service struct {
-	coreRepoFactory core.RepoFactory
-	repoFactory     repository.RepoFactory
+	coreRepoFactory        core.RepoFactory
+	repoFactory            repository.RepoFactory
+	idempotencyRepoFactory idempotency.RepoFactory
 }
 
 func (s *service) Create(ctx context.Context, deposit *Deposit) error {
@@ -35,32 +64,99 @@ func (s *service) Create(ctx context.Context, deposit *Deposit) error {
 	defer func() {
 		_ = depositRepo.RollBack()
 	}()
-	if err := depositRepo.Create(ctx, deposit); err != nil {
+	if err := s.create(ctx, depositRepo, coreRepo, deposit); err != nil {
+		return err
+	}
+	return depositRepo.Commit()
+}
+
+// CreateIdempotent creates the deposit once per key; a replayed request gets
+// the originally created deposit back in deposit.
+func (s *service) CreateIdempotent(ctx context.Context, deposit *Deposit, key idempotency.Key) (bool, error) {
+	depositRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(depositRepo.GetDBTransaction())
+	idempotencyRepo := s.idempotencyRepoFactory.New(depositRepo.GetDBTransaction())
+	defer func() {
+		_ = depositRepo.RollBack()
+	}()
+	replayed, err := idempotency.Do(ctx, idempotencyRepo, key, deposit, func() error {
+		return s.create(ctx, depositRepo, coreRepo, deposit)
+	})
+	if err != nil || replayed {
+		return replayed, err
+	}
+	return false, depositRepo.Commit()
+}
+
+func (s *service) create(ctx context.Context, depositRepo repository.Repo, coreRepo core.Repo, deposit *Deposit) error {
+	if err := schedule(deposit); err != nil {
 		return err
 	}
-	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, deposit.UserID)
+	if deposit.SourceType == "" {
+		deposit.SourceType = repository.SourceOther
+	}
+	deposit.Status = repository.Pending
+	deposit.BlockedAmount = deposit.Amount
+	err := depositRepo.Create(ctx, deposit)
+	// a duplicate upstream callback, the first one already blocked the funds
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicateReference
+	}
 	if err != nil {
 		return err
 	}
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, deposit.UserID, deposit.Currency)
+	if err != nil {
+		return err
+	}
+	if err := wallet.CanCredit(); err != nil {
+		return err
+	}
 	wallet.BlockedBalance += deposit.Amount
 	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
 		return err
 	}
 	trx := &core.Transaction{
+		Kind:          core.KindBlock,
 		WalletID:      wallet.UserID,
+		Currency:      wallet.Currency,
 		BlockedAmount: deposit.Amount,
 		Amount:        0,
 		Reference:     deposit.ID,
 		Description:   deposit.Description,
 	}
-	if err := coreRepo.Transaction().Create(ctx, trx); err != nil {
+	if err := core.Post(ctx, coreRepo, core.PSPReceivable, trx); err != nil {
 		return err
 	}
 	deposit.BlockTransactionID = trx.ID
-	if err := depositRepo.Update(ctx, deposit); err != nil {
-		return err
+	return depositRepo.Update(ctx, deposit)
+}
+
+// schedule checks the legs of a new deposit, or makes a single leg releasing
+// the whole amount at ApplyAt when there are none.
+func schedule(deposit *Deposit) error {
+	if len(deposit.Legs) == 0 {
+		deposit.Legs = []Leg{{Amount: deposit.Amount, ApplyAt: deposit.ApplyAt}}
 	}
-	return depositRepo.Commit()
+	var sum int64
+	for i := range deposit.Legs {
+		leg := &deposit.Legs[i]
+		if leg.Amount <= 0 || leg.ApplyAt.IsZero() {
+			return ErrInvalidSchedule
+		}
+		sum += leg.Amount
+		leg.UserID = deposit.UserID
+		leg.Currency = deposit.Currency
+		leg.Status = repository.Pending
+	}
+	if sum != deposit.Amount {
+		return ErrInvalidSchedule
+	}
+	sort.SliceStable(deposit.Legs, func(i, j int) bool {
+		return deposit.Legs[i].ApplyAt.Before(deposit.Legs[j].ApplyAt)
+	})
+	deposit.ApplyAt = deposit.Legs[len(deposit.Legs)-1].ApplyAt
+	return nil
 }
 
 func (s *service) Apply(ctx context.Context, deposit *Deposit) error {
@@ -69,32 +165,248 @@ func (s *service) Apply(ctx context.Context, deposit *Deposit) error {
 	defer func() {
 		_ = depositRepo.RollBack()
 	}()
-	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, deposit.UserID)
+	locked, err := getPendingForUpdate(ctx, depositRepo, deposit.ID)
 	if err != nil {
 		return err
 	}
-	wallet.AvailableBalance += deposit.Amount
-	wallet.BlockedBalance -= deposit.Amount
+	*deposit = *locked
+	// it may have been rescheduled since it was listed
+	now := time.Now()
+	var due []*Leg
+	var dueAmount int64
+	for i := range deposit.Legs {
+		leg := &deposit.Legs[i]
+		if leg.Status == repository.Pending && !leg.ApplyAt.After(now) {
+			due = append(due, leg)
+			dueAmount += leg.Amount
+		}
+	}
+	if len(due) == 0 {
+		return ErrNotDue
+	}
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, deposit.UserID, deposit.Currency)
+	if err != nil {
+		return err
+	}
+	// a fully frozen wallet keeps the deposit blocked, the applier retries it later
+	if err := wallet.CanCredit(); err != nil {
+		return err
+	}
+	// the blocked funds were taken by something else, e.g. an adjustment, so
+	// retrying will not help
+	if wallet.BlockedBalance < dueAmount {
+		deposit.Status = repository.Failed
+		if err := depositRepo.Update(ctx, deposit); err != nil {
+			return err
+		}
+		if err := depositRepo.Commit(); err != nil {
+			return err
+		}
+		return ErrBlockedBalanceShort
+	}
+	wallet.AvailableBalance += dueAmount
+	wallet.BlockedBalance -= dueAmount
 	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
 		return err
 	}
+	for _, leg := range due {
+		trx := &core.Transaction{
+			Kind:          core.KindApply,
+			WalletID:      deposit.UserID,
+			Currency:      deposit.Currency,
+			Amount:        leg.Amount,
+			BlockedAmount: -leg.Amount,
+			Reference:     deposit.ID,
+			Description:   deposit.Description,
+		}
+		if err := core.Post(ctx, coreRepo, core.Suspense, trx); err != nil {
+			return err
+		}
+		leg.ApplyTransactionID = trx.ID
+		leg.Status = repository.Applied
+		if err := depositRepo.UpdateLeg(ctx, leg); err != nil {
+			return err
+		}
+		deposit.BlockedAmount -= leg.Amount
+	}
+	if deposit.BlockedAmount == 0 {
+		deposit.Status = repository.Applied
+	}
+	if err := depositRepo.Update(ctx, deposit); err != nil {
+		return err
+	}
+	return depositRepo.Commit()
+}
+
+func (s *service) Cancel(ctx context.Context, id uuid.UUID) (*Deposit, error) {
+	depositRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(depositRepo.GetDBTransaction())
+	defer func() {
+		_ = depositRepo.RollBack()
+	}()
+	deposit, err := getPendingForUpdate(ctx, depositRepo, id)
+	if err != nil {
+		return nil, err
+	}
+	// part of the funds already reached the owner
+	for _, leg := range deposit.Legs {
+		if leg.Status == repository.Applied {
+			return nil, ErrAlreadyApplied
+		}
+	}
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, deposit.UserID, deposit.Currency)
+	if err != nil {
+		return nil, err
+	}
+	// the funds never reached the owner, so a frozen wallet does not stop the
+	// cancellation
+	wallet.BlockedBalance -= deposit.BlockedAmount
+	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
+		return nil, err
+	}
 	trx := &core.Transaction{
+		Kind:          core.KindReversal,
 		WalletID:      deposit.UserID,
-		Amount:        deposit.Amount,
-		BlockedAmount: -deposit.Amount,
+		Currency:      deposit.Currency,
+		BlockedAmount: -deposit.BlockedAmount,
 		Reference:     deposit.ID,
-		Description:   deposit.Description,
+		Description:   "deposit cancelled",
 	}
-	if err := coreRepo.Transaction().Create(ctx, trx); err != nil {
-		return err
+	if err := core.Post(ctx, coreRepo, core.PSPReceivable, trx); err != nil {
+		return nil, err
 	}
-	deposit.ApplyTransactionID = trx.ID
+	for i := range deposit.Legs {
+		leg := &deposit.Legs[i]
+		if leg.Status != repository.Pending {
+			continue
+		}
+		leg.Status = repository.Cancelled
+		if err := depositRepo.UpdateLeg(ctx, leg); err != nil {
+			return nil, err
+		}
+	}
+	deposit.CancelTransactionID = trx.ID
+	deposit.BlockedAmount = 0
+	deposit.Status = repository.Cancelled
 	if err := depositRepo.Update(ctx, deposit); err != nil {
-		return err
+		return nil, err
 	}
-	return depositRepo.Commit()
+	return deposit, depositRepo.Commit()
+}
+
+func (s *service) Reschedule(ctx context.Context, id uuid.UUID, applyAt time.Time) (*Deposit, error) {
+	if !applyAt.After(time.Now()) {
+		return nil, ErrInvalidApplyAt
+	}
+	depositRepo := s.repoFactory.New(nil)
+	defer func() {
+		_ = depositRepo.RollBack()
+	}()
+	deposit, err := getPendingForUpdate(ctx, depositRepo, id)
+	if err != nil {
+		return nil, err
+	}
+	// legs are ordered by apply time, the first pending one is the next
+	var shift time.Duration
+	next := true
+	for i := range deposit.Legs {
+		leg := &deposit.Legs[i]
+		if leg.Status != repository.Pending {
+			continue
+		}
+		if next {
+			shift = applyAt.Round(0).Sub(leg.ApplyAt.Round(0))
+			leg.ApplyAt = applyAt
+			next = false
+		} else {
+			leg.ApplyAt = leg.ApplyAt.Add(shift)
+		}
+		if err := depositRepo.UpdateLeg(ctx, leg); err != nil {
+			return nil, err
+		}
+		deposit.ApplyAt = leg.ApplyAt
+	}
+	if err := depositRepo.Update(ctx, deposit); err != nil {
+		return nil, err
+	}
+	return deposit, depositRepo.Commit()
+}
+
+func (s *service) Retry(ctx context.Context, id uuid.UUID) (*Deposit, error) {
+	depositRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(depositRepo.GetDBTransaction())
+	defer func() {
+		_ = depositRepo.RollBack()
+	}()
+	deposit, err := depositRepo.GetForUpdate(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deposit.Status != repository.Failed {
+		return nil, ErrNotFailed
+	}
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, deposit.UserID, deposit.Currency)
+	if err != nil {
+		return nil, err
+	}
+	// retrying would only fail it again
+	if wallet.BlockedBalance < deposit.BlockedAmount {
+		return nil, ErrBlockedBalanceShort
+	}
+	deposit.Status = repository.Pending
+	if err := depositRepo.Update(ctx, deposit); err != nil {
+		return nil, err
+	}
+	return deposit, depositRepo.Commit()
+}
+
+// getPendingForUpdate locks a deposit that is still pending.
+func getPendingForUpdate(ctx context.Context, depositRepo repository.Repo, id uuid.UUID) (*Deposit, error) {
+	deposit, err := depositRepo.GetForUpdate(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch deposit.Status {
+	case repository.Applied:
+		return nil, ErrAlreadyApplied
+	case repository.Cancelled:
+		return nil, ErrAlreadyCancelled
+	case repository.Failed:
+		return nil, ErrFailed
+	}
+	return deposit, nil
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*Deposit, error) {
+	depositRepo := s.repoFactory.New(nil)
+	defer func() {
+		_ = depositRepo.RollBack()
+	}()
+	deposit, err := depositRepo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return deposit, err
+}
+
+func (s *service) List(ctx context.Context, filter repository.Filter, pageNumber int, pageSize int) ([]Deposit, bool, error) {
+	depositRepo := s.repoFactory.New(nil)
+	defer func() {
+		_ = depositRepo.RollBack()
+	}()
+	return depositRepo.List(ctx, filter, pageNumber, pageSize)
 }
 
 func (s *service) GetApplicableDeposits(ctx context.Context, IDPrefix string) ([]Deposit, error) {
-	return s.repoFactory.New(nil).GetApplicableDeposits(ctx, IDPrefix)
+	depositRepo := s.repoFactory.New(nil)
+	defer func() {
+		_ = depositRepo.RollBack()
+	}()
+	return depositRepo.GetApplicableDeposits(ctx, IDPrefix)
 }
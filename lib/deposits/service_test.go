@@ -3,9 +3,11 @@ package deposits_test
 import (
 	"context"
 	"testing"
+	"time"
 	"wallet/lib/core"
 	"wallet/lib/deposits"
 	"wallet/lib/deposits/repository"
+	"wallet/lib/idempotency"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -23,6 +25,21 @@ func (m *MockDepositRepo) Create(ctx context.Context, d *repository.Deposit) err
 func (m *MockDepositRepo) Update(ctx context.Context, d *repository.Deposit) error {
 	return m.Called(ctx, d).Error(0)
 }
+func (m *MockDepositRepo) UpdateLeg(ctx context.Context, l *repository.Leg) error {
+	return m.Called(ctx, l).Error(0)
+}
+func (m *MockDepositRepo) Get(ctx context.Context, id uuid.UUID) (*repository.Deposit, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*repository.Deposit), args.Error(1)
+}
+func (m *MockDepositRepo) List(ctx context.Context, filter repository.Filter, pageNumber int, pageSize int) ([]repository.Deposit, bool, error) {
+	args := m.Called(ctx, filter, pageNumber, pageSize)
+	return args.Get(0).([]repository.Deposit), args.Bool(1), args.Error(2)
+}
+func (m *MockDepositRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*repository.Deposit, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*repository.Deposit), args.Error(1)
+}
 func (m *MockDepositRepo) GetApplicableDeposits(ctx context.Context, prefix string) ([]repository.Deposit, error) {
 	args := m.Called(ctx, prefix)
 	return args.Get(0).([]repository.Deposit), args.Error(1)
@@ -40,27 +57,55 @@ func (m *MockDepositRepoFactory) New(tx *gorm.DB) repository.Repo {
 
 type MockWalletRepo struct{ mock.Mock }
 
-func (m *MockWalletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID) (*core.Wallet, error) {
-	args := m.Called(ctx, userID)
+func (m *MockWalletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
 	return args.Get(0).(*core.Wallet), args.Error(1)
 }
-func (m *MockWalletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID) (*core.Wallet, error) {
+func (m *MockWalletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).(*core.Wallet), args.Error(1)
 }
+func (m *MockWalletRepo) List(ctx context.Context, userID uuid.UUID) ([]core.Wallet, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]core.Wallet), args.Error(1)
+}
 func (m *MockWalletRepo) Update(ctx context.Context, wallet *core.Wallet) error {
 	return m.Called(ctx, wallet).Error(0)
 }
+func (m *MockWalletRepo) UpdateCreditLimit(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateStatus(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
 
 type MockTransactionRepo struct{ mock.Mock }
 
 func (m *MockTransactionRepo) Create(ctx context.Context, trx *core.Transaction) error {
 	return m.Called(ctx, trx).Error(0)
 }
-func (m *MockTransactionRepo) Get(ctx context.Context, userID uuid.UUID, pageNumber int, pageSize int) ([]core.Transaction, bool, error) {
-	args := m.Called(ctx, userID, pageNumber, pageSize)
+func (m *MockTransactionRepo) Get(ctx context.Context, filter core.TransactionFilter, pageNumber int, pageSize int) ([]core.Transaction, bool, error) {
+	args := m.Called(ctx, filter, pageNumber, pageSize)
 	return args.Get(0).([]core.Transaction), args.Bool(1), args.Error(2)
 }
+func (m *MockTransactionRepo) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency core.Currency, at time.Time) (*core.Balance, error) {
+	args := m.Called(ctx, userID, currency, at)
+	return args.Get(0).(*core.Balance), args.Error(1)
+}
+func (m *MockTransactionRepo) List(ctx context.Context, filter core.TransactionFilter, cursor uint64, limit int) ([]core.Transaction, uint64, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	return args.Get(0).([]core.Transaction), args.Get(1).(uint64), args.Error(2)
+}
+
+type MockLedgerRepo struct{ mock.Mock }
+
+func (m *MockLedgerRepo) Post(ctx context.Context, entries []core.LedgerEntry) error {
+	return m.Called(ctx, entries).Error(0)
+}
+func (m *MockLedgerRepo) GetBalance(ctx context.Context, account core.Account, currency core.Currency) (*core.AccountBalance, error) {
+	args := m.Called(ctx, account, currency)
+	return args.Get(0).(*core.AccountBalance), args.Error(1)
+}
 
 type MockCoreRepo struct{ mock.Mock }
 
@@ -70,6 +115,12 @@ func (m *MockCoreRepo) Wallet() core.WalletRepo {
 func (m *MockCoreRepo) Transaction() core.TransactionRepo {
 	return m.Called().Get(0).(core.TransactionRepo)
 }
+func (m *MockCoreRepo) Ledger() core.LedgerRepo {
+	return m.Called().Get(0).(core.LedgerRepo)
+}
+func (m *MockCoreRepo) CreditLimitChange() core.CreditLimitChangeRepo {
+	return m.Called().Get(0).(core.CreditLimitChangeRepo)
+}
 func (m *MockCoreRepo) GetDBTransaction() *gorm.DB { return nil }
 func (m *MockCoreRepo) Commit() error              { return m.Called().Error(0) }
 func (m *MockCoreRepo) RollBack() error            { return m.Called().Error(0) }
@@ -88,13 +139,16 @@ func TestService_Create(t *testing.T) {
 	deposit := &repository.Deposit{
 		ID:          uuid.New(),
 		UserID:      uuid.New(),
+		Currency:    core.IRR,
 		Amount:      100,
+		ApplyAt:     time.Now(),
 		Description: "test deposit",
 	}
 
 	depRepo := new(MockDepositRepo)
 	walletRepo := new(MockWalletRepo)
 	trxRepo := new(MockTransactionRepo)
+	ledgerRepo := new(MockLedgerRepo)
 	coreRepo := new(MockCoreRepo)
 	depRepoFactory := new(MockDepositRepoFactory)
 	coreRepoFactory := new(MockCoreRepoFactory)
@@ -107,24 +161,37 @@ func TestService_Create(t *testing.T) {
 	depRepoFactory.On("New", (*gorm.DB)(nil)).Return(depRepo)
 
 	// WalletRepo & CoreRepo mocks
-	wallet := &core.Wallet{UserID: deposit.UserID}
-	walletRepo.On("GetOrCreateForUpdate", ctx, deposit.UserID).Return(wallet, nil)
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR}
+	walletRepo.On("GetOrCreateForUpdate", ctx, deposit.UserID, core.IRR).Return(wallet, nil)
 	walletRepo.On("Update", ctx, wallet).Return(nil)
 
 	trxRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
 		trx := args.Get(1).(*core.Transaction)
 		trx.ID = 1 // assign fake ID so deposit.BlockTransactionID is set
 	}).Return(nil)
+	ledgerRepo.On("Post", ctx, mock.MatchedBy(func(entries []core.LedgerEntry) bool {
+		return len(entries) == 2 &&
+			entries[0].Account == core.WalletBlockedAccount(deposit.UserID) && entries[0].Credit == 100 &&
+			entries[1].Account == core.PSPReceivable && entries[1].Debit == 100 && entries[1].Currency == core.IRR
+	})).Return(nil)
 
 	coreRepo.On("Wallet").Return(walletRepo)
 	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
 	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
 
-	service := deposits.New(coreRepoFactory, depRepoFactory)
+	service := deposits.New(coreRepoFactory, depRepoFactory, nil)
 	err := service.Create(ctx, deposit)
 	assert.NoError(t, err)
 	assert.Equal(t, int64(100), wallet.BlockedBalance)
 	assert.NotZero(t, deposit.BlockTransactionID)
+	assert.Equal(t, repository.Pending, deposit.Status)
+	assert.Equal(t, int64(100), deposit.BlockedAmount)
+	assert.Equal(t, repository.SourceOther, deposit.SourceType)
+	// without a schedule the whole amount is released at once
+	assert.Len(t, deposit.Legs, 1)
+	assert.Equal(t, int64(100), deposit.Legs[0].Amount)
+	ledgerRepo.AssertExpectations(t)
 }
 
 func TestService_Apply(t *testing.T) {
@@ -132,44 +199,61 @@ func TestService_Apply(t *testing.T) {
 	deposit := &repository.Deposit{
 		ID:                 uuid.New(),
 		UserID:             uuid.New(),
+		Currency:           core.IRR,
 		Amount:             200,
+		BlockedAmount:      200,
+		Status:             repository.Pending,
 		Description:        "apply deposit",
 		BlockTransactionID: 1,
+		Legs:               []repository.Leg{{ID: uuid.New(), Amount: 200, ApplyAt: time.Now().Add(-time.Minute), Status: repository.Pending}},
 	}
 
 	depRepo := new(MockDepositRepo)
 	walletRepo := new(MockWalletRepo)
 	trxRepo := new(MockTransactionRepo)
+	ledgerRepo := new(MockLedgerRepo)
 	coreRepo := new(MockCoreRepo)
 	depRepoFactory := new(MockDepositRepoFactory)
 	coreRepoFactory := new(MockCoreRepoFactory)
 
 	// DepositRepo mocks
+	depRepo.On("GetForUpdate", ctx, deposit.ID).Return(deposit, nil)
 	depRepo.On("Update", ctx, deposit).Return(nil)
+	depRepo.On("UpdateLeg", ctx, mock.Anything).Return(nil)
 	depRepo.On("Commit").Return(nil)
 	depRepo.On("RollBack").Return(nil)
 	depRepoFactory.On("New", (*gorm.DB)(nil)).Return(depRepo)
 
 	// WalletRepo & CoreRepo mocks
-	wallet := &core.Wallet{UserID: deposit.UserID, BlockedBalance: 200}
-	walletRepo.On("GetOrCreateForUpdate", ctx, deposit.UserID).Return(wallet, nil)
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR, BlockedBalance: 200}
+	walletRepo.On("GetOrCreateForUpdate", ctx, deposit.UserID, core.IRR).Return(wallet, nil)
 	walletRepo.On("Update", ctx, wallet).Return(nil)
 
 	trxRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
 		trx := args.Get(1).(*core.Transaction)
-		trx.ID = 2 // assign fake ID so deposit.ApplyTransactionID is set
+		trx.ID = 2 // assign fake ID so the leg's ApplyTransactionID is set
 	}).Return(nil)
+	ledgerRepo.On("Post", ctx, mock.MatchedBy(func(entries []core.LedgerEntry) bool {
+		return len(entries) == 2 &&
+			entries[0].Account == core.WalletAvailableAccount(deposit.UserID) && entries[0].Credit == 200 &&
+			entries[1].Account == core.WalletBlockedAccount(deposit.UserID) && entries[1].Debit == 200
+	})).Return(nil)
 
 	coreRepo.On("Wallet").Return(walletRepo)
 	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
 	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
 
-	service := deposits.New(coreRepoFactory, depRepoFactory)
+	service := deposits.New(coreRepoFactory, depRepoFactory, nil)
 	err := service.Apply(ctx, deposit)
 	assert.NoError(t, err)
 	assert.Equal(t, int64(0), wallet.BlockedBalance)
 	assert.Equal(t, int64(200), wallet.AvailableBalance)
-	assert.NotZero(t, deposit.ApplyTransactionID)
+	assert.NotZero(t, deposit.Legs[0].ApplyTransactionID)
+	assert.Equal(t, repository.Applied, deposit.Legs[0].Status)
+	assert.Equal(t, int64(0), deposit.BlockedAmount)
+	assert.Equal(t, repository.Applied, deposit.Status)
+	ledgerRepo.AssertExpectations(t)
 }
 
 func TestService_GetApplicableDeposits(t *testing.T) {
@@ -182,10 +266,394 @@ func TestService_GetApplicableDeposits(t *testing.T) {
 	}
 
 	depRepo.On("GetApplicableDeposits", ctx, "test").Return(expected, nil)
+	depRepo.On("RollBack").Return(nil)
 	depRepoFactory.On("New", (*gorm.DB)(nil)).Return(depRepo)
 
-	service := deposits.New(nil, depRepoFactory)
+	service := deposits.New(nil, depRepoFactory, nil)
 	result, err := service.GetApplicableDeposits(ctx, "test")
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
+	// the listing transaction is not left open
+	depRepo.AssertCalled(t, "RollBack")
+}
+
+func setupPending(deposit *repository.Deposit, wallet *core.Wallet) (deposits.Service, *MockDepositRepo, *MockLedgerRepo) {
+	ctx := context.Background()
+	depRepo := new(MockDepositRepo)
+	walletRepo := new(MockWalletRepo)
+	trxRepo := new(MockTransactionRepo)
+	ledgerRepo := new(MockLedgerRepo)
+	coreRepo := new(MockCoreRepo)
+	depRepoFactory := new(MockDepositRepoFactory)
+	coreRepoFactory := new(MockCoreRepoFactory)
+
+	depRepo.On("GetForUpdate", ctx, deposit.ID).Return(deposit, nil)
+	depRepo.On("Update", ctx, deposit).Return(nil)
+	depRepo.On("UpdateLeg", ctx, mock.Anything).Return(nil)
+	depRepo.On("Commit").Return(nil)
+	depRepo.On("RollBack").Return(nil)
+	depRepoFactory.On("New", (*gorm.DB)(nil)).Return(depRepo)
+
+	walletRepo.On("GetOrCreateForUpdate", ctx, deposit.UserID, core.IRR).Return(wallet, nil)
+	walletRepo.On("Update", ctx, wallet).Return(nil)
+	trxRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*core.Transaction).ID = 3
+	}).Return(nil)
+	ledgerRepo.On("Post", ctx, mock.Anything).Return(nil)
+	coreRepo.On("Wallet").Return(walletRepo)
+	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
+	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
+
+	return deposits.New(coreRepoFactory, depRepoFactory, nil), depRepo, ledgerRepo
+}
+
+func TestService_Cancel(t *testing.T) {
+	deposit := &repository.Deposit{
+		ID:                 uuid.New(),
+		UserID:             uuid.New(),
+		Currency:           core.IRR,
+		Amount:             200,
+		BlockedAmount:      200,
+		ApplyAt:            time.Now().Add(time.Hour),
+		Status:             repository.Pending,
+		BlockTransactionID: 1,
+		Legs:               []repository.Leg{{ID: uuid.New(), Amount: 200, ApplyAt: time.Now().Add(time.Hour), Status: repository.Pending}},
+	}
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR, BlockedBalance: 200, Status: core.WalletFullyFrozen}
+	service, depRepo, ledgerRepo := setupPending(deposit, wallet)
+
+	cancelled, err := service.Cancel(context.Background(), deposit.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), cancelled.CancelTransactionID)
+	assert.Equal(t, repository.Cancelled, cancelled.Status)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+	assert.Equal(t, int64(0), wallet.AvailableBalance)
+	depRepo.AssertCalled(t, "Commit")
+	ledgerRepo.AssertCalled(t, "Post", context.Background(), mock.MatchedBy(func(entries []core.LedgerEntry) bool {
+		return len(entries) == 2 &&
+			entries[0].Account == core.WalletBlockedAccount(deposit.UserID) && entries[0].Debit == 200 &&
+			entries[1].Account == core.PSPReceivable && entries[1].Credit == 200
+	}))
+
+	_, err = service.Cancel(context.Background(), deposit.ID)
+	assert.ErrorIs(t, err, deposits.ErrAlreadyCancelled)
+	_, err = service.Reschedule(context.Background(), deposit.ID, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, deposits.ErrAlreadyCancelled)
+	assert.ErrorIs(t, service.Apply(context.Background(), deposit), deposits.ErrAlreadyCancelled)
+}
+
+func TestService_Reschedule(t *testing.T) {
+	deposit := &repository.Deposit{
+		ID:       uuid.New(),
+		UserID:   uuid.New(),
+		Currency: core.IRR,
+		Amount:   200,
+		ApplyAt:  time.Now().Add(-time.Minute),
+		Status:   repository.Pending,
+		Legs:     []repository.Leg{{ID: uuid.New(), Amount: 200, ApplyAt: time.Now().Add(-time.Minute), Status: repository.Pending}},
+	}
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR, BlockedBalance: 200}
+	service, _, _ := setupPending(deposit, wallet)
+
+	_, err := service.Reschedule(context.Background(), deposit.ID, time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, deposits.ErrInvalidApplyAt)
+
+	applyAt := time.Now().Add(24 * time.Hour)
+	rescheduled, err := service.Reschedule(context.Background(), deposit.ID, applyAt)
+	assert.NoError(t, err)
+	assert.Equal(t, applyAt, rescheduled.ApplyAt)
+	assert.Equal(t, applyAt, rescheduled.Legs[0].ApplyAt)
+
+	// the applier may have listed it before the reschedule
+	assert.ErrorIs(t, service.Apply(context.Background(), deposit), deposits.ErrNotDue)
+	assert.Equal(t, int64(200), wallet.BlockedBalance)
+}
+
+func TestService_CancelApplied(t *testing.T) {
+	deposit := &repository.Deposit{ID: uuid.New(), UserID: uuid.New(), Currency: core.IRR, Amount: 200, Status: repository.Applied, ApplyTransactionID: 2}
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR, AvailableBalance: 200}
+	service, _, _ := setupPending(deposit, wallet)
+
+	_, err := service.Cancel(context.Background(), deposit.ID)
+	assert.ErrorIs(t, err, deposits.ErrAlreadyApplied)
+	_, err = service.Reschedule(context.Background(), deposit.ID, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, deposits.ErrAlreadyApplied)
+	assert.Equal(t, int64(200), wallet.AvailableBalance)
+}
+
+func TestService_CancelPartlyApplied(t *testing.T) {
+	deposit := &repository.Deposit{
+		ID:            uuid.New(),
+		UserID:        uuid.New(),
+		Currency:      core.IRR,
+		Amount:        200,
+		BlockedAmount: 80,
+		Status:        repository.Pending,
+		Legs: []repository.Leg{
+			{ID: uuid.New(), Amount: 120, ApplyAt: time.Now().Add(-time.Hour), Status: repository.Applied, ApplyTransactionID: 2},
+			{ID: uuid.New(), Amount: 80, ApplyAt: time.Now().Add(time.Hour), Status: repository.Pending},
+		},
+	}
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR, AvailableBalance: 120, BlockedBalance: 80}
+	service, depRepo, ledgerRepo := setupPending(deposit, wallet)
+
+	_, err := service.Cancel(context.Background(), deposit.ID)
+	assert.ErrorIs(t, err, deposits.ErrAlreadyApplied)
+	assert.Equal(t, repository.Pending, deposit.Status)
+	assert.Equal(t, int64(80), wallet.BlockedBalance)
+	depRepo.AssertNotCalled(t, "Commit")
+	ledgerRepo.AssertNotCalled(t, "Post", mock.Anything, mock.Anything)
+
+	// the rest can still be moved
+	rescheduled, err := service.Reschedule(context.Background(), deposit.ID, time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, repository.Applied, rescheduled.Legs[0].Status)
+}
+
+func TestService_ApplyFailsWithoutBlockedFunds(t *testing.T) {
+	deposit := &repository.Deposit{
+		ID:            uuid.New(),
+		UserID:        uuid.New(),
+		Currency:      core.IRR,
+		Amount:        200,
+		BlockedAmount: 200,
+		Status:        repository.Pending,
+		Legs:          []repository.Leg{{ID: uuid.New(), Amount: 200, ApplyAt: time.Now().Add(-time.Minute), Status: repository.Pending}},
+	}
+	// an approved adjustment took part of the blocked balance
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR, BlockedBalance: 150}
+	service, depRepo, ledgerRepo := setupPending(deposit, wallet)
+
+	assert.ErrorIs(t, service.Apply(context.Background(), deposit), deposits.ErrBlockedBalanceShort)
+	assert.Equal(t, repository.Failed, deposit.Status)
+	assert.Equal(t, int64(150), wallet.BlockedBalance)
+	depRepo.AssertCalled(t, "Commit")
+	ledgerRepo.AssertNotCalled(t, "Post", mock.Anything, mock.Anything)
+
+	// failed deposits wait for an operator
+	_, err := service.Cancel(context.Background(), deposit.ID)
+	assert.ErrorIs(t, err, deposits.ErrFailed)
+}
+
+func TestService_Retry(t *testing.T) {
+	deposit := &repository.Deposit{
+		ID:            uuid.New(),
+		UserID:        uuid.New(),
+		Currency:      core.IRR,
+		Amount:        200,
+		BlockedAmount: 200,
+		Status:        repository.Failed,
+		Legs:          []repository.Leg{{ID: uuid.New(), Amount: 200, ApplyAt: time.Now().Add(-time.Minute), Status: repository.Pending}},
+	}
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR, BlockedBalance: 150}
+	service, depRepo, _ := setupPending(deposit, wallet)
+
+	// the blocked balance is still short, the deposit stays failed
+	_, err := service.Retry(context.Background(), deposit.ID)
+	assert.ErrorIs(t, err, deposits.ErrBlockedBalanceShort)
+	assert.Equal(t, repository.Failed, deposit.Status)
+	depRepo.AssertNotCalled(t, "Commit")
+
+	// an operator restored it
+	wallet.BlockedBalance = 200
+	retried, err := service.Retry(context.Background(), deposit.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, repository.Pending, retried.Status)
+	depRepo.AssertCalled(t, "Commit")
+
+	assert.NoError(t, service.Apply(context.Background(), deposit))
+	assert.Equal(t, repository.Applied, deposit.Status)
+	assert.Equal(t, int64(200), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+
+	_, err = service.Retry(context.Background(), deposit.ID)
+	assert.ErrorIs(t, err, deposits.ErrNotFailed)
+}
+
+func TestService_CreateRejectsBadSchedule(t *testing.T) {
+	deposit := &repository.Deposit{
+		ID:       uuid.New(),
+		UserID:   uuid.New(),
+		Currency: core.IRR,
+		Amount:   200,
+		Legs: []repository.Leg{
+			{Amount: 100, ApplyAt: time.Now()},
+			{Amount: 50, ApplyAt: time.Now().Add(time.Hour)},
+		},
+	}
+	service, depRepo, _ := setupPending(deposit, &core.Wallet{UserID: deposit.UserID, Currency: core.IRR})
+
+	assert.ErrorIs(t, service.Create(context.Background(), deposit), deposits.ErrInvalidSchedule)
+	depRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_ApplyLegs(t *testing.T) {
+	deposit := &repository.Deposit{
+		ID:            uuid.New(),
+		UserID:        uuid.New(),
+		Currency:      core.IRR,
+		Amount:        300,
+		BlockedAmount: 300,
+		ApplyAt:       time.Now().Add(24 * time.Hour),
+		Status:        repository.Pending,
+		Legs: []repository.Leg{
+			{ID: uuid.New(), Amount: 100, ApplyAt: time.Now().Add(-time.Hour), Status: repository.Pending},
+			{ID: uuid.New(), Amount: 50, ApplyAt: time.Now().Add(-time.Minute), Status: repository.Pending},
+			{ID: uuid.New(), Amount: 150, ApplyAt: time.Now().Add(24 * time.Hour), Status: repository.Pending},
+		},
+	}
+	wallet := &core.Wallet{UserID: deposit.UserID, Currency: core.IRR, BlockedBalance: 300}
+	service, depRepo, ledgerRepo := setupPending(deposit, wallet)
+
+	// each due leg is released with its own transaction
+	assert.NoError(t, service.Apply(context.Background(), deposit))
+	assert.Equal(t, int64(150), wallet.AvailableBalance)
+	assert.Equal(t, int64(150), wallet.BlockedBalance)
+	assert.Equal(t, int64(150), deposit.BlockedAmount)
+	assert.Equal(t, repository.Pending, deposit.Status)
+	assert.Equal(t, repository.Applied, deposit.Legs[0].Status)
+	assert.Equal(t, repository.Applied, deposit.Legs[1].Status)
+	assert.Equal(t, repository.Pending, deposit.Legs[2].Status)
+	ledgerRepo.AssertNumberOfCalls(t, "Post", 2)
+	depRepo.AssertNumberOfCalls(t, "UpdateLeg", 2)
+
+	assert.ErrorIs(t, service.Apply(context.Background(), deposit), deposits.ErrNotDue)
+
+	// part of the funds reached the owner, the deposit can no longer be cancelled
+	_, err := service.Cancel(context.Background(), deposit.ID)
+	assert.ErrorIs(t, err, deposits.ErrAlreadyApplied)
+	assert.Equal(t, int64(150), wallet.BlockedBalance)
+	ledgerRepo.AssertNumberOfCalls(t, "Post", 2)
+}
+
+func TestService_CreateDuplicateReference(t *testing.T) {
+	ctx := context.Background()
+	reference := "psp-payment-1"
+	deposit := &repository.Deposit{
+		ID:                uuid.New(),
+		UserID:            uuid.New(),
+		Currency:          core.IRR,
+		Amount:            100,
+		ApplyAt:           time.Now(),
+		SourceType:        repository.SourcePSP,
+		ExternalReference: &reference,
+	}
+
+	depRepo := new(MockDepositRepo)
+	coreRepo := new(MockCoreRepo)
+	depRepoFactory := new(MockDepositRepoFactory)
+	coreRepoFactory := new(MockCoreRepoFactory)
+	depRepo.On("Create", ctx, deposit).Return(gorm.ErrDuplicatedKey)
+	depRepo.On("RollBack").Return(nil)
+	depRepoFactory.On("New", (*gorm.DB)(nil)).Return(depRepo)
+	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
+
+	service := deposits.New(coreRepoFactory, depRepoFactory, nil)
+	// the first callback already blocked the funds, nothing is posted again
+	assert.ErrorIs(t, service.Create(ctx, deposit), deposits.ErrDuplicateReference)
+	depRepo.AssertNotCalled(t, "Commit")
+	coreRepo.AssertNotCalled(t, "Wallet")
+}
+
+// keyStore is an idempotency repo keeping its keys in memory.
+type keyStore struct {
+	records map[string]idempotency.Record
+}
+
+func (s *keyStore) Reserve(ctx context.Context, scope, key, fingerprint string) (*idempotency.Record, error) {
+	record, ok := s.records[scope+key]
+	if !ok {
+		record = idempotency.Record{Scope: scope, Key: key, Fingerprint: fingerprint}
+		s.records[scope+key] = record
+	}
+	return &record, nil
+}
+func (s *keyStore) Update(ctx context.Context, record *idempotency.Record) error {
+	s.records[record.Scope+record.Key] = *record
+	return nil
+}
+func (s *keyStore) GetDBTransaction() *gorm.DB { return nil }
+func (s *keyStore) Commit() error              { return nil }
+func (s *keyStore) RollBack() error            { return nil }
+
+type keyStoreFactory struct{ store *keyStore }
+
+func (f *keyStoreFactory) New(tx *gorm.DB) idempotency.Repo { return f.store }
+
+func setupIdempotent(userID uuid.UUID) (deposits.Service, *MockDepositRepo, *core.Wallet) {
+	ctx := context.Background()
+	depRepo := new(MockDepositRepo)
+	depRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*repository.Deposit).ID = uuid.New()
+	}).Return(nil)
+	depRepo.On("Update", ctx, mock.Anything).Return(nil)
+	depRepo.On("Commit").Return(nil)
+	depRepo.On("RollBack").Return(nil)
+	depRepoFactory := new(MockDepositRepoFactory)
+	depRepoFactory.On("New", (*gorm.DB)(nil)).Return(depRepo)
+
+	wallet := &core.Wallet{UserID: userID, Currency: core.IRR}
+	walletRepo := new(MockWalletRepo)
+	walletRepo.On("GetOrCreateForUpdate", ctx, userID, core.IRR).Return(wallet, nil)
+	walletRepo.On("Update", ctx, wallet).Return(nil)
+	trxRepo := new(MockTransactionRepo)
+	trxRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*core.Transaction).ID = 1
+	}).Return(nil)
+	ledgerRepo := new(MockLedgerRepo)
+	ledgerRepo.On("Post", ctx, mock.Anything).Return(nil)
+	coreRepo := new(MockCoreRepo)
+	coreRepo.On("Wallet").Return(walletRepo)
+	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
+	coreRepoFactory := new(MockCoreRepoFactory)
+	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
+
+	keys := &keyStoreFactory{store: &keyStore{records: map[string]idempotency.Record{}}}
+	return deposits.New(coreRepoFactory, depRepoFactory, keys), depRepo, wallet
+}
+
+func TestService_CreateIdempotentReplay(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	service, depRepo, wallet := setupIdempotent(userID)
+	key := idempotency.Key{Scope: "deposit", Value: "retry-1", Fingerprint: idempotency.Fingerprint([]byte(`{"amount":100}`))}
+
+	first := &repository.Deposit{UserID: userID, Currency: core.IRR, Amount: 100, ApplyAt: time.Now()}
+	replayed, err := service.CreateIdempotent(ctx, first, key)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	// the retry gets the first deposit back and blocks nothing more
+	retry := &repository.Deposit{UserID: userID, Currency: core.IRR, Amount: 100, ApplyAt: time.Now()}
+	replayed, err = service.CreateIdempotent(ctx, retry, key)
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ID, retry.ID)
+	assert.Equal(t, first.BlockTransactionID, retry.BlockTransactionID)
+	assert.Equal(t, int64(100), wallet.BlockedBalance)
+	depRepo.AssertNumberOfCalls(t, "Create", 1)
+	depRepo.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestService_CreateIdempotentKeyReused(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	service, depRepo, wallet := setupIdempotent(userID)
+
+	first := &repository.Deposit{UserID: userID, Currency: core.IRR, Amount: 100, ApplyAt: time.Now()}
+	key := idempotency.Key{Scope: "deposit", Value: "retry-1", Fingerprint: idempotency.Fingerprint([]byte(`{"amount":100}`))}
+	_, err := service.CreateIdempotent(ctx, first, key)
+	assert.NoError(t, err)
+
+	other := &repository.Deposit{UserID: userID, Currency: core.IRR, Amount: 500, ApplyAt: time.Now()}
+	key.Fingerprint = idempotency.Fingerprint([]byte(`{"amount":500}`))
+	replayed, err := service.CreateIdempotent(ctx, other, key)
+	assert.ErrorIs(t, err, idempotency.ErrKeyReused)
+	assert.False(t, replayed)
+	assert.Equal(t, uuid.Nil, other.ID)
+	assert.Equal(t, int64(100), wallet.BlockedBalance)
+	depRepo.AssertNumberOfCalls(t, "Create", 1)
+	depRepo.AssertNumberOfCalls(t, "Commit", 1)
 }
@@ -0,0 +1,12 @@
+package exchange
+
+import "errors"
+
+var ErrInsufficientBalance = errors.New("insufficient balance")
+var ErrInvalidAmount = errors.New("amount must be positive")
+var ErrAmountTooSmall = errors.New("converted amount is zero")
+var ErrSameCurrency = errors.New("cant exchange a currency to itself")
+var ErrRateUnavailable = errors.New("no rate for currency pair")
+var ErrQuoteNotFound = errors.New("quote not found")
+var ErrQuoteExpired = errors.New("quote is expired")
+var ErrQuoteExecuted = errors.New("quote is already executed")
@@ -0,0 +1,89 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"wallet/lib/core"
+)
+
+// RateProvider returns the mid-market rate of a currency pair: how many major
+// units of to one major unit of from is worth.
+type RateProvider interface {
+	GetRate(ctx context.Context, from, to core.Currency) (*big.Rat, error)
+}
+
+// PairKey formats a currency pair as used in config, e.g. "USD/IRR".
+func PairKey(from, to core.Currency) string {
+	return fmt.Sprintf("%s/%s", from, to)
+}
+
+type staticRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticRateProvider builds a RateProvider from fixed rates keyed by pair
+// (e.g. "USD/IRR": "600000"), meant for local testing. The inverse of every
+// configured pair is available as well.
+func NewStaticRateProvider(rates map[string]string) (RateProvider, error) {
+	p := &staticRateProvider{rates: make(map[string]*big.Rat, 2*len(rates))}
+	for pair, value := range rates {
+		from, to, err := parsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for pair %s", value, pair)
+		}
+		p.rates[PairKey(from, to)] = rate
+		if _, ok := rates[PairKey(to, from)]; !ok {
+			p.rates[PairKey(to, from)] = new(big.Rat).Inv(rate)
+		}
+	}
+	return p, nil
+}
+
+func (p *staticRateProvider) GetRate(ctx context.Context, from, to core.Currency) (*big.Rat, error) {
+	rate, ok := p.rates[PairKey(from, to)]
+	if !ok {
+		return nil, ErrRateUnavailable
+	}
+	return new(big.Rat).Set(rate), nil
+}
+
+// parsePair parses a config pair key; keys may be lower-cased by the config loader.
+func parsePair(pair string) (core.Currency, core.Currency, error) {
+	parts := strings.Split(pair, "/")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid currency pair %q", pair)
+	}
+	from, err := core.ParseCurrency(parts[0])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid currency pair %q: %w", pair, err)
+	}
+	to, err := core.ParseCurrency(parts[1])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid currency pair %q: %w", pair, err)
+	}
+	return from, to, nil
+}
+
+// applySpread lowers rate by spreadBps basis points.
+func applySpread(rate *big.Rat, spreadBps int64) *big.Rat {
+	return new(big.Rat).Mul(rate, big.NewRat(10000-spreadBps, 10000))
+}
+
+// convert converts amount minor units of from into minor units of to, rounding down.
+func convert(amount int64, from, to core.Currency, rate *big.Rat) int64 {
+	r := new(big.Rat).SetInt64(amount)
+	r.Mul(r, rate)
+	r.Mul(r, new(big.Rat).SetInt(pow10(to.MinorUnits())))
+	r.Quo(r, new(big.Rat).SetInt(pow10(from.MinorUnits())))
+	return new(big.Int).Quo(r.Num(), r.Denom()).Int64()
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,45 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+	"wallet/lib/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	provider, err := NewStaticRateProvider(map[string]string{"usd/irr": "600000"})
+	assert.NoError(t, err)
+
+	rate, err := provider.GetRate(context.Background(), core.USD, core.IRR)
+	assert.NoError(t, err)
+	assert.Equal(t, "600000", rate.RatString())
+
+	inverse, err := provider.GetRate(context.Background(), core.IRR, core.USD)
+	assert.NoError(t, err)
+	assert.Equal(t, "1/600000", inverse.RatString())
+
+	_, err = provider.GetRate(context.Background(), core.EUR, core.USD)
+	assert.ErrorIs(t, err, ErrRateUnavailable)
+
+	_, err = NewStaticRateProvider(map[string]string{"USD-IRR": "1"})
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	provider, _ := NewStaticRateProvider(map[string]string{"USD/IRR": "600000", "EUR/USD": "1.08"})
+	ctx := context.Background()
+
+	// 12.34 USD -> IRR, 1% spread
+	rate, _ := provider.GetRate(ctx, core.USD, core.IRR)
+	assert.Equal(t, int64(7329960), convert(1234, core.USD, core.IRR, applySpread(rate, 100)))
+
+	// 1,000,000 IRR -> USD, rounded down to cents
+	rate, _ = provider.GetRate(ctx, core.IRR, core.USD)
+	assert.Equal(t, int64(166), convert(1000000, core.IRR, core.USD, rate))
+
+	// 10.00 EUR -> USD
+	rate, _ = provider.GetRate(ctx, core.EUR, core.USD)
+	assert.Equal(t, int64(1080), convert(1000, core.EUR, core.USD, rate))
+}
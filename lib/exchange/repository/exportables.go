@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"wallet/lib/exchange/repository/internal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Quote = internal.Quote
+
+type Repo interface {
+	Create(context.Context, *Quote) error
+	Update(context.Context, *Quote) error
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*Quote, error)
+
+	GetDBTransaction() *gorm.DB
+	Commit() error
+	RollBack() error
+}
+
+type RepoFactory interface {
+	New(tx *gorm.DB) Repo
+}
+
+func NewFactory(db *gorm.DB) RepoFactory {
+	return &repoFactory{
+		db: db,
+	}
+}
+
+type repoFactory struct {
+	db *gorm.DB
+}
+
+func (rf *repoFactory) New(tx *gorm.DB) Repo {
+	if tx == nil {
+		tx = rf.db.Begin()
+	}
+	return internal.NewQuoteRepo(tx)
+}
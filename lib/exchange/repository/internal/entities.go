@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"time"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+)
+
+// Quote locks an exchange rate for a user until ExpiresAt.
+type Quote struct {
+	ID                  uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID              uuid.UUID     `gorm:"type:uuid;index" json:"user_id"`
+	FromCurrency        core.Currency `gorm:"type:varchar(3);not null" json:"from_currency"`
+	ToCurrency          core.Currency `gorm:"type:varchar(3);not null" json:"to_currency"`
+	FromAmount          int64         `gorm:"not null" json:"from_amount"`
+	ToAmount            int64         `gorm:"not null" json:"to_amount"`
+	Rate                string        `gorm:"type:numeric(36,18);not null" json:"rate"`
+	SpreadBps           int64         `gorm:"not null" json:"spread_bps"`
+	CreatedAt           time.Time     `json:"created_at"`
+	ExpiresAt           time.Time     `gorm:"index" json:"expires_at"`
+	ExecutedAt          *time.Time    `json:"executed_at,omitempty"`
+	DebitTransactionID  uint64        `gorm:"index" json:"debit_transaction_id"`
+	CreditTransactionID uint64        `gorm:"index" json:"credit_transaction_id"`
+}
+
+func (Quote) TableName() string {
+	return "fx_quotes"
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type quoteRepo struct {
+	tx *gorm.DB
+}
+
+func NewQuoteRepo(tx *gorm.DB) *quoteRepo {
+	return &quoteRepo{tx: tx}
+}
+
+// Create inserts a new quote and fills in ID automatically.
+func (r *quoteRepo) Create(ctx context.Context, quote *Quote) error {
+	if quote.ID == uuid.Nil {
+		quote.ID = uuid.New()
+	}
+	return r.tx.WithContext(ctx).Create(quote).Error
+}
+
+// Update updates an existing quote by ID.
+func (r *quoteRepo) Update(ctx context.Context, quote *Quote) error {
+	return r.tx.WithContext(ctx).
+		Model(&Quote{}).
+		Where("id = ?", quote.ID).
+		Updates(quote).Error
+}
+
+// GetForUpdate fetches a quote by ID with row-level locking.
+func (r *quoteRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*Quote, error) {
+	var quote Quote
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&quote, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// GetDBTransaction returns the underlying gorm.DB (transaction).
+func (r *quoteRepo) GetDBTransaction() *gorm.DB {
+	return r.tx
+}
+
+// Commit commits the transaction.
+func (r *quoteRepo) Commit() error {
+	return r.tx.Commit().Error
+}
+
+// RollBack rolls back the transaction.
+func (r *quoteRepo) RollBack() error {
+	return r.tx.Rollback().Error
+}
@@ -0,0 +1,206 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/exchange/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Quote = repository.Quote
+
+type Config struct {
+	QuoteLifetime    time.Duration
+	DefaultSpreadBps int64
+	SpreadsBps       map[string]int64  // per pair, e.g. "USD/IRR": 50
+	Rates            map[string]string // static rates, e.g. "USD/IRR": "600000"
+}
+
+func (c *Config) FillDefaults() {
+	if c.QuoteLifetime == 0 {
+		c.QuoteLifetime = 30 * time.Second
+	}
+}
+
+type Service interface {
+	// RequestQuote prices converting amount minor units of from into to and
+	// locks the rate for the configured quote lifetime.
+	RequestQuote(ctx context.Context, userID uuid.UUID, from, to core.Currency, amount int64) (*Quote, error)
+	// Execute debits the from wallet and credits the to wallet of the quote owner.
+	Execute(ctx context.Context, userID uuid.UUID, quoteID uuid.UUID) (*Quote, error)
+}
+
+func New(
+	coreRepoFactory core.RepoFactory,
+	repoFactory repository.RepoFactory,
+	rateProvider RateProvider,
+	config Config,
+) Service {
+	spreads := make(map[string]int64, len(config.SpreadsBps))
+	for pair, bps := range config.SpreadsBps {
+		if from, to, err := parsePair(pair); err == nil {
+			spreads[PairKey(from, to)] = bps
+		}
+	}
+	return &service{
+		coreRepoFactory:  coreRepoFactory,
+		repoFactory:      repoFactory,
+		rateProvider:     rateProvider,
+		quoteLifetime:    config.QuoteLifetime,
+		defaultSpreadBps: config.DefaultSpreadBps,
+		spreadsBps:       spreads,
+	}
+}
+
+type service struct {
+	coreRepoFactory  core.RepoFactory
+	repoFactory      repository.RepoFactory
+	rateProvider     RateProvider
+	quoteLifetime    time.Duration
+	defaultSpreadBps int64
+	spreadsBps       map[string]int64
+}
+
+func (s *service) spreadBps(from, to core.Currency) int64 {
+	if bps, ok := s.spreadsBps[PairKey(from, to)]; ok {
+		return bps
+	}
+	return s.defaultSpreadBps
+}
+
+func (s *service) RequestQuote(ctx context.Context, userID uuid.UUID, from, to core.Currency, amount int64) (*Quote, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if from == to {
+		return nil, ErrSameCurrency
+	}
+	mid, err := s.rateProvider.GetRate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	spread := s.spreadBps(from, to)
+	rate := applySpread(mid, spread)
+	toAmount := convert(amount, from, to, rate)
+	if toAmount <= 0 {
+		return nil, ErrAmountTooSmall
+	}
+	now := time.Now()
+	quote := &Quote{
+		UserID:       userID,
+		FromCurrency: from,
+		ToCurrency:   to,
+		FromAmount:   amount,
+		ToAmount:     toAmount,
+		Rate:         rate.FloatString(18),
+		SpreadBps:    spread,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.quoteLifetime),
+	}
+	quoteRepo := s.repoFactory.New(nil)
+	defer func() {
+		_ = quoteRepo.RollBack()
+	}()
+	if err := quoteRepo.Create(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, quoteRepo.Commit()
+}
+
+func (s *service) Execute(ctx context.Context, userID uuid.UUID, quoteID uuid.UUID) (*Quote, error) {
+	quoteRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(quoteRepo.GetDBTransaction())
+	defer func() {
+		_ = quoteRepo.RollBack()
+	}()
+	quote, err := quoteRepo.GetForUpdate(ctx, quoteID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrQuoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if quote.UserID != userID {
+		return nil, ErrQuoteNotFound
+	}
+	if quote.ExecutedAt != nil {
+		return nil, ErrQuoteExecuted
+	}
+	now := time.Now()
+	if now.After(quote.ExpiresAt) {
+		return nil, ErrQuoteExpired
+	}
+	source, target, err := lockWallets(ctx, coreRepo, userID, quote.FromCurrency, quote.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if err := source.CanDebit(); err != nil {
+		return nil, err
+	}
+	if err := target.CanCredit(); err != nil {
+		return nil, err
+	}
+	if source.Spendable() < quote.FromAmount {
+		return nil, ErrInsufficientBalance
+	}
+	source.AvailableBalance -= quote.FromAmount
+	target.AvailableBalance += quote.ToAmount
+	if err := coreRepo.Wallet().Update(ctx, source); err != nil {
+		return nil, err
+	}
+	if err := coreRepo.Wallet().Update(ctx, target); err != nil {
+		return nil, err
+	}
+	description := fmt.Sprintf("exchange %s to %s at %s", quote.FromCurrency, quote.ToCurrency, quote.Rate)
+	debitTrx := &core.Transaction{
+		Kind:        core.KindExchange,
+		WalletID:    userID,
+		Currency:    quote.FromCurrency,
+		Amount:      -quote.FromAmount,
+		Reference:   quote.ID,
+		Description: description,
+	}
+	creditTrx := &core.Transaction{
+		Kind:        core.KindExchange,
+		WalletID:    userID,
+		Currency:    quote.ToCurrency,
+		Amount:      quote.ToAmount,
+		Reference:   quote.ID,
+		Description: description,
+	}
+	if err := core.Post(ctx, coreRepo, core.FXPosition, debitTrx, creditTrx); err != nil {
+		return nil, err
+	}
+	quote.ExecutedAt = &now
+	quote.DebitTransactionID = debitTrx.ID
+	quote.CreditTransactionID = creditTrx.ID
+	if err := quoteRepo.Update(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, quoteRepo.Commit()
+}
+
+// lockWallets locks both currency wallets of the user in a deterministic order.
+func lockWallets(ctx context.Context, coreRepo core.Repo, userID uuid.UUID, from, to core.Currency) (*core.Wallet, *core.Wallet, error) {
+	first, second := from, to
+	if first > second {
+		first, second = second, first
+	}
+	firstWallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, userID, first)
+	if err != nil {
+		return nil, nil, err
+	}
+	secondWallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, userID, second)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first == from {
+		return firstWallet, secondWallet, nil
+	}
+	return secondWallet, firstWallet, nil
+}
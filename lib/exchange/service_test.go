@@ -0,0 +1,255 @@
+package exchange_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/exchange"
+	"wallet/lib/exchange/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// --- Mocks ---
+
+type MockQuoteRepo struct{ mock.Mock }
+
+func (m *MockQuoteRepo) Create(ctx context.Context, q *repository.Quote) error {
+	return m.Called(ctx, q).Error(0)
+}
+func (m *MockQuoteRepo) Update(ctx context.Context, q *repository.Quote) error {
+	return m.Called(ctx, q).Error(0)
+}
+func (m *MockQuoteRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*repository.Quote, error) {
+	args := m.Called(ctx, id)
+	quote, _ := args.Get(0).(*repository.Quote)
+	return quote, args.Error(1)
+}
+func (m *MockQuoteRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockQuoteRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockQuoteRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockQuoteRepoFactory struct{ mock.Mock }
+
+func (m *MockQuoteRepoFactory) New(tx *gorm.DB) repository.Repo {
+	return m.Called(tx).Get(0).(repository.Repo)
+}
+
+type MockWalletRepo struct{ mock.Mock }
+
+func (m *MockWalletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) List(ctx context.Context, userID uuid.UUID) ([]core.Wallet, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) Update(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateCreditLimit(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateStatus(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+
+type MockTransactionRepo struct {
+	mock.Mock
+	nextID uint64
+}
+
+func (m *MockTransactionRepo) Create(ctx context.Context, trx *core.Transaction) error {
+	m.nextID++
+	trx.ID = m.nextID
+	return m.Called(ctx, trx).Error(0)
+}
+func (m *MockTransactionRepo) Get(ctx context.Context, filter core.TransactionFilter, pageNumber int, pageSize int) ([]core.Transaction, bool, error) {
+	args := m.Called(ctx, filter, pageNumber, pageSize)
+	return args.Get(0).([]core.Transaction), args.Bool(1), args.Error(2)
+}
+func (m *MockTransactionRepo) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency core.Currency, at time.Time) (*core.Balance, error) {
+	args := m.Called(ctx, userID, currency, at)
+	return args.Get(0).(*core.Balance), args.Error(1)
+}
+func (m *MockTransactionRepo) List(ctx context.Context, filter core.TransactionFilter, cursor uint64, limit int) ([]core.Transaction, uint64, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	return args.Get(0).([]core.Transaction), args.Get(1).(uint64), args.Error(2)
+}
+
+type MockLedgerRepo struct{ mock.Mock }
+
+func (m *MockLedgerRepo) Post(ctx context.Context, entries []core.LedgerEntry) error {
+	return m.Called(ctx, entries).Error(0)
+}
+func (m *MockLedgerRepo) GetBalance(ctx context.Context, account core.Account, currency core.Currency) (*core.AccountBalance, error) {
+	args := m.Called(ctx, account, currency)
+	return args.Get(0).(*core.AccountBalance), args.Error(1)
+}
+
+type MockCoreRepo struct{ mock.Mock }
+
+func (m *MockCoreRepo) Wallet() core.WalletRepo {
+	return m.Called().Get(0).(core.WalletRepo)
+}
+func (m *MockCoreRepo) Transaction() core.TransactionRepo {
+	return m.Called().Get(0).(core.TransactionRepo)
+}
+func (m *MockCoreRepo) Ledger() core.LedgerRepo {
+	return m.Called().Get(0).(core.LedgerRepo)
+}
+func (m *MockCoreRepo) CreditLimitChange() core.CreditLimitChangeRepo {
+	return m.Called().Get(0).(core.CreditLimitChangeRepo)
+}
+func (m *MockCoreRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockCoreRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockCoreRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockCoreRepoFactory struct{ mock.Mock }
+
+func (m *MockCoreRepoFactory) New(tx *gorm.DB) core.Repo {
+	return m.Called(tx).Get(0).(core.Repo)
+}
+
+// --- Helpers ---
+
+// setup serves quote to Execute, with the USD and IRR wallets of its owner.
+func setup(quote *repository.Quote, usd, irr *core.Wallet) (exchange.Service, *MockQuoteRepo, *MockWalletRepo) {
+	ctx := context.Background()
+	quoteRepo := &MockQuoteRepo{}
+	quoteRepo.On("GetForUpdate", ctx, quote.ID).Return(quote, nil)
+	quoteRepo.On("GetForUpdate", ctx, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	quoteRepo.On("Update", ctx, mock.Anything).Return(nil)
+	quoteRepo.On("Commit").Return(nil)
+	quoteRepo.On("RollBack").Return(nil)
+	quoteRepoFactory := &MockQuoteRepoFactory{}
+	quoteRepoFactory.On("New", (*gorm.DB)(nil)).Return(quoteRepo)
+
+	walletRepo := &MockWalletRepo{}
+	walletRepo.On("GetOrCreateForUpdate", ctx, usd.UserID, core.USD).Return(usd, nil)
+	walletRepo.On("GetOrCreateForUpdate", ctx, irr.UserID, core.IRR).Return(irr, nil)
+	walletRepo.On("Update", ctx, mock.Anything).Return(nil)
+	trxRepo := &MockTransactionRepo{}
+	trxRepo.On("Create", ctx, mock.Anything).Return(nil)
+	ledgerRepo := &MockLedgerRepo{}
+	ledgerRepo.On("Post", ctx, mock.Anything).Return(nil)
+	coreRepo := &MockCoreRepo{}
+	coreRepo.On("Wallet").Return(walletRepo)
+	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
+	coreRepoFactory := &MockCoreRepoFactory{}
+	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
+
+	return exchange.New(coreRepoFactory, quoteRepoFactory, nil, exchange.Config{}), quoteRepo, walletRepo
+}
+
+// newQuote converts 10.00 USD to 6,000,000 IRR until it expires in a minute.
+func newQuote(userID uuid.UUID) *repository.Quote {
+	return &repository.Quote{
+		ID:           uuid.New(),
+		UserID:       userID,
+		FromCurrency: core.USD,
+		ToCurrency:   core.IRR,
+		FromAmount:   1000,
+		ToAmount:     6000000,
+		Rate:         "6000",
+		CreatedAt:    time.Now(),
+		ExpiresAt:    time.Now().Add(time.Minute),
+	}
+}
+
+func newWallets(userID uuid.UUID, usdBalance int64) (*core.Wallet, *core.Wallet) {
+	return &core.Wallet{UserID: userID, Currency: core.USD, AvailableBalance: usdBalance, Status: core.WalletActive},
+		&core.Wallet{UserID: userID, Currency: core.IRR, Status: core.WalletActive}
+}
+
+// assertNotExecuted checks that a failed Execute moved no money and left the quote open.
+func assertNotExecuted(t *testing.T, quote *repository.Quote, quoteRepo *MockQuoteRepo, walletRepo *MockWalletRepo, usd, irr *core.Wallet, usdBalance int64) {
+	assert.Equal(t, usdBalance, usd.AvailableBalance)
+	assert.Zero(t, irr.AvailableBalance)
+	assert.Nil(t, quote.ExecutedAt)
+	walletRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	quoteRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	quoteRepo.AssertNotCalled(t, "Commit")
+}
+
+// --- Tests ---
+
+func TestService_Execute(t *testing.T) {
+	userID := uuid.New()
+	quote := newQuote(userID)
+	usd, irr := newWallets(userID, 1500)
+	service, quoteRepo, _ := setup(quote, usd, irr)
+
+	executed, err := service.Execute(context.Background(), userID, quote.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, executed.ExecutedAt)
+	assert.Equal(t, int64(500), usd.AvailableBalance)
+	assert.Equal(t, int64(6000000), irr.AvailableBalance)
+	assert.NotZero(t, executed.DebitTransactionID)
+	assert.NotZero(t, executed.CreditTransactionID)
+	quoteRepo.AssertCalled(t, "Commit")
+}
+
+func TestService_ExecuteExpired(t *testing.T) {
+	userID := uuid.New()
+	quote := newQuote(userID)
+	quote.ExpiresAt = time.Now().Add(-time.Second)
+	usd, irr := newWallets(userID, 1500)
+	service, quoteRepo, walletRepo := setup(quote, usd, irr)
+
+	_, err := service.Execute(context.Background(), userID, quote.ID)
+	assert.ErrorIs(t, err, exchange.ErrQuoteExpired)
+	assertNotExecuted(t, quote, quoteRepo, walletRepo, usd, irr, 1500)
+}
+
+func TestService_ExecuteTwice(t *testing.T) {
+	userID := uuid.New()
+	quote := newQuote(userID)
+	executedAt := time.Now().Add(-time.Second)
+	quote.ExecutedAt = &executedAt
+	usd, irr := newWallets(userID, 1500)
+	service, quoteRepo, walletRepo := setup(quote, usd, irr)
+
+	_, err := service.Execute(context.Background(), userID, quote.ID)
+	assert.ErrorIs(t, err, exchange.ErrQuoteExecuted)
+	assert.Equal(t, int64(1500), usd.AvailableBalance)
+	walletRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	quoteRepo.AssertNotCalled(t, "Commit")
+}
+
+func TestService_ExecuteOtherUsersQuote(t *testing.T) {
+	owner := uuid.New()
+	quote := newQuote(owner)
+	usd, irr := newWallets(owner, 1500)
+	service, quoteRepo, walletRepo := setup(quote, usd, irr)
+
+	// the quotes of other users do not exist for this one
+	_, err := service.Execute(context.Background(), uuid.New(), quote.ID)
+	assert.ErrorIs(t, err, exchange.ErrQuoteNotFound)
+	assertNotExecuted(t, quote, quoteRepo, walletRepo, usd, irr, 1500)
+	walletRepo.AssertNotCalled(t, "GetOrCreateForUpdate", mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = service.Execute(context.Background(), owner, uuid.New())
+	assert.ErrorIs(t, err, exchange.ErrQuoteNotFound)
+}
+
+func TestService_ExecuteInsufficientBalance(t *testing.T) {
+	userID := uuid.New()
+	quote := newQuote(userID)
+	usd, irr := newWallets(userID, 999)
+	service, quoteRepo, walletRepo := setup(quote, usd, irr)
+
+	_, err := service.Execute(context.Background(), userID, quote.ID)
+	assert.ErrorIs(t, err, exchange.ErrInsufficientBalance)
+	assertNotExecuted(t, quote, quoteRepo, walletRepo, usd, irr, 999)
+}
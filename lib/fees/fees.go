@@ -0,0 +1,151 @@
+package fees
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"wallet/lib/core"
+)
+
+var ErrInvalidConfig = errors.New("invalid fee config")
+var ErrNoSchedule = errors.New("no fee schedule for the currency")
+
+// Config is the fee policy of payouts, per currency. Payouts in a currency
+// without schedules are refused rather than charged nothing.
+type Config struct {
+	Currencies map[string]Schedules // e.g. "IRR"
+}
+
+// Schedules are the fees of one currency, in its minor units.
+type Schedules struct {
+	Default Schedule
+	Banks   map[string]Schedule // per bank type, replaces the default, e.g. "saman"
+}
+
+// Schedule charges Flat plus Bps basis points of the amount, clamped to
+// [Min, Max]. A zero Max means no cap.
+type Schedule struct {
+	Flat  int64
+	Bps   int64
+	Min   int64
+	Max   int64
+	Tiers []Tier
+}
+
+// Tier replaces Flat and Bps of its schedule for amounts of at least From.
+type Tier struct {
+	From int64
+	Flat int64
+	Bps  int64
+}
+
+// Breakdown is how a fee was made up. Total differs from Flat + Percentage
+// when the fee was clamped to the min or max of the schedule.
+type Breakdown struct {
+	Flat       int64 `json:"flat"`
+	Bps        int64 `json:"bps"`
+	Percentage int64 `json:"percentage"`
+	Total      int64 `json:"total"`
+}
+
+type Policy interface {
+	// Calculate returns the fee of paying amount of currency out through
+	// bank, or ErrNoSchedule when the currency has no schedules.
+	Calculate(currency core.Currency, bank string, amount int64) (Breakdown, error)
+}
+
+func New(config Config) (Policy, error) {
+	currencies := make(map[core.Currency]Schedules, len(config.Currencies))
+	for code, schedules := range config.Currencies {
+		// config keys come in lower case, ParseCurrency takes them
+		currency, err := core.ParseCurrency(code)
+		if err != nil {
+			return nil, ErrInvalidConfig
+		}
+		if err := schedules.Default.validate(); err != nil {
+			return nil, err
+		}
+		banks := make(map[string]Schedule, len(schedules.Banks))
+		for bank, schedule := range schedules.Banks {
+			if err := schedule.validate(); err != nil {
+				return nil, err
+			}
+			banks[strings.ToLower(bank)] = schedule.sorted()
+		}
+		currencies[currency] = Schedules{
+			Default: schedules.Default.sorted(),
+			Banks:   banks,
+		}
+	}
+	return &policy{currencies: currencies}, nil
+}
+
+type policy struct {
+	currencies map[core.Currency]Schedules
+}
+
+func (p *policy) Calculate(currency core.Currency, bank string, amount int64) (Breakdown, error) {
+	schedules, ok := p.currencies[currency]
+	if !ok {
+		return Breakdown{}, ErrNoSchedule
+	}
+	schedule, ok := schedules.Banks[strings.ToLower(bank)]
+	if !ok {
+		schedule = schedules.Default
+	}
+	return schedule.calculate(amount), nil
+}
+
+func (s Schedule) calculate(amount int64) Breakdown {
+	flat, bps := s.Flat, s.Bps
+	for _, tier := range s.Tiers {
+		if amount < tier.From {
+			break
+		}
+		flat, bps = tier.Flat, tier.Bps
+	}
+	fee := Breakdown{
+		Flat:       flat,
+		Bps:        bps,
+		Percentage: percentage(amount, bps),
+	}
+	fee.Total = fee.Flat + fee.Percentage
+	if fee.Total < s.Min {
+		fee.Total = s.Min
+	}
+	if s.Max > 0 && fee.Total > s.Max {
+		fee.Total = s.Max
+	}
+	return fee
+}
+
+// percentage returns bps basis points of amount, rounded up.
+func percentage(amount, bps int64) int64 {
+	// split amount so large amounts do not overflow
+	return amount/10000*bps + (amount%10000*bps+9999)/10000
+}
+
+func (s Schedule) sorted() Schedule {
+	tiers := make([]Tier, len(s.Tiers))
+	copy(tiers, s.Tiers)
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].From < tiers[j].From
+	})
+	s.Tiers = tiers
+	return s
+}
+
+func (s Schedule) validate() error {
+	if s.Flat < 0 || s.Bps < 0 || s.Bps > 10000 || s.Min < 0 || s.Max < 0 {
+		return ErrInvalidConfig
+	}
+	if s.Max > 0 && s.Max < s.Min {
+		return ErrInvalidConfig
+	}
+	for _, tier := range s.Tiers {
+		if tier.From < 0 || tier.Flat < 0 || tier.Bps < 0 || tier.Bps > 10000 {
+			return ErrInvalidConfig
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package fees
+
+import (
+	"math"
+	"testing"
+	"wallet/lib/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Calculate(t *testing.T) {
+	policy, err := New(Config{Currencies: map[string]Schedules{
+		"irr": {
+			Default: Schedule{Flat: 1000, Bps: 10, Min: 2000, Max: 50000},
+			Banks: map[string]Schedule{
+				"Mellat": {
+					Flat: 500,
+					Tiers: []Tier{
+						{From: 100000000, Bps: 5},
+						{From: 1000000, Flat: 500, Bps: 20},
+					},
+				},
+			},
+		},
+		"USD": {Default: Schedule{Flat: 50}},
+	}})
+	assert.NoError(t, err)
+	calculate := func(currency core.Currency, bank string, amount int64) Breakdown {
+		fee, err := policy.Calculate(currency, bank, amount)
+		assert.NoError(t, err)
+		return fee
+	}
+
+	// 1000 flat + 0.1% of 5,000,000
+	assert.Equal(t, Breakdown{Flat: 1000, Bps: 10, Percentage: 5000, Total: 6000}, calculate(core.IRR, "saman", 5000000))
+	// raised to the min
+	assert.Equal(t, Breakdown{Flat: 1000, Bps: 10, Percentage: 1, Total: 2000}, calculate(core.IRR, "saman", 1))
+	// capped at the max
+	assert.Equal(t, Breakdown{Flat: 1000, Bps: 10, Percentage: 100000, Total: 50000}, calculate(core.IRR, "saman", 100000000))
+
+	// below the first tier the schedule's own flat and bps apply
+	assert.Equal(t, Breakdown{Flat: 500, Total: 500}, calculate(core.IRR, "mellat", 999999))
+	assert.Equal(t, Breakdown{Flat: 500, Bps: 20, Percentage: 2000, Total: 2500}, calculate(core.IRR, "mellat", 1000000))
+	assert.Equal(t, Breakdown{Bps: 5, Percentage: 50000, Total: 50000}, calculate(core.IRR, "mellat", 100000000))
+
+	// the schedules of one currency do not apply to another
+	assert.Equal(t, Breakdown{Flat: 50, Total: 50}, calculate(core.USD, "mellat", 1000000))
+	_, err = policy.Calculate(core.EUR, "saman", 1000)
+	assert.ErrorIs(t, err, ErrNoSchedule)
+}
+
+func TestPercentage(t *testing.T) {
+	assert.Equal(t, int64(0), percentage(0, 25))
+	// rounded up
+	assert.Equal(t, int64(1), percentage(1, 25))
+	assert.Equal(t, int64(25), percentage(10000, 25))
+	// does not overflow
+	assert.Equal(t, int64(23058430092136940), percentage(math.MaxInt64, 25))
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	for _, schedules := range []Schedules{
+		{Default: Schedule{Min: 100, Max: 50}},
+		{Banks: map[string]Schedule{"saman": {Bps: -1}}},
+		{Default: Schedule{Tiers: []Tier{{From: 10, Bps: 20000}}}},
+	} {
+		_, err := New(Config{Currencies: map[string]Schedules{"IRR": schedules}})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+	_, err := New(Config{Currencies: map[string]Schedules{"XYZ": {}}})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	// an empty schedule charges nothing, no schedule refuses the payout
+	policy, err := New(Config{Currencies: map[string]Schedules{"IRR": {}}})
+	assert.NoError(t, err)
+	fee, err := policy.Calculate(core.IRR, "dummy", 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, Breakdown{}, fee)
+	_, err = policy.Calculate(core.USD, "dummy", 1000)
+	assert.ErrorIs(t, err, ErrNoSchedule)
+}
@@ -0,0 +1,11 @@
+package holds
+
+import "errors"
+
+var ErrInsufficientBalance = errors.New("insufficient balance")
+var ErrInvalidAmount = errors.New("hold amount must be positive")
+var ErrInvalidExpiry = errors.New("hold must expire in the future")
+var ErrCaptureExceedsHold = errors.New("capture amount exceeds the hold")
+var ErrNotFound = errors.New("hold not found")
+var ErrInvalidState = errors.New("hold is already captured, released or expired")
+var ErrExpired = errors.New("hold is expired")
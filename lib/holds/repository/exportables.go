@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"wallet/lib/holds/repository/internal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Hold = internal.Hold
+type Status = internal.Status
+
+const (
+	Active   = internal.Active
+	Captured = internal.Captured
+	Released = internal.Released
+	Expired  = internal.Expired
+)
+
+type Repo interface {
+	Create(context.Context, *Hold) error
+	Update(context.Context, *Hold) error
+	Get(ctx context.Context, id uuid.UUID) (*Hold, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*Hold, error)
+	GetExpiredHolds(ctx context.Context, IDPrefix string) ([]Hold, error)
+
+	GetDBTransaction() *gorm.DB
+	Commit() error
+	RollBack() error
+}
+
+type RepoFactory interface {
+	New(tx *gorm.DB) Repo
+}
+
+func NewFactory(db *gorm.DB) RepoFactory {
+	return &repoFactory{
+		db: db,
+	}
+}
+
+type repoFactory struct {
+	db *gorm.DB
+}
+
+func (rf *repoFactory) New(tx *gorm.DB) Repo {
+	if tx == nil {
+		tx = rf.db.Begin()
+	}
+	return internal.NewHoldRepo(tx)
+}
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"time"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	Active   = Status("active")
+	Captured = Status("captured")
+	Released = Status("released")
+	Expired  = Status("expired")
+)
+
+// Hold is an authorization keeping an amount of a wallet in its blocked
+// balance until it is captured, released or expires.
+type Hold struct {
+	ID                   uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID               uuid.UUID     `gorm:"type:uuid;not null;index" json:"user_id"`
+	Currency             core.Currency `gorm:"type:varchar(3);not null" json:"currency"`
+	Amount               int64         `gorm:"not null" json:"amount"`
+	CapturedAmount       int64         `gorm:"not null;default:0" json:"captured_amount"`
+	Reference            string        `gorm:"size:255;index" json:"reference"`
+	Status               Status        `gorm:"type:varchar(16);not null;index" json:"status"`
+	CreatedAt            time.Time     `gorm:"index" json:"created_at"`
+	ExpiresAt            time.Time     `gorm:"not null;index" json:"expires_at"`
+	ClosedAt             *time.Time    `json:"closed_at,omitempty"`
+	BlockTransactionID   uint64        `gorm:"index" json:"block_transaction_id"`
+	CaptureTransactionID uint64        `gorm:"index" json:"capture_transaction_id"`
+	ReleaseTransactionID uint64        `gorm:"index" json:"release_transaction_id"`
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type holdRepo struct {
+	tx *gorm.DB
+}
+
+func NewHoldRepo(tx *gorm.DB) *holdRepo {
+	return &holdRepo{tx: tx}
+}
+
+// Create inserts a new hold and fills in ID automatically.
+func (r *holdRepo) Create(ctx context.Context, hold *Hold) error {
+	if hold.ID == uuid.Nil {
+		hold.ID = uuid.New()
+	}
+	return r.tx.WithContext(ctx).Create(hold).Error
+}
+
+// Update updates an existing hold by ID.
+func (r *holdRepo) Update(ctx context.Context, hold *Hold) error {
+	return r.tx.WithContext(ctx).
+		Model(&Hold{}).
+		Where("id = ?", hold.ID).
+		Updates(hold).Error
+}
+
+// Get fetches a hold by ID.
+func (r *holdRepo) Get(ctx context.Context, id uuid.UUID) (*Hold, error) {
+	var hold Hold
+	if err := r.tx.WithContext(ctx).First(&hold, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// GetForUpdate fetches a hold by ID with row-level locking.
+func (r *holdRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*Hold, error) {
+	var hold Hold
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&hold, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// GetExpiredHolds fetches holds that:
+// - ID starts with IDPrefix
+// - are still active
+// - ExpiresAt is before now
+func (r *holdRepo) GetExpiredHolds(ctx context.Context, IDPrefix string) ([]Hold, error) {
+	var holds []Hold
+
+	if err := r.tx.WithContext(ctx).
+		Where("id::text LIKE ?", IDPrefix+"%").
+		Where("status = ?", Active).
+		Where("expires_at < NOW()").
+		Find(&holds).Error; err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+// GetDBTransaction returns the underlying gorm.DB (transaction).
+func (r *holdRepo) GetDBTransaction() *gorm.DB {
+	return r.tx
+}
+
+// Commit commits the transaction.
+func (r *holdRepo) Commit() error {
+	return r.tx.Commit().Error
+}
+
+// RollBack rolls back the transaction.
+func (r *holdRepo) RollBack() error {
+	return r.tx.Rollback().Error
+}
@@ -0,0 +1,261 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/holds/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Hold = repository.Hold
+
+type Service interface {
+	// Create moves amount from the available to the blocked balance of a
+	// wallet until the hold is captured, released or expires at expiresAt.
+	Create(ctx context.Context, userID uuid.UUID, currency core.Currency, amount int64, reference string, expiresAt time.Time) (*Hold, error)
+	// Capture debits amount of the held funds and returns the rest to the
+	// available balance. A zero amount captures the whole hold. There is a
+	// single capture per hold: a partial one closes the hold as well, so the
+	// rest can not be captured later and a second Capture gets ErrInvalidState.
+	Capture(ctx context.Context, id uuid.UUID, amount int64) (*Hold, error)
+	// Release returns the held funds to the available balance.
+	Release(ctx context.Context, id uuid.UUID) (*Hold, error)
+	// Expire releases an active hold past its expiry.
+	Expire(context.Context, *Hold) error
+	Get(ctx context.Context, id uuid.UUID) (*Hold, error)
+	GetExpiredHolds(ctx context.Context, IDPrefix string) ([]Hold, error)
+}
+
+func New(
+	coreRepoFactory core.RepoFactory,
+	repoFactory repository.RepoFactory,
+) Service {
+	return &service{
+		coreRepoFactory: coreRepoFactory,
+		repoFactory:     repoFactory,
+	}
+}
+
+type service struct {
+	coreRepoFactory core.RepoFactory
+	repoFactory     repository.RepoFactory
+}
+
+func (s *service) Create(ctx context.Context, userID uuid.UUID, currency core.Currency, amount int64, reference string, expiresAt time.Time) (*Hold, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return nil, ErrInvalidExpiry
+	}
+	if !currency.IsSupported() {
+		return nil, core.ErrUnsupportedCurrency
+	}
+	hold := &Hold{
+		UserID:    userID,
+		Currency:  currency,
+		Amount:    amount,
+		Reference: reference,
+		Status:    repository.Active,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	holdRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(holdRepo.GetDBTransaction())
+	defer func() {
+		_ = holdRepo.RollBack()
+	}()
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := wallet.CanDebit(); err != nil {
+		return nil, err
+	}
+	if wallet.Spendable() < amount {
+		return nil, ErrInsufficientBalance
+	}
+	if err := holdRepo.Create(ctx, hold); err != nil {
+		return nil, err
+	}
+	wallet.AvailableBalance -= amount
+	wallet.BlockedBalance += amount
+	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
+		return nil, err
+	}
+	trx := &core.Transaction{
+		Kind:          core.KindBlock,
+		WalletID:      userID,
+		Currency:      currency,
+		Amount:        -amount,
+		BlockedAmount: amount,
+		Reference:     hold.ID,
+		Description:   "hold " + reference,
+	}
+	if err := core.Post(ctx, coreRepo, core.Suspense, trx); err != nil {
+		return nil, err
+	}
+	hold.BlockTransactionID = trx.ID
+	if err := holdRepo.Update(ctx, hold); err != nil {
+		return nil, err
+	}
+	return hold, holdRepo.Commit()
+}
+
+func (s *service) Capture(ctx context.Context, id uuid.UUID, amount int64) (*Hold, error) {
+	if amount < 0 {
+		return nil, ErrInvalidAmount
+	}
+	holdRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(holdRepo.GetDBTransaction())
+	defer func() {
+		_ = holdRepo.RollBack()
+	}()
+	hold, err := getActiveForUpdate(ctx, holdRepo, id)
+	if err != nil {
+		return nil, err
+	}
+	if !hold.ExpiresAt.After(time.Now()) {
+		return nil, ErrExpired
+	}
+	if amount == 0 {
+		amount = hold.Amount
+	}
+	if amount > hold.Amount {
+		return nil, ErrCaptureExceedsHold
+	}
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, hold.UserID, hold.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := wallet.CanDebit(); err != nil {
+		return nil, err
+	}
+	rest := hold.Amount - amount
+	wallet.AvailableBalance += rest
+	wallet.BlockedBalance -= hold.Amount
+	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
+		return nil, err
+	}
+	trx := &core.Transaction{
+		Kind:          core.KindCapture,
+		WalletID:      hold.UserID,
+		Currency:      hold.Currency,
+		Amount:        rest,
+		BlockedAmount: -hold.Amount,
+		Reference:     hold.ID,
+		Description:   "hold capture " + hold.Reference,
+	}
+	if err := core.Post(ctx, coreRepo, core.Settlement, trx); err != nil {
+		return nil, err
+	}
+	closeHold(hold, repository.Captured)
+	hold.CapturedAmount = amount
+	hold.CaptureTransactionID = trx.ID
+	if err := holdRepo.Update(ctx, hold); err != nil {
+		return nil, err
+	}
+	return hold, holdRepo.Commit()
+}
+
+func (s *service) Release(ctx context.Context, id uuid.UUID) (*Hold, error) {
+	return s.release(ctx, id, repository.Released)
+}
+
+func (s *service) Expire(ctx context.Context, hold *Hold) error {
+	released, err := s.release(ctx, hold.ID, repository.Expired)
+	if err != nil {
+		return err
+	}
+	*hold = *released
+	return nil
+}
+
+// release returns the held funds to the available balance and closes the
+// hold with status. Like withdrawal reversals it is allowed on frozen wallets.
+func (s *service) release(ctx context.Context, id uuid.UUID, status repository.Status) (*Hold, error) {
+	holdRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(holdRepo.GetDBTransaction())
+	defer func() {
+		_ = holdRepo.RollBack()
+	}()
+	hold, err := getActiveForUpdate(ctx, holdRepo, id)
+	if err != nil {
+		return nil, err
+	}
+	if status == repository.Expired && hold.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidState
+	}
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, hold.UserID, hold.Currency)
+	if err != nil {
+		return nil, err
+	}
+	wallet.AvailableBalance += hold.Amount
+	wallet.BlockedBalance -= hold.Amount
+	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
+		return nil, err
+	}
+	trx := &core.Transaction{
+		Kind:          core.KindReversal,
+		WalletID:      hold.UserID,
+		Currency:      hold.Currency,
+		Amount:        hold.Amount,
+		BlockedAmount: -hold.Amount,
+		Reference:     hold.ID,
+		Description:   "hold " + string(status) + " " + hold.Reference,
+	}
+	if err := core.Post(ctx, coreRepo, core.Suspense, trx); err != nil {
+		return nil, err
+	}
+	closeHold(hold, status)
+	hold.ReleaseTransactionID = trx.ID
+	if err := holdRepo.Update(ctx, hold); err != nil {
+		return nil, err
+	}
+	return hold, holdRepo.Commit()
+}
+
+func getActiveForUpdate(ctx context.Context, holdRepo repository.Repo, id uuid.UUID) (*Hold, error) {
+	hold, err := holdRepo.GetForUpdate(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != repository.Active {
+		return nil, ErrInvalidState
+	}
+	return hold, nil
+}
+
+func closeHold(hold *Hold, status repository.Status) {
+	now := time.Now()
+	hold.Status = status
+	hold.ClosedAt = &now
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*Hold, error) {
+	holdRepo := s.repoFactory.New(nil)
+	defer func() {
+		_ = holdRepo.RollBack()
+	}()
+	hold, err := holdRepo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return hold, err
+}
+
+func (s *service) GetExpiredHolds(ctx context.Context, IDPrefix string) ([]Hold, error) {
+	holdRepo := s.repoFactory.New(nil)
+	defer func() {
+		_ = holdRepo.RollBack()
+	}()
+	return holdRepo.GetExpiredHolds(ctx, IDPrefix)
+}
@@ -0,0 +1,269 @@
+package holds_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/holds"
+	"wallet/lib/holds/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// --- Mocks ---
+
+type MockHoldRepo struct {
+	mock.Mock
+	hold *repository.Hold
+}
+
+func (m *MockHoldRepo) Create(ctx context.Context, h *repository.Hold) error {
+	h.ID = uuid.New()
+	m.hold = h
+	return m.Called(ctx, h).Error(0)
+}
+func (m *MockHoldRepo) Update(ctx context.Context, h *repository.Hold) error {
+	return m.Called(ctx, h).Error(0)
+}
+func (m *MockHoldRepo) Get(ctx context.Context, id uuid.UUID) (*repository.Hold, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*repository.Hold), args.Error(1)
+}
+func (m *MockHoldRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*repository.Hold, error) {
+	// the lock returns a fresh copy of the row, like the database would
+	locked := *m.hold
+	return &locked, m.Called(ctx, id).Error(0)
+}
+func (m *MockHoldRepo) GetExpiredHolds(ctx context.Context, prefix string) ([]repository.Hold, error) {
+	args := m.Called(ctx, prefix)
+	return args.Get(0).([]repository.Hold), args.Error(1)
+}
+func (m *MockHoldRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockHoldRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockHoldRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockHoldRepoFactory struct{ mock.Mock }
+
+func (m *MockHoldRepoFactory) New(tx *gorm.DB) repository.Repo {
+	return m.Called(tx).Get(0).(repository.Repo)
+}
+
+type MockWalletRepo struct{ mock.Mock }
+
+func (m *MockWalletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) List(ctx context.Context, userID uuid.UUID) ([]core.Wallet, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) Update(ctx context.Context, wallet *core.Wallet) error {
+	// the real repo enforces the balance policy
+	if err := wallet.Validate(); err != nil {
+		return err
+	}
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateCreditLimit(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateStatus(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+
+type MockTransactionRepo struct{ mock.Mock }
+
+func (m *MockTransactionRepo) Create(ctx context.Context, trx *core.Transaction) error {
+	trx.ID = 42
+	return m.Called(ctx, trx).Error(0)
+}
+func (m *MockTransactionRepo) Get(ctx context.Context, filter core.TransactionFilter, pageNumber int, pageSize int) ([]core.Transaction, bool, error) {
+	args := m.Called(ctx, filter, pageNumber, pageSize)
+	return args.Get(0).([]core.Transaction), args.Bool(1), args.Error(2)
+}
+func (m *MockTransactionRepo) List(ctx context.Context, filter core.TransactionFilter, cursor uint64, limit int) ([]core.Transaction, uint64, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	return args.Get(0).([]core.Transaction), args.Get(1).(uint64), args.Error(2)
+}
+func (m *MockTransactionRepo) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency core.Currency, at time.Time) (*core.Balance, error) {
+	args := m.Called(ctx, userID, currency, at)
+	return args.Get(0).(*core.Balance), args.Error(1)
+}
+
+type MockLedgerRepo struct{ mock.Mock }
+
+func (m *MockLedgerRepo) Post(ctx context.Context, entries []core.LedgerEntry) error {
+	return m.Called(ctx, entries).Error(0)
+}
+func (m *MockLedgerRepo) GetBalance(ctx context.Context, account core.Account, currency core.Currency) (*core.AccountBalance, error) {
+	args := m.Called(ctx, account, currency)
+	return args.Get(0).(*core.AccountBalance), args.Error(1)
+}
+
+type MockCoreRepo struct{ mock.Mock }
+
+func (m *MockCoreRepo) Wallet() core.WalletRepo {
+	return m.Called().Get(0).(core.WalletRepo)
+}
+func (m *MockCoreRepo) Transaction() core.TransactionRepo {
+	return m.Called().Get(0).(core.TransactionRepo)
+}
+func (m *MockCoreRepo) Ledger() core.LedgerRepo {
+	return m.Called().Get(0).(core.LedgerRepo)
+}
+func (m *MockCoreRepo) CreditLimitChange() core.CreditLimitChangeRepo {
+	return m.Called().Get(0).(core.CreditLimitChangeRepo)
+}
+func (m *MockCoreRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockCoreRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockCoreRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockCoreRepoFactory struct{ mock.Mock }
+
+func (m *MockCoreRepoFactory) New(tx *gorm.DB) core.Repo {
+	return m.Called(tx).Get(0).(core.Repo)
+}
+
+// --- Tests ---
+
+func setup(wallet *core.Wallet) (holds.Service, *MockHoldRepo, *MockLedgerRepo) {
+	ctx := context.Background()
+	holdRepo := new(MockHoldRepo)
+	holdRepoFactory := new(MockHoldRepoFactory)
+	walletRepo := new(MockWalletRepo)
+	trxRepo := new(MockTransactionRepo)
+	ledgerRepo := new(MockLedgerRepo)
+	coreRepo := new(MockCoreRepo)
+	coreRepoFactory := new(MockCoreRepoFactory)
+
+	holdRepo.On("Create", ctx, mock.Anything).Return(nil)
+	holdRepo.On("Update", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		saved := *args.Get(1).(*repository.Hold)
+		holdRepo.hold = &saved
+	}).Return(nil)
+	holdRepo.On("GetForUpdate", ctx, mock.Anything).Return(nil)
+	holdRepo.On("Commit").Return(nil)
+	holdRepo.On("RollBack").Return(nil)
+	holdRepoFactory.On("New", (*gorm.DB)(nil)).Return(holdRepo)
+
+	walletRepo.On("GetOrCreateForUpdate", ctx, wallet.UserID, wallet.Currency).Return(wallet, nil)
+	walletRepo.On("Update", ctx, wallet).Return(nil)
+	trxRepo.On("Create", ctx, mock.Anything).Return(nil)
+	ledgerRepo.On("Post", ctx, mock.Anything).Return(nil)
+	coreRepo.On("Wallet").Return(walletRepo)
+	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
+	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
+
+	return holds.New(coreRepoFactory, holdRepoFactory), holdRepo, ledgerRepo
+}
+
+func TestService_Create(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500}
+	service, holdRepo, _ := setup(wallet)
+
+	hold, err := service.Create(context.Background(), wallet.UserID, core.IRR, 200, "order-1", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, repository.Active, hold.Status)
+	assert.Equal(t, uint64(42), hold.BlockTransactionID)
+	assert.Equal(t, int64(300), wallet.AvailableBalance)
+	assert.Equal(t, int64(200), wallet.BlockedBalance)
+	holdRepo.AssertCalled(t, "Commit")
+
+	_, err = service.Create(context.Background(), wallet.UserID, core.IRR, 400, "order-2", time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, holds.ErrInsufficientBalance)
+	_, err = service.Create(context.Background(), wallet.UserID, core.IRR, 100, "order-3", time.Now())
+	assert.ErrorIs(t, err, holds.ErrInvalidExpiry)
+}
+
+func TestService_CapturePartial(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500}
+	service, _, ledgerRepo := setup(wallet)
+	hold, err := service.Create(context.Background(), wallet.UserID, core.IRR, 200, "order-1", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	_, err = service.Capture(context.Background(), hold.ID, 201)
+	assert.ErrorIs(t, err, holds.ErrCaptureExceedsHold)
+
+	captured, err := service.Capture(context.Background(), hold.ID, 150)
+	assert.NoError(t, err)
+	assert.Equal(t, repository.Captured, captured.Status)
+	assert.Equal(t, int64(150), captured.CapturedAmount)
+	assert.NotNil(t, captured.ClosedAt)
+	// the uncaptured rest is back in the available balance
+	assert.Equal(t, int64(350), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+	ledgerRepo.AssertCalled(t, "Post", context.Background(), mock.MatchedBy(func(entries []core.LedgerEntry) bool {
+		return len(entries) == 3 &&
+			entries[0].Account == core.WalletAvailableAccount(wallet.UserID) && entries[0].Credit == 50 &&
+			entries[1].Account == core.WalletBlockedAccount(wallet.UserID) && entries[1].Debit == 200 &&
+			entries[2].Account == core.Settlement && entries[2].Credit == 150
+	}))
+
+	// the partial capture closed the hold, the rest can not be captured later
+	_, err = service.Capture(context.Background(), hold.ID, 50)
+	assert.ErrorIs(t, err, holds.ErrInvalidState)
+	assert.Equal(t, int64(350), wallet.AvailableBalance)
+	_, err = service.Release(context.Background(), hold.ID)
+	assert.ErrorIs(t, err, holds.ErrInvalidState)
+}
+
+func TestService_CaptureFull(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500}
+	service, _, _ := setup(wallet)
+	hold, err := service.Create(context.Background(), wallet.UserID, core.IRR, 200, "order-1", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	captured, err := service.Capture(context.Background(), hold.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), captured.CapturedAmount)
+	assert.Equal(t, int64(300), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+}
+
+func TestService_Release(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500}
+	service, _, _ := setup(wallet)
+	hold, err := service.Create(context.Background(), wallet.UserID, core.IRR, 200, "order-1", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	// releasing is allowed on frozen wallets, capturing is not
+	wallet.Status = core.WalletFullyFrozen
+	_, err = service.Capture(context.Background(), hold.ID, 0)
+	assert.ErrorIs(t, err, core.ErrWalletFrozen)
+
+	released, err := service.Release(context.Background(), hold.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, repository.Released, released.Status)
+	assert.NotZero(t, released.ReleaseTransactionID)
+	assert.Equal(t, int64(500), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+}
+
+func TestService_Expire(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500}
+	service, holdRepo, _ := setup(wallet)
+	hold, err := service.Create(context.Background(), wallet.UserID, core.IRR, 200, "order-1", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	// not expired yet
+	assert.ErrorIs(t, service.Expire(context.Background(), hold), holds.ErrInvalidState)
+
+	holdRepo.hold.ExpiresAt = time.Now().Add(-time.Minute)
+	_, err = service.Capture(context.Background(), hold.ID, 0)
+	assert.ErrorIs(t, err, holds.ErrExpired)
+
+	assert.NoError(t, service.Expire(context.Background(), hold))
+	assert.Equal(t, repository.Expired, hold.Status)
+	assert.Equal(t, int64(500), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+}
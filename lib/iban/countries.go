@@ -0,0 +1,140 @@
+package iban
+
+// country is the IBAN format of a country from the ISO 13616 registry. The
+// bank code is bankLength characters from bankOffset of the account number
+// (BBAN), unknown when bankLength is zero.
+type country struct {
+	length     int
+	bankOffset int
+	bankLength int
+	numeric    bool // the account number is digits only
+}
+
+var countries = map[string]country{
+	"AD": {length: 24, bankLength: 4},
+	"AE": {length: 23, bankLength: 3, numeric: true},
+	"AL": {length: 28, bankLength: 3},
+	"AT": {length: 20, bankLength: 5, numeric: true},
+	"AZ": {length: 28, bankLength: 4},
+	"BA": {length: 20, bankLength: 3, numeric: true},
+	"BE": {length: 16, bankLength: 3, numeric: true},
+	"BG": {length: 22, bankLength: 4},
+	"BH": {length: 22, bankLength: 4},
+	"BI": {length: 27, bankLength: 5, numeric: true},
+	"BR": {length: 29, bankLength: 8},
+	"BY": {length: 28, bankLength: 4},
+	"CH": {length: 21, bankLength: 5},
+	"CR": {length: 22, bankOffset: 1, bankLength: 3, numeric: true},
+	"CY": {length: 28, bankLength: 3},
+	"CZ": {length: 24, bankLength: 4, numeric: true},
+	"DE": {length: 22, bankLength: 8, numeric: true},
+	"DJ": {length: 27, bankLength: 5, numeric: true},
+	"DK": {length: 18, bankLength: 4, numeric: true},
+	"DO": {length: 28, bankLength: 4},
+	"EE": {length: 20, bankLength: 2, numeric: true},
+	"EG": {length: 29, bankLength: 4, numeric: true},
+	"ES": {length: 24, bankLength: 4, numeric: true},
+	"FI": {length: 18, bankLength: 3, numeric: true},
+	"FK": {length: 18, bankLength: 2},
+	"FO": {length: 18, bankLength: 4, numeric: true},
+	"FR": {length: 27, bankLength: 5},
+	"GB": {length: 22, bankLength: 4},
+	"GE": {length: 22, bankLength: 2},
+	"GI": {length: 23, bankLength: 4},
+	"GL": {length: 18, bankLength: 4, numeric: true},
+	"GR": {length: 27, bankLength: 3},
+	"GT": {length: 28, bankLength: 4},
+	"HR": {length: 21, bankLength: 7, numeric: true},
+	"HU": {length: 28, bankLength: 3, numeric: true},
+	"IE": {length: 22, bankLength: 4},
+	"IL": {length: 23, bankLength: 3, numeric: true},
+	"IQ": {length: 23, bankLength: 4},
+	"IR": {length: 26, bankLength: 3, numeric: true},
+	"IS": {length: 26, bankLength: 2, numeric: true},
+	"IT": {length: 27, bankOffset: 1, bankLength: 5},
+	"JO": {length: 30, bankLength: 4},
+	"KW": {length: 30, bankLength: 4},
+	"KZ": {length: 20, bankLength: 3},
+	"LB": {length: 28, bankLength: 4},
+	"LC": {length: 32, bankLength: 4},
+	"LI": {length: 21, bankLength: 5},
+	"LT": {length: 20, bankLength: 5, numeric: true},
+	"LU": {length: 20, bankLength: 3},
+	"LV": {length: 21, bankLength: 4},
+	"LY": {length: 25, bankLength: 3, numeric: true},
+	"MC": {length: 27, bankLength: 5},
+	"MD": {length: 24, bankLength: 2},
+	"ME": {length: 22, bankLength: 3, numeric: true},
+	"MK": {length: 19, bankLength: 3},
+	"MN": {length: 20, bankLength: 4, numeric: true},
+	"MR": {length: 27, bankLength: 5, numeric: true},
+	"MT": {length: 31, bankLength: 4},
+	"MU": {length: 30, bankLength: 6},
+	"NI": {length: 28, bankLength: 4},
+	"NL": {length: 18, bankLength: 4},
+	"NO": {length: 15, bankLength: 4, numeric: true},
+	"OM": {length: 23, bankLength: 3},
+	"PK": {length: 24, bankLength: 4},
+	"PL": {length: 28, bankLength: 8, numeric: true},
+	"PS": {length: 29, bankLength: 4},
+	"PT": {length: 25, bankLength: 4, numeric: true},
+	"QA": {length: 29, bankLength: 4},
+	"RO": {length: 24, bankLength: 4},
+	"RS": {length: 22, bankLength: 3, numeric: true},
+	"RU": {length: 33, bankLength: 9},
+	"SA": {length: 24, bankLength: 2},
+	"SC": {length: 31, bankLength: 6},
+	"SD": {length: 18, bankLength: 2, numeric: true},
+	"SE": {length: 24, bankLength: 3, numeric: true},
+	"SI": {length: 19, bankLength: 5, numeric: true},
+	"SK": {length: 24, bankLength: 4, numeric: true},
+	"SM": {length: 27, bankOffset: 1, bankLength: 5},
+	"SO": {length: 23, bankLength: 4, numeric: true},
+	"ST": {length: 25, bankLength: 4, numeric: true},
+	"SV": {length: 28, bankLength: 4},
+	"TL": {length: 23, bankLength: 3, numeric: true},
+	"TN": {length: 24, bankLength: 2, numeric: true},
+	"TR": {length: 26, bankLength: 5},
+	"UA": {length: 29, bankLength: 6},
+	"VA": {length: 22, bankLength: 3, numeric: true},
+	"VG": {length: 24, bankLength: 4},
+	"XK": {length: 20, bankLength: 2, numeric: true},
+	"YE": {length: 30, bankLength: 4},
+}
+
+// iranianBanks names the banks by the code in Iranian IBANs.
+var iranianBanks = map[string]string{
+	"010": "Central Bank of Iran",
+	"011": "Sanat va Madan",
+	"012": "Mellat",
+	"013": "Refah",
+	"014": "Maskan",
+	"015": "Sepah",
+	"016": "Keshavarzi",
+	"017": "Melli",
+	"018": "Tejarat",
+	"019": "Saderat",
+	"020": "Tosee Saderat",
+	"021": "Post Bank",
+	"022": "Tosee Taavon",
+	"053": "Karafarin",
+	"054": "Parsian",
+	"055": "Eghtesad Novin",
+	"056": "Saman",
+	"057": "Pasargad",
+	"058": "Sarmayeh",
+	"059": "Sina",
+	"061": "Shahr",
+	"062": "Ayandeh",
+	"066": "Dey",
+	"069": "Iran Zamin",
+}
+
+// BankName returns the name of the account's bank where it is known, which
+// is for Iranian IBANs only.
+func (i IBAN) BankName() string {
+	if i.Country() != "IR" {
+		return ""
+	}
+	return iranianBanks[i.BankCode()]
+}
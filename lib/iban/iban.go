@@ -0,0 +1,92 @@
+package iban
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid iban")
+
+// IBAN is a validated international bank account number (ISO 13616).
+type IBAN struct {
+	value string // electronic format, upper case without spaces
+}
+
+// Parse validates s, which may be in print format (groups of four separated
+// by spaces) and lower case, against the checksum and the length and
+// characters of its country.
+func Parse(s string) (IBAN, error) {
+	value := strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	if len(value) < 5 {
+		return IBAN{}, fmt.Errorf("%w: too short", ErrInvalid)
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		isLetter := c >= 'A' && c <= 'Z'
+		isDigit := c >= '0' && c <= '9'
+		switch {
+		case i < 2 && !isLetter:
+			return IBAN{}, fmt.Errorf("%w: country code must be letters", ErrInvalid)
+		case i >= 2 && i < 4 && !isDigit:
+			return IBAN{}, fmt.Errorf("%w: check digits must be digits", ErrInvalid)
+		case !isLetter && !isDigit:
+			return IBAN{}, fmt.Errorf("%w: invalid character %q", ErrInvalid, c)
+		}
+	}
+	country, ok := countries[value[:2]]
+	if !ok {
+		return IBAN{}, fmt.Errorf("%w: unknown country %s", ErrInvalid, value[:2])
+	}
+	if len(value) != country.length {
+		return IBAN{}, fmt.Errorf("%w: %s ibans have %d characters, not %d", ErrInvalid, value[:2], country.length, len(value))
+	}
+	if country.numeric && strings.IndexFunc(value[4:], func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return IBAN{}, fmt.Errorf("%w: %s account numbers are digits only", ErrInvalid, value[:2])
+	}
+	if mod97(value[4:]+value[:4]) != 1 {
+		return IBAN{}, fmt.Errorf("%w: checksum mismatch", ErrInvalid)
+	}
+	return IBAN{value: value}, nil
+}
+
+// Validate reports whether s is a valid IBAN, see Parse.
+func Validate(s string) error {
+	_, err := Parse(s)
+	return err
+}
+
+func (i IBAN) String() string { return i.value }
+
+func (i IBAN) Country() string {
+	if i.value == "" {
+		return ""
+	}
+	return i.value[:2]
+}
+
+// BankCode returns the identifier of the account's bank, e.g. "012" of an
+// Iranian IBAN held at Mellat, or "" where it is not known for the country.
+func (i IBAN) BankCode() string {
+	country, ok := countries[i.Country()]
+	if !ok || country.bankLength == 0 {
+		return ""
+	}
+	start := 4 + country.bankOffset
+	return i.value[start : start+country.bankLength]
+}
+
+// mod97 returns s modulo 97, reading letters as the numbers 10 to 35.
+func mod97(s string) int {
+	remainder := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' {
+			n := int(c-'A') + 10
+			remainder = (remainder*100 + n) % 97
+		} else {
+			remainder = (remainder*10 + int(c-'0')) % 97
+		}
+	}
+	return remainder
+}
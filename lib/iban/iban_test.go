@@ -0,0 +1,56 @@
+package iban
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		value    string
+		bankCode string
+	}{
+		{"IR160120000000001234567890", "IR160120000000001234567890", "012"},
+		{"ir93 0560 0000 0000 1234 5678 90", "IR930560000000001234567890", "056"},
+		{"DE89370400440532013000", "DE89370400440532013000", "37040044"},
+		{"GB82 WEST 1234 5698 7654 32", "GB82WEST12345698765432", "WEST"},
+		{"FR1420041010050500013M02606", "FR1420041010050500013M02606", "20041"},
+		{"IT60X0542811101000000123456", "IT60X0542811101000000123456", "05428"},
+		{"NL91ABNA0417164300", "NL91ABNA0417164300", "ABNA"},
+	} {
+		parsed, err := Parse(tc.input)
+		assert.NoError(t, err, tc.input)
+		assert.Equal(t, tc.value, parsed.String(), tc.input)
+		assert.Equal(t, tc.bankCode, parsed.BankCode(), tc.input)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"IR16",
+		"IR120120000000001234567890",  // checksum
+		"IR16012000000000123456789",   // length
+		"IR1601200000000012345678901", // length
+		"XX160120000000001234567890",  // country
+		"1R160120000000001234567890",
+		"IRAB0120000000001234567890",
+		"IR16012000000000123456789A", // digits only in Iran
+		"DE89-3704-0044-0532-0130-00",
+	} {
+		assert.ErrorIs(t, Validate(input), ErrInvalid, input)
+	}
+}
+
+func TestBankName(t *testing.T) {
+	parsed, err := Parse("IR160120000000001234567890")
+	assert.NoError(t, err)
+	assert.Equal(t, "Mellat", parsed.BankName())
+
+	parsed, err = Parse("DE89370400440532013000")
+	assert.NoError(t, err)
+	assert.Equal(t, "", parsed.BankName())
+	assert.Equal(t, "", IBAN{}.BankCode())
+}
@@ -0,0 +1,56 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"wallet/lib/idempotency/internal"
+
+	"gorm.io/gorm"
+)
+
+type Record = internal.Record
+
+// Key identifies a client request. Scope separates endpoints sharing the same
+// key space, Fingerprint is a digest of the request body.
+type Key struct {
+	Scope       string
+	Value       string
+	Fingerprint string
+}
+
+type Repo interface {
+	Reserve(ctx context.Context, scope, key, fingerprint string) (*Record, error)
+	Update(context.Context, *Record) error
+
+	GetDBTransaction() *gorm.DB
+	Commit() error
+	RollBack() error
+}
+
+type RepoFactory interface {
+	New(tx *gorm.DB) Repo
+}
+
+func NewFactory(db *gorm.DB) RepoFactory {
+	return &repoFactory{
+		db: db,
+	}
+}
+
+type repoFactory struct {
+	db *gorm.DB
+}
+
+func (rf *repoFactory) New(tx *gorm.DB) Repo {
+	if tx == nil {
+		tx = rf.db.Begin()
+	}
+	return internal.NewIdempotencyRepo(tx)
+}
+
+// Fingerprint returns the digest of a request body used to detect key reuse.
+func Fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
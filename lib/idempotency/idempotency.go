@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+var ErrKeyReused = errors.New("idempotency key is already used for a different request")
+
+// Do runs fn at most once per key within the transaction of repo.
+// If the key was already used for the same request, the stored result is
+// decoded into result and replayed is true; fn is not called in that case.
+// Otherwise fn fills result and it is stored alongside the key. The caller
+// commits the transaction, so the key is only persisted together with the
+// writes of fn.
+func Do(ctx context.Context, repo Repo, key Key, result any, fn func() error) (replayed bool, err error) {
+	record, err := repo.Reserve(ctx, key.Scope, key.Value, key.Fingerprint)
+	if err != nil {
+		return false, err
+	}
+	if record.Fingerprint != key.Fingerprint {
+		return false, ErrKeyReused
+	}
+	if record.Response != nil {
+		return true, json.Unmarshal([]byte(*record.Response), result)
+	}
+	if err := fn(); err != nil {
+		return false, err
+	}
+	response, err := json.Marshal(result)
+	if err != nil {
+		return false, err
+	}
+	stored := string(response)
+	record.Response = &stored
+	return false, repo.Update(ctx, record)
+}
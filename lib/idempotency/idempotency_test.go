@@ -0,0 +1,85 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"wallet/lib/idempotency"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type fakeRepo struct {
+	records map[string]*idempotency.Record
+}
+
+func (r *fakeRepo) Reserve(ctx context.Context, scope, key, fingerprint string) (*idempotency.Record, error) {
+	if record, ok := r.records[scope+key]; ok {
+		stored := *record
+		return &stored, nil
+	}
+	r.records[scope+key] = &idempotency.Record{Scope: scope, Key: key, Fingerprint: fingerprint}
+	stored := *r.records[scope+key]
+	return &stored, nil
+}
+func (r *fakeRepo) Update(ctx context.Context, record *idempotency.Record) error {
+	r.records[record.Scope+record.Key] = record
+	return nil
+}
+func (r *fakeRepo) GetDBTransaction() *gorm.DB { return nil }
+func (r *fakeRepo) Commit() error              { return nil }
+func (r *fakeRepo) RollBack() error            { return nil }
+
+type result struct {
+	ID int `json:"id"`
+}
+
+func TestDo_ReplaysStoredResult(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepo{records: map[string]*idempotency.Record{}}
+	key := idempotency.Key{Scope: "deposit", Value: "k1", Fingerprint: idempotency.Fingerprint([]byte(`{"amount":1}`))}
+	calls := 0
+
+	first := result{}
+	replayed, err := idempotency.Do(ctx, repo, key, &first, func() error {
+		calls++
+		first.ID = 42
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	second := result{}
+	replayed, err = idempotency.Do(ctx, repo, key, &second, func() error {
+		calls++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, 42, second.ID)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDo_RejectsDifferentRequest(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepo{records: map[string]*idempotency.Record{}}
+	key := idempotency.Key{Scope: "withdraw", Value: "k1", Fingerprint: idempotency.Fingerprint([]byte(`{"amount":1}`))}
+	_, err := idempotency.Do(ctx, repo, key, &result{}, func() error { return nil })
+	assert.NoError(t, err)
+
+	key.Fingerprint = idempotency.Fingerprint([]byte(`{"amount":2}`))
+	_, err = idempotency.Do(ctx, repo, key, &result{}, func() error { return nil })
+	assert.ErrorIs(t, err, idempotency.ErrKeyReused)
+}
+
+func TestDo_DoesNotStoreFailedResult(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepo{records: map[string]*idempotency.Record{}}
+	key := idempotency.Key{Scope: "deposit", Value: "k1", Fingerprint: "f"}
+	failure := errors.New("failure")
+
+	_, err := idempotency.Do(ctx, repo, key, &result{}, func() error { return failure })
+	assert.ErrorIs(t, err, failure)
+	assert.Nil(t, repo.records["depositk1"].Response)
+}
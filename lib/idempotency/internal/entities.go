@@ -0,0 +1,16 @@
+package internal
+
+import "time"
+
+type Record struct {
+	Scope       string    `gorm:"primaryKey;type:varchar(32)" json:"scope"`
+	Key         string    `gorm:"primaryKey;type:varchar(255)" json:"key"`
+	Fingerprint string    `gorm:"type:varchar(64);not null" json:"fingerprint"`
+	Response    *string   `gorm:"type:jsonb" json:"response"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyRepo struct {
+	tx *gorm.DB
+}
+
+func NewIdempotencyRepo(tx *gorm.DB) *idempotencyRepo {
+	return &idempotencyRepo{tx: tx}
+}
+
+// Reserve inserts the record if its key is not used yet and returns the stored
+// record locked for update. A concurrent request with the same key blocks here
+// until the first one commits or rolls back.
+func (r *idempotencyRepo) Reserve(ctx context.Context, scope, key, fingerprint string) (*Record, error) {
+	now := time.Now()
+	record := Record{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&record).Error; err != nil {
+		return nil, err
+	}
+	var stored Record
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&stored, "scope = ? AND key = ?", scope, key).Error; err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// Update stores the response of a reserved record.
+func (r *idempotencyRepo) Update(ctx context.Context, record *Record) error {
+	record.UpdatedAt = time.Now()
+	return r.tx.WithContext(ctx).
+		Model(&Record{}).
+		Where("scope = ? AND key = ?", record.Scope, record.Key).
+		Updates(map[string]any{
+			"response":   record.Response,
+			"updated_at": record.UpdatedAt,
+		}).Error
+}
+
+func (r *idempotencyRepo) GetDBTransaction() *gorm.DB {
+	return r.tx
+}
+
+func (r *idempotencyRepo) Commit() error {
+	return r.tx.Commit().Error
+}
+
+func (r *idempotencyRepo) RollBack() error {
+	return r.tx.Rollback().Error
+}
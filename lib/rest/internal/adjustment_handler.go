@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"wallet/lib/adjustments"
+	"wallet/lib/adjustments/repository"
+	"wallet/lib/core"
+	"wallet/lib/rest/internal/payloads"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (s *server) proposeAdjustmentHandler(ctx *gin.Context) {
+	actor, ok := getActor(ctx)
+	if !ok {
+		return
+	}
+	var request payloads.ProposeAdjustmentRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	currency, err := normalizeCurrency(request.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+		return
+	}
+	adjustment := payloads.Adjustment{
+		UserID:   request.UserID,
+		Currency: currency,
+		Balance:  repository.Balance(request.Balance),
+		Amount:   request.Amount,
+		Reason:   request.Reason,
+	}
+	err = s.adjustmentService.Propose(ctx, &adjustment, actor)
+	if s.handleAdjustmentError(ctx, err, "cant propose adjustment") {
+		return
+	}
+	ctx.JSON(http.StatusCreated, payloads.Response{
+		Data: adjustment,
+	})
+}
+
+func (s *server) approveAdjustmentHandler(ctx *gin.Context) {
+	s.reviewAdjustment(ctx, s.adjustmentService.Approve, "cant approve adjustment")
+}
+
+func (s *server) rejectAdjustmentHandler(ctx *gin.Context) {
+	s.reviewAdjustment(ctx, s.adjustmentService.Reject, "cant reject adjustment")
+}
+
+func (s *server) reviewAdjustment(ctx *gin.Context, review func(context.Context, uuid.UUID, string) (*payloads.Adjustment, error), msg string) {
+	actor, ok := getActor(ctx)
+	if !ok {
+		return
+	}
+	id, ok := getAdjustmentID(ctx)
+	if !ok {
+		return
+	}
+	adjustment, err := review(ctx, id, actor)
+	if s.handleAdjustmentError(ctx, err, msg) {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: adjustment,
+	})
+}
+
+func (s *server) getAdjustmentHandler(ctx *gin.Context) {
+	id, ok := getAdjustmentID(ctx)
+	if !ok {
+		return
+	}
+	adjustment, err := s.adjustmentService.Get(ctx, id)
+	if s.handleAdjustmentError(ctx, err, "cant get adjustment") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: adjustment,
+	})
+}
+
+func (s *server) getAdjustmentsHandler(ctx *gin.Context) {
+	var userID uuid.UUID
+	if userIDStr := ctx.Query("user_id"); userIDStr != "" {
+		var err error
+		if userID, err = uuid.Parse(userIDStr); err != nil {
+			ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("user_id"))
+			return
+		}
+	}
+	status := repository.Status(ctx.Query("status"))
+	switch status {
+	case "", repository.Pending, repository.Approved, repository.Rejected:
+	default:
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("status"))
+		return
+	}
+	list, err := s.adjustmentService.List(ctx, userID, status)
+	if err != nil {
+		respondUnexpectedError(ctx, "cant list adjustments", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: list,
+	})
+}
+
+func getAdjustmentID(ctx *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("id"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// handleAdjustmentError writes the response for err and reports whether it did.
+func (s *server) handleAdjustmentError(ctx *gin.Context, err error, msg string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, adjustments.ErrInvalidAmount):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("amount"))
+	case errors.Is(err, adjustments.ErrInvalidBalance):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("balance"))
+	case errors.Is(err, adjustments.ErrMissingReason):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateRequiredParamResponse("reason"))
+	case errors.Is(err, core.ErrUnsupportedCurrency):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+	case errors.Is(err, adjustments.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, payloads.CreateErrorResponse("adjustment_not_found", "adjustment not found"))
+	case errors.Is(err, adjustments.ErrInvalidState):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("adjustment_reviewed", "adjustment is already approved or rejected"))
+	case errors.Is(err, adjustments.ErrSameOperator):
+		ctx.JSON(http.StatusForbidden, payloads.CreateErrorResponse("same_operator", "adjustment must be approved by another operator"))
+	case handleWalletPolicyError(ctx, err):
+	default:
+		respondUnexpectedError(ctx, msg, err)
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"wallet/lib/core"
+	"wallet/lib/rest/internal/middlewares"
+	"wallet/lib/rest/internal/payloads"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getActor returns the operator calling an admin endpoint, as authenticated
+// by their token; it is recorded with every change made through them.
+func getActor(ctx *gin.Context) (string, bool) {
+	actor := ctx.GetString(middlewares.OperatorKey)
+	if actor == "" {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return "", false
+	}
+	return actor, true
+}
+
+func (s *server) setCreditLimitHandler(ctx *gin.Context) {
+	actor, ok := getActor(ctx)
+	if !ok {
+		return
+	}
+	var request payloads.SetCreditLimitRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	currency, err := normalizeCurrency(request.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+		return
+	}
+	coreRepo := s.coreRepoFactory.New(nil)
+	defer func() {
+		_ = coreRepo.RollBack()
+	}()
+	wallet, err := core.SetCreditLimit(ctx, coreRepo, request.UserID, currency, request.CreditLimit, actor, request.Reason)
+	if err == nil {
+		err = coreRepo.Commit()
+	}
+	switch {
+	case errors.Is(err, core.ErrInvalidCreditLimit):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("credit_limit"))
+		return
+	case errors.Is(err, core.ErrMissingActor):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateRequiredParamResponse("reason"))
+		return
+	case errors.Is(err, core.ErrNegativeBalance):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("credit_limit_in_use", "wallet already uses more credit than the new limit"))
+		return
+	case err != nil:
+		respondUnexpectedError(ctx, "cant set credit limit", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: payloads.NewWalletBalance(wallet),
+	})
+}
+
+func (s *server) getCreditLimitChangesHandler(ctx *gin.Context) {
+	userID, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+	currency, ok := getCurrency(ctx)
+	if !ok {
+		return
+	}
+	coreRepo := s.coreRepoFactory.New(nil)
+	defer func() {
+		_ = coreRepo.RollBack()
+	}()
+	changes, err := coreRepo.CreditLimitChange().List(ctx, userID, currency)
+	if err != nil {
+		respondUnexpectedError(ctx, "cant get credit limit changes", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: changes,
+	})
+}
+
+func (s *server) setWalletStatusHandler(ctx *gin.Context) {
+	actor, ok := getActor(ctx)
+	if !ok {
+		return
+	}
+	var request payloads.SetWalletStatusRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	currency, err := normalizeCurrency(request.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+		return
+	}
+	status, err := core.ParseWalletStatus(request.Status)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("status"))
+		return
+	}
+	coreRepo := s.coreRepoFactory.New(nil)
+	defer func() {
+		_ = coreRepo.RollBack()
+	}()
+	wallet, err := core.SetWalletStatus(ctx, coreRepo, request.UserID, currency, status, actor, request.Reason)
+	if err == nil {
+		err = coreRepo.Commit()
+	}
+	switch {
+	case errors.Is(err, core.ErrMissingActor):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateRequiredParamResponse("reason"))
+		return
+	case errors.Is(err, core.ErrWalletNotEmpty):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("wallet_not_empty", "only an empty wallet can be closed"))
+		return
+	case errors.Is(err, core.ErrWalletClosed):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("wallet_closed", "a closed wallet can not change status"))
+		return
+	case err != nil:
+		respondUnexpectedError(ctx, "cant set wallet status", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: payloads.NewWalletBalance(wallet),
+	})
+}
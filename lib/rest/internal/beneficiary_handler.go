@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"wallet/lib/beneficiaries"
+	"wallet/lib/rest/internal/payloads"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxBeneficiaryLabelLength = 64
+
+func (s *server) createBeneficiaryHandler(ctx *gin.Context) {
+	var request payloads.CreateBeneficiaryRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	if !validBeneficiaryFields(ctx, &request.HolderName, &request.Label) {
+		return
+	}
+	beneficiary := payloads.Beneficiary{
+		UserID:     request.UserID,
+		Iban:       request.IBan,
+		HolderName: request.HolderName,
+		Label:      request.Label,
+	}
+	err := s.beneficiaryService.Create(ctx, &beneficiary)
+	if s.handleBeneficiaryError(ctx, err, "cant create beneficiary") {
+		return
+	}
+	ctx.JSON(http.StatusCreated, payloads.Response{
+		Data: beneficiary,
+	})
+}
+
+func (s *server) getBeneficiariesHandler(ctx *gin.Context) {
+	userID, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+	list, err := s.beneficiaryService.List(ctx, userID)
+	if s.handleBeneficiaryError(ctx, err, "cant list beneficiaries") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: list,
+	})
+}
+
+func (s *server) getBeneficiaryHandler(ctx *gin.Context) {
+	userID, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := getBeneficiaryID(ctx)
+	if !ok {
+		return
+	}
+	beneficiary, err := s.beneficiaryService.Get(ctx, id, userID)
+	if s.handleBeneficiaryError(ctx, err, "cant get beneficiary") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: beneficiary,
+	})
+}
+
+func (s *server) updateBeneficiaryHandler(ctx *gin.Context) {
+	userID, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := getBeneficiaryID(ctx)
+	if !ok {
+		return
+	}
+	var request payloads.UpdateBeneficiaryRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	if !validBeneficiaryFields(ctx, request.HolderName, request.Label) {
+		return
+	}
+	beneficiary, err := s.beneficiaryService.Update(ctx, id, userID, request.HolderName, request.Label)
+	if s.handleBeneficiaryError(ctx, err, "cant update beneficiary") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: beneficiary,
+	})
+}
+
+func (s *server) deleteBeneficiaryHandler(ctx *gin.Context) {
+	userID, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := getBeneficiaryID(ctx)
+	if !ok {
+		return
+	}
+	err := s.beneficiaryService.Delete(ctx, id, userID)
+	if s.handleBeneficiaryError(ctx, err, "cant delete beneficiary") {
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (s *server) verifyBeneficiaryHandler(ctx *gin.Context) {
+	actor, ok := getActor(ctx)
+	if !ok {
+		return
+	}
+	id, ok := getBeneficiaryID(ctx)
+	if !ok {
+		return
+	}
+	beneficiary, err := s.beneficiaryService.Verify(ctx, id, actor)
+	if s.handleBeneficiaryError(ctx, err, "cant verify beneficiary") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: beneficiary,
+	})
+}
+
+// validBeneficiaryFields checks the lengths of the given fields; on failure it
+// writes the error response.
+func validBeneficiaryFields(ctx *gin.Context, holderName *string, label *string) bool {
+	if holderName != nil && len(*holderName) > maxBeneficiaryNameLength {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("holder_name"))
+		return false
+	}
+	if label != nil && len(*label) > maxBeneficiaryLabelLength {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("label"))
+		return false
+	}
+	return true
+}
+
+func getBeneficiaryID(ctx *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("id"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// handleBeneficiaryError writes the response for err and reports whether it did.
+func (s *server) handleBeneficiaryError(ctx *gin.Context, err error, msg string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, beneficiaries.ErrInvalidIban):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("invalid_iban", err.Error()))
+	case errors.Is(err, beneficiaries.ErrDuplicate):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("duplicate_beneficiary", "the iban is already saved as a beneficiary"))
+	case errors.Is(err, beneficiaries.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, payloads.CreateErrorResponse("beneficiary_not_found", "beneficiary not found"))
+	case errors.Is(err, beneficiaries.ErrCoolingOff):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("beneficiary_cooling_off", "beneficiary can not receive funds until its cooling-off period is over"))
+	case errors.Is(err, beneficiaries.ErrNotVerified):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("beneficiary_not_verified", "beneficiary can not receive funds until an operator verified it"))
+	case errors.Is(err, beneficiaries.ErrNotSaved):
+		ctx.JSON(http.StatusUnprocessableEntity, payloads.CreateErrorResponse("beneficiary_required", "withdrawals go to saved beneficiaries only, save the iban first"))
+	default:
+		respondUnexpectedError(ctx, msg, err)
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	token := encodeCursor(1234)
+	assert.NotEmpty(t, token)
+	id, err := decodeCursor(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234), id)
+}
+
+func TestCursor_Empty(t *testing.T) {
+	// the last page has no next cursor and an empty cursor is the first page
+	assert.Empty(t, encodeCursor(0))
+	id, err := decodeCursor("")
+	assert.NoError(t, err)
+	assert.Zero(t, id)
+}
+
+func TestCursor_Invalid(t *testing.T) {
+	for _, token := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("1234")),
+		base64.RawURLEncoding.EncodeToString([]byte("trx:")),
+		base64.RawURLEncoding.EncodeToString([]byte("trx:0")),
+		base64.RawURLEncoding.EncodeToString([]byte("trx:-5")),
+		base64.RawURLEncoding.EncodeToString([]byte("trx:12a")),
+	} {
+		_, err := decodeCursor(token)
+		assert.ErrorIs(t, err, ErrInvalidCursor, token)
+	}
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"wallet/lib/deposits"
+	"wallet/lib/deposits/repository"
+	"wallet/lib/rest/internal/payloads"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (s *server) cancelDepositHandler(ctx *gin.Context) {
+	id, ok := getDepositID(ctx)
+	if !ok {
+		return
+	}
+	deposit, err := s.depositService.Cancel(ctx, id)
+	if s.handleDepositError(ctx, err, "cant cancel deposit") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: deposit,
+	})
+}
+
+func (s *server) retryDepositHandler(ctx *gin.Context) {
+	id, ok := getDepositID(ctx)
+	if !ok {
+		return
+	}
+	deposit, err := s.depositService.Retry(ctx, id)
+	if s.handleDepositError(ctx, err, "cant retry deposit") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: deposit,
+	})
+}
+
+func (s *server) rescheduleDepositHandler(ctx *gin.Context) {
+	id, ok := getDepositID(ctx)
+	if !ok {
+		return
+	}
+	var request payloads.RescheduleDepositRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	deposit, err := s.depositService.Reschedule(ctx, id, request.ApplyAt)
+	if s.handleDepositError(ctx, err, "cant reschedule deposit") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: deposit,
+	})
+}
+
+func (s *server) getDepositHandler(ctx *gin.Context) {
+	id, ok := getDepositID(ctx)
+	if !ok {
+		return
+	}
+	deposit, err := s.depositService.Get(ctx, id)
+	if s.handleDepositError(ctx, err, "cant get deposit") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: deposit,
+	})
+}
+
+func (s *server) getDepositsHandler(ctx *gin.Context) {
+	var filter repository.Filter
+	var ok bool
+	if filter.UserID, ok = getUserID(ctx); !ok {
+		return
+	}
+	filter.Status = repository.Status(ctx.Query("status"))
+	switch filter.Status {
+	case "", repository.Pending, repository.Applied, repository.Cancelled, repository.Failed:
+	default:
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("status"))
+		return
+	}
+	filter.SourceType = repository.SourceType(ctx.Query("source_type"))
+	if filter.SourceType != "" && !validSourceType(filter.SourceType) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("source_type"))
+		return
+	}
+	filter.ExternalReference = ctx.Query("external_reference")
+	page, pageSize, err := getPageAndPageSize(ctx)
+	if errors.Is(err, ErrInvalidPage) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("page"))
+		return
+	}
+	if errors.Is(err, ErrInvalidPageSize) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("page_size"))
+		return
+	}
+	list, hasMore, err := s.depositService.List(ctx, filter, page, pageSize)
+	if err != nil {
+		respondUnexpectedError(ctx, "cant list deposits", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: payloads.DepositListResponse{
+			HasMore:  hasMore,
+			Deposits: list,
+		},
+	})
+}
+
+const maxDepositTextLength = 255
+
+// setDepositSource copies the source fields of the request to deposit; on
+// failure it writes the error response.
+func setDepositSource(ctx *gin.Context, request *payloads.CreateDepositRequest, deposit *payloads.Deposit) bool {
+	if len(request.Description) > maxDepositTextLength {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("description"))
+		return false
+	}
+	deposit.SourceType = repository.SourceType(request.SourceType)
+	if deposit.SourceType != "" && !validSourceType(deposit.SourceType) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("source_type"))
+		return false
+	}
+	if len(request.ExternalReference) > maxDepositTextLength {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("external_reference"))
+		return false
+	}
+	if request.ExternalReference != "" {
+		deposit.ExternalReference = &request.ExternalReference
+	}
+	if len(request.Metadata) > 0 && string(request.Metadata) != "null" {
+		if request.Metadata[0] != '{' {
+			ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("metadata"))
+			return false
+		}
+		deposit.Metadata = repository.Metadata(request.Metadata)
+	}
+	return true
+}
+
+func validSourceType(sourceType repository.SourceType) bool {
+	switch sourceType {
+	case repository.SourcePSP, repository.SourceOrder, repository.SourceBankTransfer, repository.SourceOther:
+		return true
+	}
+	return false
+}
+
+func getDepositID(ctx *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("id"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// handleDepositError writes the response for err and reports whether it did.
+func (s *server) handleDepositError(ctx *gin.Context, err error, msg string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, deposits.ErrInvalidApplyAt):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("apply_at"))
+	case errors.Is(err, deposits.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, payloads.CreateErrorResponse("deposit_not_found", "deposit not found"))
+	case errors.Is(err, deposits.ErrAlreadyApplied):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("deposit_applied", "deposit is already applied"))
+	case errors.Is(err, deposits.ErrAlreadyCancelled):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("deposit_cancelled", "deposit is cancelled"))
+	case errors.Is(err, deposits.ErrFailed):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("deposit_failed", "deposit has failed"))
+	case errors.Is(err, deposits.ErrNotFailed):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("deposit_not_failed", "only failed deposits can be retried"))
+	case errors.Is(err, deposits.ErrBlockedBalanceShort):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("blocked_balance_short", "the blocked balance of the wallet does not cover the deposit"))
+	case handleWalletPolicyError(ctx, err):
+	default:
+		respondUnexpectedError(ctx, msg, err)
+	}
+	return true
+}
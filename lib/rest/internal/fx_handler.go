@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"wallet/lib/core"
+	"wallet/lib/exchange"
+	"wallet/lib/rest/internal/payloads"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (s *server) createQuoteHandler(ctx *gin.Context) {
+	var request payloads.CreateQuoteRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	from, err := core.ParseCurrency(string(request.FromCurrency))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("from_currency"))
+		return
+	}
+	to, err := core.ParseCurrency(string(request.ToCurrency))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("to_currency"))
+		return
+	}
+	quote, err := s.exchangeService.RequestQuote(ctx, request.UserID, from, to, request.Amount)
+	if s.handleExchangeError(ctx, err, "cant create quote") {
+		return
+	}
+	ctx.JSON(http.StatusCreated, payloads.Response{
+		Data: quote,
+	})
+}
+
+func (s *server) executeQuoteHandler(ctx *gin.Context) {
+	quoteID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("id"))
+		return
+	}
+	var request payloads.ExecuteQuoteRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	quote, err := s.exchangeService.Execute(ctx, request.UserID, quoteID)
+	if s.handleExchangeError(ctx, err, "cant execute quote") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: quote,
+	})
+}
+
+// handleExchangeError writes the response for err and reports whether it did.
+func (s *server) handleExchangeError(ctx *gin.Context, err error, msg string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, exchange.ErrInvalidAmount):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("amount"))
+	case errors.Is(err, exchange.ErrAmountTooSmall):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("amount_too_small", "converted amount is zero"))
+	case errors.Is(err, exchange.ErrSameCurrency):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("same_currency", "cant exchange a currency to itself"))
+	case errors.Is(err, exchange.ErrRateUnavailable):
+		ctx.JSON(http.StatusUnprocessableEntity, payloads.CreateErrorResponse("rate_unavailable", "no rate is available for this currency pair"))
+	case errors.Is(err, exchange.ErrQuoteNotFound):
+		ctx.JSON(http.StatusNotFound, payloads.CreateErrorResponse("quote_not_found", "quote not found"))
+	case errors.Is(err, exchange.ErrQuoteExpired):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("quote_expired", "quote is expired, request a new one"))
+	case errors.Is(err, exchange.ErrQuoteExecuted):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("quote_executed", "quote is already executed"))
+	case errors.Is(err, exchange.ErrInsufficientBalance):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("insufficient_balance", "there is not enough available balance to exchange"))
+	case handleWalletPolicyError(ctx, err):
+	default:
+		respondUnexpectedError(ctx, msg, err)
+	}
+	return true
+}
@@ -2,53 +2,174 @@ package internal
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"wallet/lib/core"
+	"wallet/lib/deposits"
+	"wallet/lib/idempotency"
 	"wallet/lib/rest/internal/payloads"
 	"wallet/lib/utils"
 	"wallet/lib/utils/logger"
 	"wallet/lib/withdraws"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
+	slog_gin "github.com/samber/slog-gin"
 )
 
 func (s *server) GetBalanceHandler(ctx *gin.Context) {
-	userIDStr := ctx.Query("user_id")
-	if userIDStr == "" {
-		ctx.JSON(http.StatusBadRequest, payloads.CreateRequiredParamResponse("user_id"))
+	userID, ok := getUserID(ctx)
+	if !ok {
 		return
 	}
-	userID, err := uuid.Parse(userIDStr)
+	currency, ok := getCurrency(ctx)
+	if !ok {
+		return
+	}
+	at, ok := getTimeParam(ctx, "at")
+	if !ok {
+		return
+	}
+	coreRepo := s.coreRepoFactory.New(nil)
+	defer func() {
+		_ = coreRepo.RollBack()
+	}()
+	if at != nil {
+		balance, err := coreRepo.Transaction().GetBalanceAt(ctx, userID, currency, *at)
+		if err != nil {
+			respondUnexpectedError(ctx, "cant get balance", err)
+			return
+		}
+		ctx.JSON(http.StatusOK, payloads.Response{
+			Data: balance,
+		})
+		return
+	}
+	wallet, err := coreRepo.Wallet().GetOrCreate(ctx, userID, currency)
+	if err == nil {
+		err = coreRepo.Commit()
+	}
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("user_id"))
+		respondUnexpectedError(ctx, "cant get wallet", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: payloads.NewWalletBalance(wallet),
+	})
+}
+
+func (s *server) getStatementHandler(ctx *gin.Context) {
+	userID, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+	currency, ok := getCurrency(ctx)
+	if !ok {
+		return
+	}
+	from, ok := getRequiredTimeParam(ctx, "from")
+	if !ok {
+		return
+	}
+	to, ok := getRequiredTimeParam(ctx, "to")
+	if !ok {
+		return
+	}
+	cursor, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("cursor"))
+		return
+	}
+	limit, err := getLimit(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("limit"))
+		return
+	}
+	coreRepo := s.coreRepoFactory.New(nil)
+	defer func() {
+		_ = coreRepo.RollBack()
+	}()
+	statement, err := core.GetStatement(ctx, coreRepo, userID, currency, from, to, cursor, limit)
+	if errors.Is(err, core.ErrInvalidPeriod) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("to"))
 		return
 	}
-	wallet, err := s.coreRepoFactory.New(nil).Wallet().GetOrCreate(ctx, userID)
 	if err != nil {
-		traceID, _ := ctx.Get("request_id")
-		logger.Get().With("trace_id", traceID)
-		ctx.JSON(http.StatusInternalServerError, payloads.CreateCallSupportResponse(traceID.(string)))
+		respondUnexpectedError(ctx, "cant get statement", err)
 		return
 	}
 	ctx.JSON(http.StatusOK, payloads.Response{
-		Data: wallet,
+		Data: payloads.StatementResponse{
+			Statement:  statement,
+			HasMore:    statement.NextCursor != 0,
+			NextCursor: encodeCursor(statement.NextCursor),
+		},
 	})
 }
 
-func (s *server) getTransactionsHistoryHandler(ctx *gin.Context) {
-	userIDStr := ctx.Query("user_id") // TODO :  unify duplicate code for get user id
-	if userIDStr == "" {
-		ctx.JSON(http.StatusBadRequest, payloads.CreateRequiredParamResponse("user_id"))
+func (s *server) getWalletsHandler(ctx *gin.Context) {
+	userID, ok := getUserID(ctx)
+	if !ok {
 		return
 	}
-	userID, err := uuid.Parse(userIDStr)
+	coreRepo := s.coreRepoFactory.New(nil)
+	defer func() {
+		_ = coreRepo.RollBack()
+	}()
+	wallets, err := coreRepo.Wallet().List(ctx, userID)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("user_id"))
+		respondUnexpectedError(ctx, "cant get wallets", err)
 		return
 	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: wallets,
+	})
+}
 
+func (s *server) getTransactionsHistoryHandler(ctx *gin.Context) {
+	filter, ok := getTransactionFilter(ctx)
+	if !ok {
+		return
+	}
+	coreRepo := s.coreRepoFactory.New(nil)
+	defer func() {
+		_ = coreRepo.RollBack()
+	}()
+
+	// cursor mode, used when the client asks for it
+	_, hasCursor := ctx.GetQuery("cursor")
+	_, hasLimit := ctx.GetQuery("limit")
+	if hasCursor || hasLimit {
+		cursor, err := decodeCursor(ctx.Query("cursor"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("cursor"))
+			return
+		}
+		limit, err := getLimit(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("limit"))
+			return
+		}
+		transactions, nextCursor, err := coreRepo.Transaction().List(ctx, filter, cursor, limit)
+		if err != nil {
+			respondUnexpectedError(ctx, "cant list transactions", err)
+			return
+		}
+		ctx.JSON(http.StatusOK, payloads.Response{
+			Data: payloads.TransactionHistoryResponse{
+				HasMore:      nextCursor != 0,
+				NextCursor:   encodeCursor(nextCursor),
+				Transactions: transactions,
+			},
+		})
+		return
+	}
+
+	// page mode, kept for backwards compatibility
 	page, pageSize, err := getPageAndPageSize(ctx)
 	if errors.Is(err, ErrInvalidPage) {
 		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("page"))
@@ -58,11 +179,9 @@ func (s *server) getTransactionsHistoryHandler(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("page_size"))
 		return
 	}
-	transactions, hasMore, err := s.coreRepoFactory.New(nil).Transaction().Get(ctx, userID, page, pageSize)
-	if err != nil { // TODO (*DUPLICATE CODE*) unify code for managing unexpected errors
-		traceID, _ := ctx.Get("request_id")
-		logger.Get().With("trace_id", traceID)
-		ctx.JSON(http.StatusInternalServerError, payloads.CreateCallSupportResponse(traceID.(string)))
+	transactions, hasMore, err := coreRepo.Transaction().Get(ctx, filter, page, pageSize)
+	if err != nil {
+		respondUnexpectedError(ctx, "cant get transactions", err)
 		return
 	}
 	ctx.JSON(http.StatusOK, payloads.Response{
@@ -74,19 +193,81 @@ func (s *server) getTransactionsHistoryHandler(ctx *gin.Context) {
 
 }
 
+func (s *server) exportTransactionsHandler(ctx *gin.Context) {
+	format, err := core.ParseExportFormat(ctx.DefaultQuery("format", string(core.ExportCSV)))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("format"))
+		return
+	}
+	filter, ok := getTransactionFilter(ctx)
+	if !ok {
+		return
+	}
+	coreRepo := s.coreRepoFactory.New(nil)
+	defer func() {
+		_ = coreRepo.RollBack()
+	}()
+
+	ctx.Header("Content-Type", format.ContentType())
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions_%s_%s.%s"`, filter.UserID, filter.Currency, format))
+	ctx.Status(http.StatusOK)
+	if err := core.ExportTransactions(ctx, coreRepo, filter, format, ctx.Writer); err != nil {
+		// the response has already started, all we can do is cut it short
+		logger.Get().With("trace_id", slog_gin.GetRequestID(ctx)).Error("cant export transactions", "error", utils.Stringify(err))
+		ctx.Abort()
+	}
+}
+
+const (
+	maxBeneficiaryNameLength     = 128
+	maxWithdrawDescriptionLength = 255
+)
+
 func (s *server) createWithdrawHandler(ctx *gin.Context) {
 	var request payloads.CreateWithdrawRequest
-	if err := ctx.ShouldBindJSON(&request); err != nil {
+	if err := ctx.ShouldBindBodyWith(&request, binding.JSON); err != nil {
 		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
 		return
 	}
+	key, err := getIdempotencyKey(ctx, "withdraw")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidHeaderResponse(idempotencyKeyHeader))
+		return
+	}
+	currency, err := normalizeCurrency(request.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+		return
+	}
+	if len(request.BeneficiaryName) > maxBeneficiaryNameLength {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("beneficiary_name"))
+		return
+	}
+	if len(request.Description) > maxWithdrawDescriptionLength {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("description"))
+		return
+	}
+	// exactly one of the two names the destination
+	if (request.IBan == "") == (request.BeneficiaryID == nil) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("invalid_destination", "exactly one of iban and beneficiary_id must be provided"))
+		return
+	}
 	withdraw := payloads.Withdraw{
-		WalletID: request.UserID,
-		Bank:     request.BankType,
-		Iban:     request.IBan,
-		Amount:   request.Amount,
+		WalletID:        request.UserID,
+		Currency:        currency,
+		Bank:            request.BankType,
+		Iban:            request.IBan,
+		BeneficiaryID:   request.BeneficiaryID,
+		Amount:          request.Amount,
+		BeneficiaryName: request.BeneficiaryName,
+		Description:     request.Description,
+	}
+	replayed := false
+	if key == nil {
+		err = s.withdrawService.Create(ctx, &withdraw)
+	} else {
+		replayed, err = s.withdrawService.CreateIdempotent(ctx, &withdraw, *key)
 	}
-	err := s.withdrawService.Create(ctx, &withdraw)
 	if errors.Is(err, withdraws.ErrInsufficientBalance) {
 		ctx.JSON(http.StatusBadRequest, payloads.Response{
 			Error: &payloads.ErrorResponse{
@@ -94,13 +275,45 @@ func (s *server) createWithdrawHandler(ctx *gin.Context) {
 				Message: "there is not enough available balance to create withdraw",
 			},
 		})
+		return
+	}
+	if errors.Is(err, withdraws.ErrInvalidAmount) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("invalid_amount", "withdrawal amount must be positive"))
+		return
+	}
+	if handleWalletPolicyError(ctx, err) {
+		return
+	}
+	if errors.Is(err, withdraws.ErrInvalidIban) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("invalid_iban", err.Error()))
+		return
+	}
+	if errors.Is(err, withdraws.ErrNoRoute) {
+		ctx.JSON(http.StatusUnprocessableEntity, payloads.CreateErrorResponse("no_route", "no bank can take this withdrawal now"))
+		return
+	}
+	if errors.Is(err, withdraws.ErrQuotaExceeded) {
+		ctx.JSON(http.StatusUnprocessableEntity, payloads.CreateErrorResponse("quota_exceeded", "the bank can not take more withdrawals in this currency today"))
+		return
+	}
+	if errors.Is(err, withdraws.ErrBeneficiaryNotFound) || errors.Is(err, withdraws.ErrCoolingOff) ||
+		errors.Is(err, withdraws.ErrBeneficiaryNotVerified) || errors.Is(err, withdraws.ErrBeneficiaryRequired) {
+		s.handleBeneficiaryError(ctx, err, "cant resolve beneficiary")
+		return
+	}
+	if errors.Is(err, withdraws.ErrNoFeeSchedule) {
+		ctx.JSON(http.StatusUnprocessableEntity, payloads.CreateErrorResponse("currency_not_supported", "withdrawals in this currency are not supported"))
+		return
+	}
+	if errors.Is(err, idempotency.ErrKeyReused) {
+		ctx.JSON(http.StatusConflict, payloads.CreateIdempotencyKeyReusedResponse())
+		return
 	}
 	if err != nil {
-		traceID, _ := ctx.Get("request_id")
-		logger.Get().With("trace_id", traceID).Error("cant create withdrawal", "error", utils.Stringify(err))
-		ctx.JSON(http.StatusInternalServerError, payloads.CreateCallSupportResponse(traceID.(string)))
+		respondUnexpectedError(ctx, "cant create withdrawal", err)
 		return
 	}
+	setReplayedHeader(ctx, replayed)
 	ctx.JSON(http.StatusCreated, payloads.Response{
 		Data: withdraw,
 	})
@@ -108,26 +321,63 @@ func (s *server) createWithdrawHandler(ctx *gin.Context) {
 
 func (s *server) createDepositHandler(ctx *gin.Context) {
 	var request payloads.CreateDepositRequest
-	if err := ctx.ShouldBindJSON(&request); err != nil {
+	if err := ctx.ShouldBindBodyWith(&request, binding.JSON); err != nil {
 		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
 		return
 	}
+	key, err := getIdempotencyKey(ctx, "deposit")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidHeaderResponse(idempotencyKeyHeader))
+		return
+	}
+	currency, err := normalizeCurrency(request.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+		return
+	}
 	if request.ApplyAt == nil {
 		now := time.Now()
 		request.ApplyAt = &now
 	}
 	deposit := payloads.Deposit{
-		UserID:  request.UserID,
-		Amount:  request.Amount,
-		ApplyAt: *request.ApplyAt,
+		UserID:      request.UserID,
+		Currency:    currency,
+		Amount:      request.Amount,
+		ApplyAt:     *request.ApplyAt,
+		Description: request.Description,
 	}
-
-	if err := s.depositService.Create(ctx, &deposit); err != nil {
-		traceID, _ := ctx.Get("request_id")
-		logger.Get().With("trace_id", traceID).Error("cant create withdrawal", "error", utils.Stringify(err))
-		ctx.JSON(http.StatusInternalServerError, payloads.CreateCallSupportResponse(traceID.(string)))
+	if !setDepositSource(ctx, &request, &deposit) {
+		return
+	}
+	for _, leg := range request.Schedule {
+		deposit.Legs = append(deposit.Legs, deposits.Leg{Amount: leg.Amount, ApplyAt: leg.ApplyAt})
+	}
+	replayed := false
+	if key == nil {
+		err = s.depositService.Create(ctx, &deposit)
+	} else {
+		replayed, err = s.depositService.CreateIdempotent(ctx, &deposit, *key)
+	}
+	if handleWalletPolicyError(ctx, err) {
+		return
+	}
+	if errors.Is(err, idempotency.ErrKeyReused) {
+		ctx.JSON(http.StatusConflict, payloads.CreateIdempotencyKeyReusedResponse())
 		return
 	}
+	if errors.Is(err, deposits.ErrInvalidSchedule) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("schedule"))
+		return
+	}
+	if errors.Is(err, deposits.ErrDuplicateReference) {
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("duplicate_external_reference", "a deposit with this external reference already exists"))
+		return
+	}
+	if err != nil {
+		respondUnexpectedError(ctx, "cant create deposit", err)
+		return
+	}
+	setReplayedHeader(ctx, replayed)
 	ctx.JSON(http.StatusCreated, payloads.Response{
 		Data: deposit,
 	})
@@ -135,6 +385,10 @@ func (s *server) createDepositHandler(ctx *gin.Context) {
 
 var ErrInvalidPage = errors.New("invalid page")
 var ErrInvalidPageSize = errors.New("invalid page")
+var ErrInvalidLimit = errors.New("invalid limit")
+
+const defaultLimit = 20
+const maxLimit = 100
 
 func getPageAndPageSize(ctx *gin.Context) (int, int, error) {
 	pageStr := ctx.Query("page")
@@ -146,12 +400,187 @@ func getPageAndPageSize(ctx *gin.Context) (int, int, error) {
 		pageSizeStr = "20"
 	}
 	page, err := strconv.ParseInt(pageStr, 10, 64)
-	if err != nil {
+	if err != nil || page < 1 {
 		return 0, 0, ErrInvalidPage
 	}
 	pageSize, err := strconv.ParseInt(pageSizeStr, 10, 64)
-	if err != nil {
+	if err != nil || pageSize < 1 || pageSize > maxLimit {
 		return 0, 0, ErrInvalidPageSize
 	}
 	return int(page), int(pageSize), nil
 }
+
+func getLimit(ctx *gin.Context) (int, error) {
+	limitStr := ctx.Query("limit")
+	if limitStr == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > maxLimit {
+		return 0, ErrInvalidLimit
+	}
+	return limit, nil
+}
+
+// getTransactionFilter parses the wallet and filter query parameters of
+// transaction listings; on failure it writes the error response.
+func getTransactionFilter(ctx *gin.Context) (core.TransactionFilter, bool) {
+	var filter core.TransactionFilter
+	var ok bool
+	if filter.UserID, ok = getUserID(ctx); !ok {
+		return filter, false
+	}
+	if filter.Currency, ok = getCurrency(ctx); !ok {
+		return filter, false
+	}
+	if filter.From, ok = getTimeParam(ctx, "from"); !ok {
+		return filter, false
+	}
+	if filter.To, ok = getTimeParam(ctx, "to"); !ok {
+		return filter, false
+	}
+	if referenceStr := ctx.Query("reference"); referenceStr != "" {
+		reference, err := uuid.Parse(referenceStr)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("reference"))
+			return filter, false
+		}
+		filter.Reference = &reference
+	}
+	for _, kinds := range ctx.QueryArray("kind") {
+		for _, kindStr := range strings.Split(kinds, ",") {
+			kind, err := core.ParseTransactionKind(kindStr)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("kind"))
+				return filter, false
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+	switch ctx.Query("sign") {
+	case "":
+	case "positive":
+		filter.Sign = 1
+	case "negative":
+		filter.Sign = -1
+	default:
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("sign"))
+		return filter, false
+	}
+	return filter, true
+}
+
+// getTimeParam parses an optional RFC 3339 query parameter; on failure it writes the error response.
+func getTimeParam(ctx *gin.Context, param string) (*time.Time, bool) {
+	value := ctx.Query(param)
+	if value == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse(param))
+		return nil, false
+	}
+	return &t, true
+}
+
+// getRequiredTimeParam is getTimeParam for mandatory parameters.
+func getRequiredTimeParam(ctx *gin.Context, param string) (time.Time, bool) {
+	if ctx.Query(param) == "" {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateRequiredParamResponse(param))
+		return time.Time{}, false
+	}
+	t, ok := getTimeParam(ctx, param)
+	if !ok {
+		return time.Time{}, false
+	}
+	return *t, true
+}
+
+const idempotencyKeyHeader = "Idempotency-Key"
+const idempotentReplayedHeader = "Idempotent-Replayed"
+const maxIdempotencyKeyLength = 255
+
+var ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+
+// getIdempotencyKey returns the idempotency key sent by the client, or nil if
+// there is none. The request body must be bound with ShouldBindBodyWith so it
+// can be fingerprinted.
+func getIdempotencyKey(ctx *gin.Context, scope string) (*idempotency.Key, error) {
+	value := ctx.GetHeader(idempotencyKeyHeader)
+	if value == "" {
+		return nil, nil
+	}
+	if len(value) > maxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+	body, _ := ctx.Get(gin.BodyBytesKey)
+	bodyBytes, _ := body.([]byte)
+	return &idempotency.Key{
+		Scope:       scope,
+		Value:       value,
+		Fingerprint: idempotency.Fingerprint(bodyBytes),
+	}, nil
+}
+
+func setReplayedHeader(ctx *gin.Context, replayed bool) {
+	if replayed {
+		ctx.Header(idempotentReplayedHeader, "true")
+	}
+}
+
+// getUserID parses the user_id query parameter; on failure it writes the error response.
+func getUserID(ctx *gin.Context) (uuid.UUID, bool) {
+	userIDStr := ctx.Query("user_id")
+	if userIDStr == "" {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateRequiredParamResponse("user_id"))
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("user_id"))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// getCurrency parses the optional currency query parameter; on failure it writes the error response.
+func getCurrency(ctx *gin.Context) (core.Currency, bool) {
+	currency, err := normalizeCurrency(core.Currency(ctx.Query("currency")))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+		return "", false
+	}
+	return currency, true
+}
+
+// normalizeCurrency validates a currency code sent by a client, falling back
+// to the default currency for clients that do not send one.
+func normalizeCurrency(currency core.Currency) (core.Currency, error) {
+	if currency == "" {
+		return core.DefaultCurrency, nil
+	}
+	return core.ParseCurrency(string(currency))
+}
+
+// handleWalletPolicyError writes the response for errors of the wallet policy
+// in lib/core and reports whether it did.
+func handleWalletPolicyError(ctx *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, core.ErrNegativeBalance):
+		ctx.JSON(http.StatusUnprocessableEntity, payloads.CreateNegativeBalanceResponse())
+	case errors.Is(err, core.ErrWalletFrozen):
+		ctx.JSON(http.StatusForbidden, payloads.CreateErrorResponse("wallet_frozen", "wallet is frozen for this operation"))
+	case errors.Is(err, core.ErrWalletClosed):
+		ctx.JSON(http.StatusForbidden, payloads.CreateErrorResponse("wallet_closed", "wallet is closed"))
+	default:
+		return false
+	}
+	return true
+}
+
+func respondUnexpectedError(ctx *gin.Context, msg string, err error) {
+	traceID := slog_gin.GetRequestID(ctx)
+	logger.Get().With("trace_id", traceID).Error(msg, "error", utils.Stringify(err))
+	ctx.JSON(http.StatusInternalServerError, payloads.CreateCallSupportResponse(traceID))
+}
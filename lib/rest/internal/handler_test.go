@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wallet/lib/core"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newQueryContext(query string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/transactions?"+query, nil)
+	return ctx, recorder
+}
+
+func TestGetTransactionFilter(t *testing.T) {
+	userID := uuid.New()
+	reference := uuid.New()
+	ctx, _ := newQueryContext("user_id=" + userID.String() + "&currency=usd" +
+		"&from=2025-09-01T00:00:00Z&to=2025-10-01T00:00:00%2B03:30" +
+		"&reference=" + reference.String() + "&kind=block,apply&kind=fee&sign=negative")
+
+	filter, ok := getTransactionFilter(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, userID, filter.UserID)
+	assert.Equal(t, core.USD, filter.Currency)
+	assert.True(t, filter.From.Equal(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, filter.To.Equal(time.Date(2025, 9, 30, 20, 30, 0, 0, time.UTC)))
+	assert.Equal(t, reference, *filter.Reference)
+	assert.Equal(t, []core.TransactionKind{core.KindBlock, core.KindApply, core.KindFee}, filter.Kinds)
+	assert.Equal(t, -1, filter.Sign)
+}
+
+func TestGetTransactionFilter_Defaults(t *testing.T) {
+	ctx, _ := newQueryContext("user_id=" + uuid.NewString())
+
+	filter, ok := getTransactionFilter(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, core.DefaultCurrency, filter.Currency)
+	assert.Nil(t, filter.From)
+	assert.Nil(t, filter.To)
+	assert.Nil(t, filter.Reference)
+	assert.Empty(t, filter.Kinds)
+	assert.Zero(t, filter.Sign)
+}
+
+func TestGetTransactionFilter_Invalid(t *testing.T) {
+	userID := "user_id=" + uuid.NewString()
+	for query, param := range map[string]string{
+		"":                            "user_id",
+		"user_id=42":                  "user_id",
+		userID + "&currency=XYZ":      "currency",
+		userID + "&from=yesterday":    "from",
+		userID + "&to=2025-10-01":     "to",
+		userID + "&reference=abc":     "reference",
+		userID + "&kind=block,refund": "kind",
+		userID + "&kind=":             "kind",
+		userID + "&sign=zero":         "sign",
+	} {
+		ctx, recorder := newQueryContext(query)
+		_, ok := getTransactionFilter(ctx)
+		assert.False(t, ok, query)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+		assert.Contains(t, recorder.Body.String(), "parameter "+param+" is", query)
+	}
+}
+
+func TestGetPageAndPageSize(t *testing.T) {
+	ctx, _ := newQueryContext("")
+	page, pageSize, err := getPageAndPageSize(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page)
+	assert.Equal(t, 20, pageSize)
+
+	ctx, _ = newQueryContext("page=3&page_size=100")
+	page, pageSize, err = getPageAndPageSize(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, page)
+	assert.Equal(t, 100, pageSize)
+
+	// a page below one would be a negative offset, a page size above the limit an unbounded read
+	for query, want := range map[string]error{
+		"page=0":        ErrInvalidPage,
+		"page=-1":       ErrInvalidPage,
+		"page=x":        ErrInvalidPage,
+		"page_size=0":   ErrInvalidPageSize,
+		"page_size=101": ErrInvalidPageSize,
+		"page_size=-5":  ErrInvalidPageSize,
+	} {
+		ctx, _ := newQueryContext(query)
+		_, _, err := getPageAndPageSize(ctx)
+		assert.ErrorIs(t, err, want, query)
+	}
+}
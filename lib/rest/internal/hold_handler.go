@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"wallet/lib/core"
+	"wallet/lib/holds"
+	"wallet/lib/rest/internal/payloads"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (s *server) createHoldHandler(ctx *gin.Context) {
+	var request payloads.CreateHoldRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	currency, err := normalizeCurrency(request.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+		return
+	}
+	hold, err := s.holdService.Create(ctx, request.UserID, currency, request.Amount, request.Reference, request.ExpiresAt)
+	if s.handleHoldError(ctx, err, "cant create hold") {
+		return
+	}
+	ctx.JSON(http.StatusCreated, payloads.Response{
+		Data: hold,
+	})
+}
+
+func (s *server) captureHoldHandler(ctx *gin.Context) {
+	id, ok := getHoldID(ctx)
+	if !ok {
+		return
+	}
+	var request payloads.CaptureHoldRequest
+	// the body is optional, an empty one captures the whole hold
+	if err := ctx.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	hold, err := s.holdService.Capture(ctx, id, request.Amount)
+	if s.handleHoldError(ctx, err, "cant capture hold") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: hold,
+	})
+}
+
+func (s *server) releaseHoldHandler(ctx *gin.Context) {
+	id, ok := getHoldID(ctx)
+	if !ok {
+		return
+	}
+	hold, err := s.holdService.Release(ctx, id)
+	if s.handleHoldError(ctx, err, "cant release hold") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: hold,
+	})
+}
+
+func (s *server) getHoldHandler(ctx *gin.Context) {
+	id, ok := getHoldID(ctx)
+	if !ok {
+		return
+	}
+	hold, err := s.holdService.Get(ctx, id)
+	if s.handleHoldError(ctx, err, "cant get hold") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: hold,
+	})
+}
+
+func getHoldID(ctx *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("id"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// handleHoldError writes the response for err and reports whether it did.
+func (s *server) handleHoldError(ctx *gin.Context, err error, msg string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, holds.ErrInvalidAmount), errors.Is(err, holds.ErrCaptureExceedsHold):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("amount"))
+	case errors.Is(err, holds.ErrInvalidExpiry):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("expires_at"))
+	case errors.Is(err, core.ErrUnsupportedCurrency):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+	case errors.Is(err, holds.ErrInsufficientBalance):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("insufficient_balance", "there is not enough available balance to hold"))
+	case errors.Is(err, holds.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, payloads.CreateErrorResponse("hold_not_found", "hold not found"))
+	case errors.Is(err, holds.ErrInvalidState):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("hold_closed", "hold is already captured, released or expired"))
+	case errors.Is(err, holds.ErrExpired):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("hold_expired", "hold is expired"))
+	case handleWalletPolicyError(ctx, err):
+	default:
+		respondUnexpectedError(ctx, msg, err)
+	}
+	return true
+}
@@ -11,8 +11,8 @@ import (
 func Auth(token string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		header := c.GetHeader("Authorization")
-		fmt.Println(token)
-		if !slices.Contains([]string{
+		// an unset token must not let "Bearer " through
+		if token == "" || !slices.Contains([]string{
 			fmt.Sprintf("Bearer %s", token),
 			fmt.Sprintf("bearer %s", token),
 		}, header) {
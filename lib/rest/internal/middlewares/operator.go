@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OperatorKey is where Operator stores the name of the authenticated operator.
+const OperatorKey = "operator"
+
+// Operator authorizes each operator with a token of their own, given as
+// operator name to token, and stores the name of the caller under
+// OperatorKey. Operators without a token are left out, so no operators
+// close the endpoints.
+func Operator(tokens map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found {
+			token, found = strings.CutPrefix(header, "bearer ")
+		}
+		operator := ""
+		if found && token != "" {
+			for name, operatorToken := range tokens {
+				// compare every token, so the time taken does not tell which one is close
+				if operatorToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(operatorToken)) == 1 {
+					operator = name
+				}
+			}
+		}
+		if operator == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Set(OperatorKey, operator)
+		c.Next()
+	}
+}
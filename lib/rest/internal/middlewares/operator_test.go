@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/", Operator(map[string]string{"ops-alice": "alice-token", "ops-bob": "bob-token", "ops-carol": ""}), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(OperatorKey))
+	})
+	call := func(authorization string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", authorization)
+		// the operator comes from the token, not from what the caller claims
+		request.Header.Set("X-Actor", "ops-bob")
+		engine.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := call("Bearer alice-token")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ops-alice", recorder.Body.String())
+	assert.Equal(t, "ops-bob", call("bearer bob-token").Body.String())
+
+	for _, authorization := range []string{"", "Bearer ", "Bearer alice", "alice-token", "Bearer alice-token-2"} {
+		assert.Equal(t, http.StatusUnauthorized, call(authorization).Code, authorization)
+	}
+}
+
+func TestOperator_NoOperators(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/", Operator(nil), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Authorization", "Bearer ")
+	engine.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
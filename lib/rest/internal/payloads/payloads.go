@@ -1,10 +1,16 @@
 package payloads
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
+	"wallet/lib/adjustments"
+	"wallet/lib/beneficiaries"
 	"wallet/lib/core"
 	"wallet/lib/deposits"
+	"wallet/lib/exchange"
+	"wallet/lib/holds"
+	"wallet/lib/transfers"
 	"wallet/lib/withdraws"
 	withdraws_enums "wallet/lib/withdraws/enums"
 
@@ -15,25 +21,154 @@ type Wallet = core.Wallet
 type Transaction = core.Transaction
 type Withdraw = withdraws.Withdrawal
 type Deposit = deposits.Deposit
+type Transfer = transfers.Transfer
+type Quote = exchange.Quote
+type Balance = core.Balance
+type Statement = core.Statement
+type CreditLimitChange = core.CreditLimitChange
+type Adjustment = adjustments.Adjustment
+type Hold = holds.Hold
+type Beneficiary = beneficiaries.Beneficiary
+
+// WalletBalance is a wallet with its credit utilisation.
+type WalletBalance struct {
+	*Wallet
+	CreditUsed       int64 `json:"credit_used"`
+	SpendableBalance int64 `json:"spendable_balance"` // available balance plus unused credit
+}
+
+func NewWalletBalance(wallet *Wallet) WalletBalance {
+	return WalletBalance{
+		Wallet:           wallet,
+		CreditUsed:       wallet.CreditUsed(),
+		SpendableBalance: wallet.Spendable(),
+	}
+}
 
 type CreateWithdrawRequest struct {
-	UserID   uuid.UUID                `json:"user_id"`
-	IBan     string                   `json:"iban"`
-	Amount   int64                    `json:"amount"`
-	BankType withdraws_enums.BankType `json:"bank_type"`
+	UserID        uuid.UUID                `json:"user_id"`
+	Currency      core.Currency            `json:"currency"`
+	IBan          string                   `json:"iban,omitempty"`
+	BeneficiaryID *uuid.UUID               `json:"beneficiary_id,omitempty"` // a saved beneficiary instead of the iban
+	Amount        int64                    `json:"amount"`
+	BankType      withdraws_enums.BankType `json:"bank_type,omitempty"` // routed when omitted
+	// passed on to the bank with the payout
+	BeneficiaryName string `json:"beneficiary_name,omitempty"`
+	Description     string `json:"description,omitempty"`
 }
 
 type CreateDepositRequest struct {
-	UserID  uuid.UUID  `json:"user_id"`
-	Amount  int64      `json:"amount"`
-	ApplyAt *time.Time `json:"apply_at,omitempty"`
+	UserID            uuid.UUID       `json:"user_id"`
+	Currency          core.Currency   `json:"currency"`
+	Amount            int64           `json:"amount"`
+	ApplyAt           *time.Time      `json:"apply_at,omitempty"`
+	Schedule          []DepositLeg    `json:"schedule,omitempty"` // if set, amount is released in these parts and apply_at is ignored
+	Description       string          `json:"description,omitempty"`
+	SourceType        string          `json:"source_type,omitempty"`        // psp, order, bank_transfer or other (default)
+	ExternalReference string          `json:"external_reference,omitempty"` // unique per source_type
+	Metadata          json.RawMessage `json:"metadata,omitempty"`           // a JSON object
+}
+
+type DepositLeg struct {
+	Amount  int64     `json:"amount"`
+	ApplyAt time.Time `json:"apply_at"`
+}
+
+type RescheduleDepositRequest struct {
+	ApplyAt time.Time `json:"apply_at"`
+}
+
+type CreateTransferRequest struct {
+	FromUserID uuid.UUID     `json:"from_user_id"`
+	ToUserID   uuid.UUID     `json:"to_user_id"`
+	Currency   core.Currency `json:"currency"`
+	ToCurrency core.Currency `json:"to_currency,omitempty"` // must be empty or equal to currency, transfers never convert
+	Amount     int64         `json:"amount"`
+	Reference  string        `json:"reference"`
+	ApplyAt    *time.Time    `json:"apply_at,omitempty"` // if set, amount is held in receiver's blocked balance until then
+}
+
+type CreateHoldRequest struct {
+	UserID    uuid.UUID     `json:"user_id"`
+	Currency  core.Currency `json:"currency"`
+	Amount    int64         `json:"amount"`
+	Reference string        `json:"reference"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+type CaptureHoldRequest struct {
+	Amount int64 `json:"amount,omitempty"` // zero captures the whole hold
+}
+
+type CreateBeneficiaryRequest struct {
+	UserID     uuid.UUID `json:"user_id"`
+	IBan       string    `json:"iban"`
+	HolderName string    `json:"holder_name,omitempty"`
+	Label      string    `json:"label,omitempty"`
+}
+
+// UpdateBeneficiaryRequest changes the fields that are set; the iban never
+// changes, a new one is a new beneficiary.
+type UpdateBeneficiaryRequest struct {
+	HolderName *string `json:"holder_name,omitempty"`
+	Label      *string `json:"label,omitempty"`
+}
+
+type CreateQuoteRequest struct {
+	UserID       uuid.UUID     `json:"user_id"`
+	FromCurrency core.Currency `json:"from_currency"`
+	ToCurrency   core.Currency `json:"to_currency"`
+	Amount       int64         `json:"amount"` // in minor units of from_currency
+}
+
+type ExecuteQuoteRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type SetCreditLimitRequest struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	Currency    core.Currency `json:"currency"`
+	CreditLimit int64         `json:"credit_limit"`
+	Reason      string        `json:"reason"`
+}
+
+type SetWalletStatusRequest struct {
+	UserID   uuid.UUID     `json:"user_id"`
+	Currency core.Currency `json:"currency"`
+	Status   string        `json:"status"`
+	Reason   string        `json:"reason"`
+}
+
+type ProposeAdjustmentRequest struct {
+	UserID   uuid.UUID     `json:"user_id"`
+	Currency core.Currency `json:"currency"`
+	Balance  string        `json:"balance"` // available or blocked
+	Amount   int64         `json:"amount"`  // positive credits, negative debits
+	Reason   string        `json:"reason"`
 }
 
 type TransactionHistoryResponse struct {
 	HasMore      bool          `json:"has_more"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
 	Transactions []Transaction `json:"transactions"`
 }
 
+type StatementResponse struct {
+	*Statement
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type DepositListResponse struct {
+	HasMore  bool      `json:"has_more"`
+	Deposits []Deposit `json:"deposits"`
+}
+
+type WithdrawalListResponse struct {
+	HasMore     bool       `json:"has_more"`
+	Withdrawals []Withdraw `json:"withdrawals"`
+}
+
 type ErrorResponse struct {
 	Code    string `json:"code,omitempty"`
 	Message string `json:"message,omitempty"`
@@ -79,3 +214,48 @@ func CreateInvalidPayloadResponse(err error) Response {
 		},
 	}
 }
+
+func CreateErrorResponse(code, message string) Response {
+	return Response{
+		Error: &ErrorResponse{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+func CreateInvalidHeaderResponse(header string) Response {
+	return Response{
+		Error: &ErrorResponse{
+			Code:    "invalid_header",
+			Message: fmt.Sprintf("header %s is invalid", header),
+		},
+	}
+}
+
+func CreateIdempotencyKeyReusedResponse() Response {
+	return Response{
+		Error: &ErrorResponse{
+			Code:    "idempotency_key_reused",
+			Message: "idempotency key is already used for a different request",
+		},
+	}
+}
+
+func CreateCurrencyMismatchResponse() Response {
+	return Response{
+		Error: &ErrorResponse{
+			Code:    "currency_mismatch",
+			Message: "cross-currency operations require an explicit conversion",
+		},
+	}
+}
+
+func CreateNegativeBalanceResponse() Response {
+	return Response{
+		Error: &ErrorResponse{
+			Code:    "negative_balance",
+			Message: "operation would take the wallet balance below its limit",
+		},
+	}
+}
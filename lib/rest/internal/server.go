@@ -5,10 +5,15 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+	"wallet/lib/adjustments"
+	"wallet/lib/beneficiaries"
 	"wallet/lib/config"
 	"wallet/lib/core"
 	"wallet/lib/deposits"
+	"wallet/lib/exchange"
+	"wallet/lib/holds"
 	"wallet/lib/rest/internal/middlewares"
+	"wallet/lib/transfers"
 	"wallet/lib/utils/logger"
 	"wallet/lib/withdraws"
 
@@ -17,18 +22,29 @@ import (
 )
 
 type server struct {
-	engine          *gin.Engine
-	httpServer      *http.Server
-	depositService  deposits.Service
-	withdrawService withdraws.Service
-	coreRepoFactory core.RepoFactory
+	engine             *gin.Engine
+	httpServer         *http.Server
+	depositService     deposits.Service
+	withdrawService    withdraws.Service
+	transferService    transfers.Service
+	exchangeService    exchange.Service
+	adjustmentService  adjustments.Service
+	holdService        holds.Service
+	beneficiaryService beneficiaries.Service
+	coreRepoFactory    core.RepoFactory
 }
 
 func New(
 	bindAt int,
 	authToken string,
+	adminTokens map[string]string,
 	depositService deposits.Service,
 	withdrawService withdraws.Service,
+	transferService transfers.Service,
+	exchangeService exchange.Service,
+	adjustmentService adjustments.Service,
+	holdService holds.Service,
+	beneficiaryService beneficiaries.Service,
 	coreRepoFactory core.RepoFactory,
 
 ) *server {
@@ -41,14 +57,17 @@ func New(
 	engine.Use(slog_gin.New(logger.Get().WithGroup("gin")))
 
 	s := &server{
-		engine:          engine,
-		depositService:  depositService,
-		withdrawService: withdrawService,
-		coreRepoFactory: coreRepoFactory,
+		engine:             engine,
+		depositService:     depositService,
+		withdrawService:    withdrawService,
+		transferService:    transferService,
+		exchangeService:    exchangeService,
+		adjustmentService:  adjustmentService,
+		holdService:        holdService,
+		beneficiaryService: beneficiaryService,
+		coreRepoFactory:    coreRepoFactory,
 	}
-	engine.Use(middlewares.Auth(authToken))
-
-	s.registerHandlers()
+	s.registerHandlers(authToken, adminTokens)
 
 	s.httpServer = &http.Server{
 		Addr:    fmt.Sprintf(":%d", bindAt),
@@ -58,12 +77,47 @@ func New(
 	return s
 }
 
-func (s *server) registerHandlers() {
-	api := s.engine.Group("/api/v1")
+func (s *server) registerHandlers(authToken string, adminTokens map[string]string) {
+	api := s.engine.Group("/api/v1", middlewares.Auth(authToken))
 	api.GET("/balance", s.GetBalanceHandler)
+	api.GET("/statement", s.getStatementHandler)
+	api.GET("/wallets", s.getWalletsHandler)
 	api.GET("/transactions", s.getTransactionsHistoryHandler)
+	api.GET("/transactions/export", s.exportTransactionsHandler)
 	api.POST("/withdraw", s.createWithdrawHandler)
+	api.GET("/withdrawals", s.getWithdrawalsHandler)
+	api.GET("/withdrawals/:id", s.getWithdrawalHandler)
+	api.POST("/withdrawals/:id/cancel", s.cancelWithdrawalHandler)
 	api.POST("/deposit", s.createDepositHandler)
+	api.GET("/deposits", s.getDepositsHandler)
+	api.GET("/deposits/:id", s.getDepositHandler)
+	api.POST("/deposits/:id/cancel", s.cancelDepositHandler)
+	api.POST("/deposits/:id/reschedule", s.rescheduleDepositHandler)
+	api.POST("/transfer", s.createTransferHandler)
+	api.POST("/holds", s.createHoldHandler)
+	api.GET("/holds/:id", s.getHoldHandler)
+	api.POST("/holds/:id/capture", s.captureHoldHandler)
+	api.POST("/holds/:id/release", s.releaseHoldHandler)
+	api.POST("/beneficiaries", s.createBeneficiaryHandler)
+	api.GET("/beneficiaries", s.getBeneficiariesHandler)
+	api.GET("/beneficiaries/:id", s.getBeneficiaryHandler)
+	api.PATCH("/beneficiaries/:id", s.updateBeneficiaryHandler)
+	api.DELETE("/beneficiaries/:id", s.deleteBeneficiaryHandler)
+	api.POST("/fx/quotes", s.createQuoteHandler)
+	api.POST("/fx/quotes/:id/execute", s.executeQuoteHandler)
+
+	// operator endpoints, each operator with a token of their own
+	admin := s.engine.Group("/api/v1/admin", middlewares.Operator(adminTokens))
+	admin.PUT("/credit_limit", s.setCreditLimitHandler)
+	admin.GET("/credit_limit/changes", s.getCreditLimitChangesHandler)
+	admin.PUT("/wallet_status", s.setWalletStatusHandler)
+	admin.POST("/adjustments", s.proposeAdjustmentHandler)
+	admin.GET("/adjustments", s.getAdjustmentsHandler)
+	admin.GET("/adjustments/:id", s.getAdjustmentHandler)
+	admin.POST("/adjustments/:id/approve", s.approveAdjustmentHandler)
+	admin.POST("/adjustments/:id/reject", s.rejectAdjustmentHandler)
+	admin.POST("/beneficiaries/:id/verify", s.verifyBeneficiaryHandler)
+	admin.POST("/deposits/:id/retry", s.retryDepositHandler)
 }
 
 func (s *server) Run(ctx context.Context) error {
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"wallet/lib/core"
+	"wallet/lib/rest/internal/payloads"
+	"wallet/lib/transfers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *server) createTransferHandler(ctx *gin.Context) {
+	var request payloads.CreateTransferRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidPayloadResponse(err))
+		return
+	}
+	currency, err := normalizeCurrency(request.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("currency"))
+		return
+	}
+	if request.ToCurrency != "" {
+		toCurrency, err := core.ParseCurrency(string(request.ToCurrency))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("to_currency"))
+			return
+		}
+		if toCurrency != currency {
+			ctx.JSON(http.StatusBadRequest, payloads.CreateCurrencyMismatchResponse())
+			return
+		}
+	}
+	var transfer *payloads.Transfer
+	if request.ApplyAt == nil {
+		transfer, err = s.transferService.Transfer(ctx, request.FromUserID, request.ToUserID, currency, request.Amount, request.Reference)
+	} else {
+		transfer, err = s.transferService.TransferHeld(ctx, request.FromUserID, request.ToUserID, currency, request.Amount, request.Reference, *request.ApplyAt)
+	}
+	switch {
+	case errors.Is(err, transfers.ErrInsufficientBalance):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("insufficient_balance", "there is not enough available balance to transfer"))
+		return
+	case errors.Is(err, transfers.ErrInvalidAmount):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("amount"))
+		return
+	case errors.Is(err, transfers.ErrSameWallet):
+		ctx.JSON(http.StatusBadRequest, payloads.CreateErrorResponse("same_wallet", "cant transfer to the same wallet"))
+		return
+	case handleWalletPolicyError(ctx, err):
+		return
+	case err != nil:
+		respondUnexpectedError(ctx, "cant create transfer", err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, payloads.Response{
+		Data: transfer,
+	})
+}
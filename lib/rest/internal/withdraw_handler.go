@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"wallet/lib/rest/internal/payloads"
+	"wallet/lib/withdraws"
+	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (s *server) getWithdrawalHandler(ctx *gin.Context) {
+	id, ok := getWithdrawalID(ctx)
+	if !ok {
+		return
+	}
+	withdraw, err := s.withdrawService.Get(ctx, id)
+	if s.handleWithdrawalError(ctx, err, "cant get withdrawal") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: withdraw,
+	})
+}
+
+func (s *server) getWithdrawalsHandler(ctx *gin.Context) {
+	var filter repository.Filter
+	var ok bool
+	if filter.WalletID, ok = getUserID(ctx); !ok {
+		return
+	}
+	filter.Status = enums.PayoutStatus(ctx.Query("status"))
+	switch filter.Status {
+	case "", enums.NEW, enums.SENDING, enums.SENT, enums.SUCCESS, enums.FAILED:
+	default:
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("status"))
+		return
+	}
+	filter.Bank = enums.BankType(ctx.Query("bank"))
+	switch filter.Bank {
+	case "", enums.DUMMY, enums.SAMANAN, enums.MELLAT:
+	default:
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("bank"))
+		return
+	}
+	from, ok := getTimeParam(ctx, "from")
+	if !ok {
+		return
+	}
+	if from != nil {
+		filter.From = *from
+	}
+	to, ok := getTimeParam(ctx, "to")
+	if !ok {
+		return
+	}
+	if to != nil {
+		filter.To = *to
+	}
+	page, pageSize, err := getPageAndPageSize(ctx)
+	if errors.Is(err, ErrInvalidPage) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("page"))
+		return
+	}
+	if errors.Is(err, ErrInvalidPageSize) {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("page_size"))
+		return
+	}
+	list, hasMore, err := s.withdrawService.List(ctx, filter, page, pageSize)
+	if err != nil {
+		respondUnexpectedError(ctx, "cant list withdrawals", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: payloads.WithdrawalListResponse{
+			HasMore:     hasMore,
+			Withdrawals: list,
+		},
+	})
+}
+
+func (s *server) cancelWithdrawalHandler(ctx *gin.Context) {
+	id, ok := getWithdrawalID(ctx)
+	if !ok {
+		return
+	}
+	withdraw, err := s.withdrawService.Cancel(ctx, id)
+	if s.handleWithdrawalError(ctx, err, "cant cancel withdrawal") {
+		return
+	}
+	ctx.JSON(http.StatusOK, payloads.Response{
+		Data: withdraw,
+	})
+}
+
+func getWithdrawalID(ctx *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, payloads.CreateInvalidParamResponse("id"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// handleWithdrawalError writes the response for err and reports whether it did.
+func (s *server) handleWithdrawalError(ctx *gin.Context, err error, msg string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, withdraws.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, payloads.CreateErrorResponse("withdrawal_not_found", "withdrawal not found"))
+	case errors.Is(err, withdraws.ErrNotCancellable):
+		ctx.JSON(http.StatusConflict, payloads.CreateErrorResponse("withdrawal_not_cancellable", "withdrawal is already handed to the bank or finished"))
+	case handleWalletPolicyError(ctx, err):
+	default:
+		respondUnexpectedError(ctx, msg, err)
+	}
+	return true
+}
@@ -0,0 +1,8 @@
+package transfers
+
+import "errors"
+
+var ErrInsufficientBalance = errors.New("insufficient balance")
+var ErrInvalidAmount = errors.New("transfer amount must be positive")
+var ErrSameWallet = errors.New("cant transfer to the same wallet")
+var ErrInvalidState = errors.New("cant call this service method for transfer of this state")
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"wallet/lib/transfers/repository/internal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Transfer = internal.Transfer
+
+type Repo interface {
+	Create(context.Context, *Transfer) error
+	Update(context.Context, *Transfer) error
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*Transfer, error)
+	GetApplicableTransfers(ctx context.Context, IDPrefix string) ([]Transfer, error)
+
+	GetDBTransaction() *gorm.DB
+	Commit() error
+	RollBack() error
+}
+
+type RepoFactory interface {
+	New(tx *gorm.DB) Repo
+}
+
+func NewFactory(db *gorm.DB) RepoFactory {
+	return &repoFactory{
+		db: db,
+	}
+}
+
+type repoFactory struct {
+	db *gorm.DB
+}
+
+func (rf *repoFactory) New(tx *gorm.DB) Repo {
+	if tx == nil {
+		tx = rf.db.Begin()
+	}
+	return internal.NewTransferRepo(tx)
+}
@@ -0,0 +1,23 @@
+package internal
+
+import (
+	"time"
+	"wallet/lib/core"
+
+	"github.com/google/uuid"
+)
+
+type Transfer struct {
+	ID                  uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	FromWalletID        uuid.UUID     `gorm:"type:uuid;index" json:"from_wallet_id"`
+	ToWalletID          uuid.UUID     `gorm:"type:uuid;index" json:"to_wallet_id"`
+	Currency            core.Currency `gorm:"type:varchar(3);not null" json:"currency"`
+	Amount              int64         `gorm:"not null" json:"amount"`
+	Reference           string        `gorm:"size:255;index" json:"reference"`
+	Held                bool          `gorm:"not null;default:false" json:"held"`
+	CreatedAt           time.Time     `gorm:"index" json:"created_at"`
+	ApplyAt             time.Time     `gorm:"index" json:"apply_at"`
+	DebitTransactionID  uint64        `gorm:"index" json:"debit_transaction_id"`
+	CreditTransactionID uint64        `gorm:"index" json:"credit_transaction_id"`
+	ApplyTransactionID  uint64        `gorm:"index" json:"apply_transaction_id"`
+}
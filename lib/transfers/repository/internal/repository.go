@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type transferRepo struct {
+	tx *gorm.DB
+}
+
+func NewTransferRepo(tx *gorm.DB) *transferRepo {
+	return &transferRepo{tx: tx}
+}
+
+// Create inserts a new transfer and fills in ID automatically.
+func (r *transferRepo) Create(ctx context.Context, transfer *Transfer) error {
+	if transfer.ID == uuid.Nil {
+		transfer.ID = uuid.New()
+	}
+	return r.tx.WithContext(ctx).Create(transfer).Error
+}
+
+// Update updates an existing transfer by ID.
+func (r *transferRepo) Update(ctx context.Context, transfer *Transfer) error {
+	return r.tx.WithContext(ctx).
+		Model(&Transfer{}).
+		Where("id = ?", transfer.ID).
+		Updates(transfer).Error
+}
+
+// GetDBTransaction returns the underlying gorm.DB (transaction).
+func (r *transferRepo) GetDBTransaction() *gorm.DB {
+	return r.tx
+}
+
+// Commit commits the transaction.
+func (r *transferRepo) Commit() error {
+	return r.tx.Commit().Error
+}
+
+// RollBack rolls back the transaction.
+func (r *transferRepo) RollBack() error {
+	return r.tx.Rollback().Error
+}
+
+// GetForUpdate fetches a transfer by ID with row-level locking.
+func (r *transferRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*Transfer, error) {
+	var transfer Transfer
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&transfer, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// GetApplicableTransfers fetches held transfers that:
+// - ID starts with IDPrefix
+// - ApplyAt is before now
+// - ApplyTransactionID = 0
+func (r *transferRepo) GetApplicableTransfers(ctx context.Context, IDPrefix string) ([]Transfer, error) {
+	var transfers []Transfer
+
+	if err := r.tx.WithContext(ctx).
+		Where("id::text LIKE ?", IDPrefix+"%").
+		Where("held").
+		Where("apply_at < NOW()").
+		Where("apply_transaction_id = 0").
+		Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
@@ -0,0 +1,212 @@
+package transfers
+
+import (
+	"bytes"
+	"context"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/transfers/repository"
+
+	"github.com/google/uuid"
+)
+
+type Transfer = repository.Transfer
+
+type Service interface {
+	// Transfer moves amount from the available balance of one wallet to the
+	// available balance of another.
+	Transfer(ctx context.Context, from, to uuid.UUID, currency core.Currency, amount int64, reference string) (*Transfer, error)
+	// TransferHeld is like Transfer but the amount lands in the blocked balance
+	// of the receiver until applyAt.
+	TransferHeld(ctx context.Context, from, to uuid.UUID, currency core.Currency, amount int64, reference string, applyAt time.Time) (*Transfer, error)
+	Apply(context.Context, *Transfer) error
+	GetApplicableTransfers(ctx context.Context, IDPrefix string) ([]Transfer, error)
+}
+
+func New(
+	coreRepoFactory core.RepoFactory,
+	repoFactory repository.RepoFactory,
+) Service {
+	return &service{
+		coreRepoFactory: coreRepoFactory,
+		repoFactory:     repoFactory,
+	}
+}
+
+type service struct {
+	coreRepoFactory core.RepoFactory
+	repoFactory     repository.RepoFactory
+}
+
+func (s *service) Transfer(ctx context.Context, from, to uuid.UUID, currency core.Currency, amount int64, reference string) (*Transfer, error) {
+	now := time.Now()
+	transfer := &Transfer{
+		FromWalletID: from,
+		ToWalletID:   to,
+		Currency:     currency,
+		Amount:       amount,
+		Reference:    reference,
+		CreatedAt:    now,
+		ApplyAt:      now,
+	}
+	return transfer, s.create(ctx, transfer)
+}
+
+func (s *service) TransferHeld(ctx context.Context, from, to uuid.UUID, currency core.Currency, amount int64, reference string, applyAt time.Time) (*Transfer, error) {
+	transfer := &Transfer{
+		FromWalletID: from,
+		ToWalletID:   to,
+		Currency:     currency,
+		Amount:       amount,
+		Reference:    reference,
+		Held:         true,
+		CreatedAt:    time.Now(),
+		ApplyAt:      applyAt,
+	}
+	return transfer, s.create(ctx, transfer)
+}
+
+func (s *service) create(ctx context.Context, transfer *Transfer) error {
+	if transfer.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if transfer.FromWalletID == transfer.ToWalletID {
+		return ErrSameWallet
+	}
+	transferRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(transferRepo.GetDBTransaction())
+	defer func() {
+		_ = transferRepo.RollBack()
+	}()
+	sender, receiver, err := lockWallets(ctx, coreRepo, transfer.FromWalletID, transfer.ToWalletID, transfer.Currency)
+	if err != nil {
+		return err
+	}
+	if err := sender.CanDebit(); err != nil {
+		return err
+	}
+	if err := receiver.CanCredit(); err != nil {
+		return err
+	}
+	if sender.Spendable() < transfer.Amount {
+		return ErrInsufficientBalance
+	}
+	if err := transferRepo.Create(ctx, transfer); err != nil {
+		return err
+	}
+	sender.AvailableBalance -= transfer.Amount
+	if transfer.Held {
+		receiver.BlockedBalance += transfer.Amount
+	} else {
+		receiver.AvailableBalance += transfer.Amount
+	}
+	if err := coreRepo.Wallet().Update(ctx, sender); err != nil {
+		return err
+	}
+	if err := coreRepo.Wallet().Update(ctx, receiver); err != nil {
+		return err
+	}
+	debitTrx := &core.Transaction{
+		Kind:        core.KindTransfer,
+		WalletID:    sender.UserID,
+		Currency:    transfer.Currency,
+		Amount:      -transfer.Amount,
+		Reference:   transfer.ID,
+		Description: "transfer to " + receiver.UserID.String(),
+	}
+	creditTrx := &core.Transaction{
+		Kind:        core.KindTransfer,
+		WalletID:    receiver.UserID,
+		Currency:    transfer.Currency,
+		Reference:   transfer.ID,
+		Description: "transfer from " + sender.UserID.String(),
+	}
+	if transfer.Held {
+		creditTrx.BlockedAmount = transfer.Amount
+	} else {
+		creditTrx.Amount = transfer.Amount
+	}
+	if err := core.Post(ctx, coreRepo, core.Suspense, debitTrx, creditTrx); err != nil {
+		return err
+	}
+	transfer.DebitTransactionID = debitTrx.ID
+	transfer.CreditTransactionID = creditTrx.ID
+	if err := transferRepo.Update(ctx, transfer); err != nil {
+		return err
+	}
+	return transferRepo.Commit()
+}
+
+// lockWallets locks both wallets in a deterministic order so two opposite
+// transfers between the same wallets can not deadlock.
+func lockWallets(ctx context.Context, coreRepo core.Repo, from, to uuid.UUID, currency core.Currency) (*core.Wallet, *core.Wallet, error) {
+	first, second := from, to
+	if bytes.Compare(from[:], to[:]) > 0 {
+		first, second = to, from
+	}
+	firstWallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, first, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	secondWallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, second, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first == from {
+		return firstWallet, secondWallet, nil
+	}
+	return secondWallet, firstWallet, nil
+}
+
+func (s *service) Apply(ctx context.Context, transfer *Transfer) error {
+	transferRepo := s.repoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(transferRepo.GetDBTransaction())
+	defer func() {
+		_ = transferRepo.RollBack()
+	}()
+	locked, err := transferRepo.GetForUpdate(ctx, transfer.ID)
+	if err != nil {
+		return err
+	}
+	if !locked.Held || locked.ApplyTransactionID != 0 {
+		return ErrInvalidState
+	}
+	*transfer = *locked
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, transfer.ToWalletID, transfer.Currency)
+	if err != nil {
+		return err
+	}
+	if err := wallet.CanCredit(); err != nil {
+		return err
+	}
+	wallet.AvailableBalance += transfer.Amount
+	wallet.BlockedBalance -= transfer.Amount
+	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
+		return err
+	}
+	trx := &core.Transaction{
+		Kind:          core.KindApply,
+		WalletID:      transfer.ToWalletID,
+		Currency:      transfer.Currency,
+		Amount:        transfer.Amount,
+		BlockedAmount: -transfer.Amount,
+		Reference:     transfer.ID,
+		Description:   "held transfer release",
+	}
+	if err := core.Post(ctx, coreRepo, core.Suspense, trx); err != nil {
+		return err
+	}
+	transfer.ApplyTransactionID = trx.ID
+	if err := transferRepo.Update(ctx, transfer); err != nil {
+		return err
+	}
+	return transferRepo.Commit()
+}
+
+func (s *service) GetApplicableTransfers(ctx context.Context, IDPrefix string) ([]Transfer, error) {
+	transferRepo := s.repoFactory.New(nil)
+	defer func() {
+		_ = transferRepo.RollBack()
+	}()
+	return transferRepo.GetApplicableTransfers(ctx, IDPrefix)
+}
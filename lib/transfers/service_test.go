@@ -0,0 +1,241 @@
+package transfers_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/transfers"
+	"wallet/lib/transfers/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// --- Mocks ---
+
+type MockTransferRepo struct{ mock.Mock }
+
+func (m *MockTransferRepo) Create(ctx context.Context, t *repository.Transfer) error {
+	return m.Called(ctx, t).Error(0)
+}
+func (m *MockTransferRepo) Update(ctx context.Context, t *repository.Transfer) error {
+	return m.Called(ctx, t).Error(0)
+}
+func (m *MockTransferRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*repository.Transfer, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*repository.Transfer), args.Error(1)
+}
+func (m *MockTransferRepo) GetApplicableTransfers(ctx context.Context, prefix string) ([]repository.Transfer, error) {
+	args := m.Called(ctx, prefix)
+	return args.Get(0).([]repository.Transfer), args.Error(1)
+}
+func (m *MockTransferRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockTransferRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockTransferRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockTransferRepoFactory struct{ mock.Mock }
+
+func (m *MockTransferRepoFactory) New(tx *gorm.DB) repository.Repo {
+	return m.Called(tx).Get(0).(repository.Repo)
+}
+
+type MockWalletRepo struct {
+	mock.Mock
+	locked []uuid.UUID
+}
+
+func (m *MockWalletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	m.locked = append(m.locked, userID)
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) List(ctx context.Context, userID uuid.UUID) ([]core.Wallet, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) Update(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateCreditLimit(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateStatus(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+
+type MockTransactionRepo struct{ mock.Mock }
+
+func (m *MockTransactionRepo) Create(ctx context.Context, trx *core.Transaction) error {
+	return m.Called(ctx, trx).Error(0)
+}
+func (m *MockTransactionRepo) Get(ctx context.Context, filter core.TransactionFilter, pageNumber int, pageSize int) ([]core.Transaction, bool, error) {
+	args := m.Called(ctx, filter, pageNumber, pageSize)
+	return args.Get(0).([]core.Transaction), args.Bool(1), args.Error(2)
+}
+func (m *MockTransactionRepo) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency core.Currency, at time.Time) (*core.Balance, error) {
+	args := m.Called(ctx, userID, currency, at)
+	return args.Get(0).(*core.Balance), args.Error(1)
+}
+func (m *MockTransactionRepo) List(ctx context.Context, filter core.TransactionFilter, cursor uint64, limit int) ([]core.Transaction, uint64, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	return args.Get(0).([]core.Transaction), args.Get(1).(uint64), args.Error(2)
+}
+
+type MockLedgerRepo struct{ mock.Mock }
+
+func (m *MockLedgerRepo) Post(ctx context.Context, entries []core.LedgerEntry) error {
+	return m.Called(ctx, entries).Error(0)
+}
+func (m *MockLedgerRepo) GetBalance(ctx context.Context, account core.Account, currency core.Currency) (*core.AccountBalance, error) {
+	args := m.Called(ctx, account, currency)
+	return args.Get(0).(*core.AccountBalance), args.Error(1)
+}
+
+type MockCoreRepo struct{ mock.Mock }
+
+func (m *MockCoreRepo) Wallet() core.WalletRepo {
+	return m.Called().Get(0).(core.WalletRepo)
+}
+func (m *MockCoreRepo) Transaction() core.TransactionRepo {
+	return m.Called().Get(0).(core.TransactionRepo)
+}
+func (m *MockCoreRepo) Ledger() core.LedgerRepo {
+	return m.Called().Get(0).(core.LedgerRepo)
+}
+func (m *MockCoreRepo) CreditLimitChange() core.CreditLimitChangeRepo {
+	return m.Called().Get(0).(core.CreditLimitChangeRepo)
+}
+func (m *MockCoreRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockCoreRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockCoreRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockCoreRepoFactory struct{ mock.Mock }
+
+func (m *MockCoreRepoFactory) New(tx *gorm.DB) core.Repo {
+	return m.Called(tx).Get(0).(core.Repo)
+}
+
+// --- Tests ---
+
+func setup(sender, receiver *core.Wallet) (transfers.Service, *MockWalletRepo, *MockTransferRepo) {
+	ctx := context.Background()
+	transferRepo := new(MockTransferRepo)
+	walletRepo := new(MockWalletRepo)
+	trxRepo := new(MockTransactionRepo)
+	ledgerRepo := new(MockLedgerRepo)
+	coreRepo := new(MockCoreRepo)
+	transferRepoFactory := new(MockTransferRepoFactory)
+	coreRepoFactory := new(MockCoreRepoFactory)
+
+	transferRepo.On("Create", ctx, mock.Anything).Return(nil)
+	transferRepo.On("Update", ctx, mock.Anything).Return(nil)
+	transferRepo.On("Commit").Return(nil)
+	transferRepo.On("RollBack").Return(nil)
+	transferRepoFactory.On("New", (*gorm.DB)(nil)).Return(transferRepo)
+
+	walletRepo.On("GetOrCreateForUpdate", ctx, sender.UserID, core.IRR).Return(sender, nil)
+	walletRepo.On("GetOrCreateForUpdate", ctx, receiver.UserID, core.IRR).Return(receiver, nil)
+	walletRepo.On("Update", ctx, mock.Anything).Return(nil)
+	trxRepo.On("Create", ctx, mock.Anything).Return(nil)
+	ledgerRepo.On("Post", ctx, mock.Anything).Return(nil)
+
+	coreRepo.On("Wallet").Return(walletRepo)
+	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
+	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
+
+	return transfers.New(coreRepoFactory, transferRepoFactory), walletRepo, transferRepo
+}
+
+func TestService_Transfer(t *testing.T) {
+	sender := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500}
+	receiver := &core.Wallet{UserID: uuid.New(), Currency: core.IRR}
+	service, walletRepo, transferRepo := setup(sender, receiver)
+
+	transfer, err := service.Transfer(context.Background(), sender.UserID, receiver.UserID, core.IRR, 200, "order-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), sender.AvailableBalance)
+	assert.Equal(t, int64(200), receiver.AvailableBalance)
+	assert.Equal(t, int64(0), receiver.BlockedBalance)
+	assert.False(t, transfer.Held)
+	transferRepo.AssertCalled(t, "Commit")
+
+	// wallets are always locked in ascending ID order
+	assert.Len(t, walletRepo.locked, 2)
+	assert.True(t, bytes.Compare(walletRepo.locked[0][:], walletRepo.locked[1][:]) < 0)
+}
+
+func TestService_TransferHeld(t *testing.T) {
+	sender := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500}
+	receiver := &core.Wallet{UserID: uuid.New(), Currency: core.IRR}
+	service, _, _ := setup(sender, receiver)
+
+	transfer, err := service.TransferHeld(context.Background(), sender.UserID, receiver.UserID, core.IRR, 200, "order-1", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, transfer.Held)
+	assert.Equal(t, int64(0), receiver.AvailableBalance)
+	assert.Equal(t, int64(200), receiver.BlockedBalance)
+}
+
+func TestService_TransferInsufficientBalance(t *testing.T) {
+	sender := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 100}
+	receiver := &core.Wallet{UserID: uuid.New(), Currency: core.IRR}
+	service, _, transferRepo := setup(sender, receiver)
+
+	_, err := service.Transfer(context.Background(), sender.UserID, receiver.UserID, core.IRR, 200, "")
+	assert.ErrorIs(t, err, transfers.ErrInsufficientBalance)
+	assert.Equal(t, int64(100), sender.AvailableBalance)
+	transferRepo.AssertNotCalled(t, "Commit")
+}
+
+func TestService_TransferWithinCreditLimit(t *testing.T) {
+	sender := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 100, CreditLimit: 150}
+	receiver := &core.Wallet{UserID: uuid.New(), Currency: core.IRR}
+	service, _, _ := setup(sender, receiver)
+
+	_, err := service.Transfer(context.Background(), sender.UserID, receiver.UserID, core.IRR, 200, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-100), sender.AvailableBalance)
+	assert.Equal(t, int64(100), sender.CreditUsed())
+
+	_, err = service.Transfer(context.Background(), sender.UserID, receiver.UserID, core.IRR, 51, "")
+	assert.ErrorIs(t, err, transfers.ErrInsufficientBalance)
+}
+
+func TestService_TransferFrozenWallets(t *testing.T) {
+	sender := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 500, Status: core.WalletDebitFrozen}
+	receiver := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, Status: core.WalletDebitFrozen}
+	service, _, transferRepo := setup(sender, receiver)
+
+	// a debit frozen wallet can receive but not send
+	_, err := service.Transfer(context.Background(), sender.UserID, receiver.UserID, core.IRR, 200, "")
+	assert.ErrorIs(t, err, core.ErrWalletFrozen)
+	assert.Equal(t, int64(500), sender.AvailableBalance)
+
+	sender.Status = core.WalletActive
+	receiver.Status = core.WalletFullyFrozen
+	_, err = service.Transfer(context.Background(), sender.UserID, receiver.UserID, core.IRR, 200, "")
+	assert.ErrorIs(t, err, core.ErrWalletFrozen)
+	transferRepo.AssertNotCalled(t, "Commit")
+
+	receiver.Status = core.WalletDebitFrozen
+	_, err = service.Transfer(context.Background(), sender.UserID, receiver.UserID, core.IRR, 200, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), receiver.AvailableBalance)
+}
+
+func TestService_TransferSameWallet(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), AvailableBalance: 100}
+	service, _, _ := setup(wallet, wallet)
+
+	_, err := service.Transfer(context.Background(), wallet.UserID, wallet.UserID, core.IRR, 10, "")
+	assert.ErrorIs(t, err, transfers.ErrSameWallet)
+}
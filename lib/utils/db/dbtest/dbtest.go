@@ -0,0 +1,93 @@
+// Package dbtest lets repository tests run GORM against a recording
+// connection instead of a database.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Statement is one statement sent to the database with its bound arguments.
+type Statement struct {
+	SQL  string
+	Args []any
+}
+
+// Recorder is a database/sql connection that keeps the statements it is
+// given. Every query is answered with Rows under Columns, every other
+// statement reports Affected rows.
+type Recorder struct {
+	Columns    []string
+	Rows       [][]driver.Value
+	Affected   int64
+	Statements []Statement
+}
+
+// Open returns a GORM postgres handle on recorder. GORM does not wrap single
+// writes in transactions of its own, like on the transactions the repository
+// factories hand out.
+func Open(t testing.TB, recorder *Recorder) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(recorder)}), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// Find returns the first statement containing fragment and its position.
+func (r *Recorder) Find(fragment string) (Statement, int, bool) {
+	for i, statement := range r.Statements {
+		if strings.Contains(statement.SQL, fragment) {
+			return statement, i, true
+		}
+	}
+	return Statement{}, -1, false
+}
+
+func (r *Recorder) Connect(context.Context) (driver.Conn, error) { return r, nil }
+func (r *Recorder) Driver() driver.Driver                        { return nil }
+func (r *Recorder) Prepare(string) (driver.Stmt, error)          { return nil, driver.ErrSkip }
+func (r *Recorder) Close() error                                 { return nil }
+func (r *Recorder) Begin() (driver.Tx, error)                    { return nil, driver.ErrSkip }
+
+func (r *Recorder) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r.record(query, args)
+	return driver.RowsAffected(r.Affected), nil
+}
+
+func (r *Recorder) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r.record(query, args)
+	return &rows{columns: r.Columns, rows: r.Rows}, nil
+}
+
+func (r *Recorder) record(query string, args []driver.NamedValue) {
+	values := make([]any, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	r.Statements = append(r.Statements, Statement{SQL: query, Args: values})
+}
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -9,6 +9,14 @@ import (
 	"wallet/lib/withdraws/integrations"
 )
 
+// payoutNotFoundGrace is how long a bank may not list a payout it
+// acknowledged before the payout is sent again.
+const payoutNotFoundGrace = 15 * time.Minute
+
+// unsupportedCurrencyReason is the reason code of withdrawals failed because
+// their bank does not pay out their currency.
+const unsupportedCurrencyReason = "unsupported_currency"
+
 type jobType int
 
 const (
@@ -27,7 +35,7 @@ type worker struct {
 	concurrency int
 	backOff     time.Duration
 	retryCount  int
-	client      integrations.BankClient
+	client      integrations.BankClientV2
 
 	jobs chan job
 	done chan struct{}
@@ -63,8 +71,8 @@ func (w *worker) doWithRetry(ctx context.Context, fn func() error) error {
 	var err error
 	for i := 0; i < w.retryCount; i++ {
 		err = fn()
-		if err == nil {
-			return nil
+		if err == nil || !retryable(err) {
+			return err
 		}
 		if errors.Is(ctx.Err(), context.Canceled) {
 			return ctx.Err()
@@ -78,49 +86,91 @@ func (w *worker) doWithRetry(ctx context.Context, fn func() error) error {
 	return err
 }
 
+// retryable tells whether asking the bank again may get another answer.
+func retryable(err error) bool {
+	return !errors.Is(err, integrations.ErrDuplicatePayout) &&
+		!errors.Is(err, integrations.ErrPayoutNotFound) &&
+		!errors.Is(err, integrations.ErrUnsupportedCurrency)
+}
+
 func (w *worker) doSend(ctx context.Context, wd *Withdrawal) error {
-	var status enums.PayoutStatus
+	// claim a new withdrawal first, so it can not be cancelled once the bank
+	// may have it; a cancelled one is not sent at all
+	if wd.Status == enums.NEW {
+		if err := w.service.Claim(ctx, wd); err != nil {
+			return err
+		}
+	}
+	request := integrations.PayoutRequest{
+		TrackID:         wd.ID.String(),
+		Iban:            wd.Iban,
+		Amount:          wd.Amount,
+		Currency:        wd.Currency,
+		BeneficiaryName: wd.BeneficiaryName,
+		Description:     wd.Description,
+	}
+	var result *integrations.PayoutResult
 	err := w.doWithRetry(ctx, func() error {
 		var e error
-		status, e = w.client.Send(wd.Iban, wd.Amount, wd.ID.String())
+		result, e = w.client.Send(ctx, request)
 		return e
 	})
-	if errors.Is(err, integrations.ErrDuplicatePayout) {
-		w.service.MarkAsSent(ctx, wd)
-		return nil
-	}
-	if err != nil {
+	w.recordSend(ctx, wd, err)
+	switch {
+	case errors.Is(err, integrations.ErrDuplicatePayout):
+		// already with the bank, the status check picks it up
+		return w.service.MarkAsSent(ctx, wd)
+	case errors.Is(err, integrations.ErrUnsupportedCurrency):
+		// the bank never takes it, the funds go back to the wallet
+		result = &integrations.PayoutResult{Status: enums.FAILED, ReasonCode: unsupportedCurrencyReason, Reason: err.Error()}
+	case err != nil:
+		// the bank may or may not have it; it stays claimed and is sent
+		// again with the same track id on the next round
 		return err
 	}
-	if status == enums.SUCCESS {
-		return w.service.Complete(ctx, wd)
-	}
-	if status == enums.FAILED {
-		return w.service.Reverse(ctx, wd)
-	}
-	if status == enums.SENT {
-		return w.service.MarkAsSent(ctx, wd)
+	return w.service.ApplyPayoutResult(ctx, wd, result)
+}
+
+// recordSend keeps the health of the bank for routing. Only failures of the
+// bank count: a duplicate payout means the bank answered, and a cancelled
+// send or a payout the bank does not take says nothing about it.
+func (w *worker) recordSend(ctx context.Context, wd *Withdrawal, err error) {
+	switch {
+	case errors.Is(err, integrations.ErrDuplicatePayout):
+		err = nil
+	case ctx.Err() != nil, errors.Is(err, integrations.ErrUnsupportedCurrency):
+		return
 	}
-	return nil
+	_ = w.service.RecordBankSend(ctx, wd.Bank, err)
 }
 
 func (w *worker) doCheck(ctx context.Context, wd *Withdrawal) error {
-	var status enums.PayoutStatus
+	var result *integrations.PayoutResult
 	err := w.doWithRetry(ctx, func() error {
 		var e error
-		status, e = w.client.GetStatus(wd.ID.String())
+		result, e = w.client.GetStatus(ctx, wd.ID.String())
 		return e
 	})
+	if errors.Is(err, integrations.ErrPayoutNotFound) {
+		return w.resend(ctx, wd)
+	}
 	if err != nil {
 		return err
 	}
-	if status == enums.SUCCESS {
-		return w.service.Complete(ctx, wd)
+	return w.service.ApplyPayoutResult(ctx, wd, result)
+}
+
+// resend sends a withdrawal again that the bank has no record of, once it
+// had time to list it; the track id stays the same, so a bank that has it
+// after all answers with a duplicate.
+func (w *worker) resend(ctx context.Context, wd *Withdrawal) error {
+	if wd.SentAt != nil && time.Since(*wd.SentAt) < payoutNotFoundGrace {
+		return integrations.ErrPayoutNotFound
 	}
-	if status == enums.FAILED {
-		return w.service.Reverse(ctx, wd)
+	if err := w.service.Resend(ctx, wd); err != nil {
+		return err
 	}
-	return nil
+	return w.doSend(ctx, wd)
 }
 
 func (w *worker) SendToBank(ctx context.Context, wd *Withdrawal) error {
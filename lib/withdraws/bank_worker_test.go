@@ -0,0 +1,139 @@
+package withdraws_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/withdraws"
+	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeBank answers every send with result or err and every status check
+// with status or statusErr.
+type fakeBank struct {
+	result    *integrations.PayoutResult
+	err       error
+	status    *integrations.PayoutResult
+	statusErr error
+	trackIDs  []string
+}
+
+func (b *fakeBank) Send(ctx context.Context, request integrations.PayoutRequest) (*integrations.PayoutResult, error) {
+	b.trackIDs = append(b.trackIDs, request.TrackID)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return b.result, b.err
+}
+
+func (b *fakeBank) GetStatus(ctx context.Context, trackID string) (*integrations.PayoutResult, error) {
+	return b.status, b.statusErr
+}
+
+func TestWorker_SendFailsAfterClaim(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.NEW)
+	service, withdrawRepo := setup(withdraw, wallet)
+	withdrawRepo.On("RecordBankSend", context.Background(), enums.DUMMY, mock.Anything).Return(nil)
+	bank := &fakeBank{err: integrations.ErrUnexpectedResponse}
+	worker := withdraws.NewWorker(service, 1, 0, 2, bank)
+
+	// the bank may have it, so it stays claimed and can not be cancelled
+	listed := *withdraw
+	assert.ErrorIs(t, withdraws.DoSend(worker, context.Background(), &listed), integrations.ErrUnexpectedResponse)
+	assert.Equal(t, enums.SENDING, withdrawRepo.stored.Status)
+	_, err := service.Cancel(context.Background(), withdraw.ID)
+	assert.ErrorIs(t, err, withdraws.ErrNotCancellable)
+	assert.Equal(t, int64(200), wallet.BlockedBalance)
+	withdrawRepo.AssertCalled(t, "RecordBankSend", context.Background(), enums.DUMMY, integrations.ErrUnexpectedResponse)
+
+	// the banker lists it again and sends it with the same track id
+	unfinished, err := service.GetUnFinishedWithdraws(context.Background(), "", enums.DUMMY)
+	assert.NoError(t, err)
+	listed = unfinished[0]
+	listed.Status = withdrawRepo.stored.Status
+	bank.err = nil
+	bank.result = &integrations.PayoutResult{Status: enums.SENT, Reference: "ref-1"}
+	assert.NoError(t, withdraws.DoSend(worker, context.Background(), &listed))
+	assert.Equal(t, enums.SENT, withdrawRepo.stored.Status)
+	assert.Equal(t, "ref-1", withdrawRepo.stored.BankResult.Reference)
+	assert.Equal(t, []string{withdraw.ID.String(), withdraw.ID.String(), withdraw.ID.String()}, bank.trackIDs)
+}
+
+func TestWorker_SendCancelledAfterClaim(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.NEW)
+	service, withdrawRepo := setup(withdraw, wallet)
+	assert.NoError(t, service.Claim(context.Background(), withdraw))
+	worker := withdraws.NewWorker(service, 1, 0, 3, &fakeBank{})
+
+	// the banker shuts down while sending
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, withdraws.DoSend(worker, ctx, withdraw), context.Canceled)
+	assert.Equal(t, enums.SENDING, withdrawRepo.stored.Status)
+	withdrawRepo.AssertNotCalled(t, "RecordBankSend", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWorker_SendDuplicate(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.SENDING)
+	service, withdrawRepo := setup(withdraw, wallet)
+	withdrawRepo.On("RecordBankSend", context.Background(), enums.DUMMY, nil).Return(nil)
+	worker := withdraws.NewWorker(service, 1, 0, 3, &fakeBank{err: integrations.ErrDuplicatePayout})
+
+	// an earlier send reached the bank after all
+	assert.NoError(t, withdraws.DoSend(worker, context.Background(), withdraw))
+	assert.Equal(t, enums.SENT, withdrawRepo.stored.Status)
+	assert.Equal(t, int64(200), wallet.BlockedBalance)
+}
+
+func TestWorker_SendUnsupportedCurrency(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.USD, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.NEW)
+	service, withdrawRepo := setup(withdraw, wallet)
+	worker := withdraws.NewWorker(service, 1, time.Hour, 3, &fakeBank{err: integrations.ErrUnsupportedCurrency})
+
+	// the bank never takes it, so the amount goes back to the wallet
+	assert.NoError(t, withdraws.DoSend(worker, context.Background(), withdraw))
+	assert.Equal(t, enums.FAILED, withdrawRepo.stored.Status)
+	assert.Equal(t, "unsupported_currency", withdrawRepo.stored.BankResult.ReasonCode)
+	assert.Equal(t, uint64(42), withdrawRepo.stored.ReverserTransactionID)
+	assert.Equal(t, int64(200), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+	withdrawRepo.AssertNotCalled(t, "RecordBankSend", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWorker_CheckPayoutNotFound(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.SENT)
+	sentAt := time.Now()
+	withdraw.SentAt = &sentAt
+	service, withdrawRepo := setup(withdraw, wallet)
+	withdrawRepo.On("RecordBankSend", context.Background(), enums.DUMMY, nil).Return(nil)
+	bank := &fakeBank{statusErr: integrations.ErrPayoutNotFound}
+	worker := withdraws.NewWorker(service, 1, 0, 3, bank)
+
+	// the bank may not list it yet
+	listed := *withdraw
+	assert.ErrorIs(t, withdraws.DoCheck(worker, context.Background(), &listed), integrations.ErrPayoutNotFound)
+	assert.Equal(t, enums.SENT, withdrawRepo.stored.Status)
+	assert.Empty(t, bank.trackIDs)
+
+	// but after a while it is sent again
+	sentAt = time.Now().Add(-time.Hour)
+	listed = *withdraw
+	bank.result = &integrations.PayoutResult{Status: enums.SENT, Reference: "ref-2"}
+	assert.NoError(t, withdraws.DoCheck(worker, context.Background(), &listed))
+	assert.Equal(t, []string{withdraw.ID.String()}, bank.trackIDs)
+	assert.Equal(t, enums.SENT, withdrawRepo.stored.Status)
+	assert.Equal(t, "ref-2", withdrawRepo.stored.BankResult.Reference)
+	assert.WithinDuration(t, time.Now(), *withdrawRepo.stored.SentAt, time.Minute)
+	assert.Equal(t, int64(200), wallet.BlockedBalance)
+}
@@ -3,6 +3,7 @@ package enums
 type PayoutStatus string
 
 const NEW = PayoutStatus("new")
+const SENDING = PayoutStatus("sending") // claimed by the banker, the bank may not have it yet
 const SENT = PayoutStatus("sent")
 const SUCCESS = PayoutStatus("success")
 const FAILED = PayoutStatus("failed")
@@ -1,6 +1,23 @@
 package withdraws
 
-import "errors"
+import (
+	"errors"
+	"wallet/lib/beneficiaries"
+	"wallet/lib/fees"
+	"wallet/lib/iban"
+	"wallet/lib/withdraws/routing"
+)
 
 var ErrInsufficientBalance = errors.New("insufficient balance")
+var ErrInvalidAmount = errors.New("withdrawal amount must be positive")
 var ErrInvalidState = errors.New("cant call this service method for withdraw of this state")
+var ErrNotFound = errors.New("withdrawal not found")
+var ErrNotCancellable = errors.New("withdrawal is already handed to the bank")
+var ErrNoRoute = errors.New("no bank can take the withdrawal")
+var ErrQuotaExceeded = routing.ErrQuotaExceeded
+var ErrInvalidIban = iban.ErrInvalid
+var ErrNoFeeSchedule = fees.ErrNoSchedule
+var ErrBeneficiaryNotFound = beneficiaries.ErrNotFound
+var ErrCoolingOff = beneficiaries.ErrCoolingOff
+var ErrBeneficiaryNotVerified = beneficiaries.ErrNotVerified
+var ErrBeneficiaryRequired = beneficiaries.ErrNotSaved
@@ -0,0 +1,13 @@
+package withdraws
+
+import "context"
+
+// DoSend and DoCheck run a job of the worker right away instead of through
+// its queue.
+func DoSend(w Worker, ctx context.Context, wd *Withdrawal) error {
+	return w.(*worker).doSend(ctx, wd)
+}
+
+func DoCheck(w Worker, ctx context.Context, wd *Withdrawal) error {
+	return w.(*worker).doCheck(ctx, wd)
+}
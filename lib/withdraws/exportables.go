@@ -3,27 +3,69 @@ package withdraws
 import (
 	"context"
 	"time"
+	"wallet/lib/beneficiaries"
 	"wallet/lib/core"
+	"wallet/lib/fees"
+	"wallet/lib/idempotency"
+	"wallet/lib/withdraws/enums"
 	"wallet/lib/withdraws/integrations"
 	"wallet/lib/withdraws/repository"
+	"wallet/lib/withdraws/routing"
+
+	"github.com/google/uuid"
 )
 
 type Withdrawal = repository.Withdrawal
 
 type Service interface {
+	// Create blocks the amount and the fee of the withdrawal. A withdrawal
+	// without a bank is routed to one, or fails with ErrNoRoute. Its
+	// destination is a saved beneficiary of the wallet owner, given by
+	// BeneficiaryID or by its IBAN, that is past the cooling-off period; an
+	// IBAN that is not saved is only accepted without a cooling-off period.
 	Create(context.Context, *Withdrawal) error
+	CreateIdempotent(context.Context, *Withdrawal, idempotency.Key) (replayed bool, err error)
 	Reverse(context.Context, *Withdrawal) error
+	// Cancel reverses a withdrawal that is still new, i.e. not claimed by
+	// the banker yet.
+	Cancel(ctx context.Context, id uuid.UUID) (*Withdrawal, error)
+	// Claim takes a new withdrawal for sending; the banker calls it before
+	// handing the withdrawal to the bank. A claimed withdrawal can not be
+	// cancelled anymore and is sent again until the bank has it.
+	Claim(context.Context, *Withdrawal) error
+	// MarkAsSent records that the bank has a claimed withdrawal.
 	MarkAsSent(context.Context, *Withdrawal) error
+	// Resend takes a sent withdrawal the bank has no record of back to
+	// sending, so the banker sends it again.
+	Resend(context.Context, *Withdrawal) error
+	// Complete pays the blocked amount out and charges the fee.
 	Complete(context.Context, *Withdrawal) error
+	// ApplyPayoutResult records what the bank reported about a claimed or
+	// sent withdrawal, completing or reversing it once the bank settled it.
+	ApplyPayoutResult(context.Context, *Withdrawal, *integrations.PayoutResult) error
+	Get(ctx context.Context, id uuid.UUID) (*Withdrawal, error)
+	List(ctx context.Context, filter repository.Filter, pageNumber int, pageSize int) ([]Withdrawal, bool, error)
+	GetUnFinishedWithdraws(ctx context.Context, IDPrefix string, bankType enums.BankType) ([]Withdrawal, error)
+	// RecordBankSend records whether sending to bank failed, which takes it
+	// out of routing after enough failures in a row.
+	RecordBankSend(ctx context.Context, bank enums.BankType, sendErr error) error
 }
 
 func NewService(
 	coreRepoFactory core.RepoFactory,
 	withdrawRepoFactory repository.RepoFactory,
+	idempotencyRepoFactory idempotency.RepoFactory,
+	feePolicy fees.Policy,
+	router routing.Router,
+	beneficiaryService beneficiaries.Service, // nil when the service does not create withdrawals
 ) Service {
 	return &service{
-		coreRepoFactory:     coreRepoFactory,
-		withdrawRepoFactory: withdrawRepoFactory,
+		coreRepoFactory:        coreRepoFactory,
+		withdrawRepoFactory:    withdrawRepoFactory,
+		idempotencyRepoFactory: idempotencyRepoFactory,
+		feePolicy:              feePolicy,
+		router:                 router,
+		beneficiaryService:     beneficiaryService,
 	}
 }
 
@@ -39,7 +81,7 @@ func NewWorker(
 	concurrency int,
 	backOff time.Duration,
 	retryCount int,
-	client integrations.BankClient,
+	client integrations.BankClientV2,
 ) Worker {
 	return &worker{
 		service:     service,
@@ -0,0 +1,51 @@
+package integrations
+
+import (
+	"context"
+	"time"
+	"wallet/lib/withdraws/enums"
+)
+
+// AdaptV1 makes a BankClient usable as a BankClientV2. Such a client knows
+// neither the context nor more than the status of a payout, so the currency,
+// beneficiary and description are not passed on, and the result carries the
+// times the adapter saw the payout accepted and settled instead of the bank's.
+func AdaptV1(client BankClient) BankClientV2 {
+	return &v1Adapter{client: client}
+}
+
+type v1Adapter struct {
+	client BankClient
+}
+
+func (a *v1Adapter) Send(ctx context.Context, request PayoutRequest) (*PayoutResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	status, err := a.client.Send(request.Iban, request.Amount, request.TrackID)
+	if err != nil {
+		return nil, err
+	}
+	result := newV1Result(status)
+	result.AcceptedAt = time.Now()
+	return result, nil
+}
+
+func (a *v1Adapter) GetStatus(ctx context.Context, trackID string) (*PayoutResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	status, err := a.client.GetStatus(trackID)
+	if err != nil {
+		return nil, err
+	}
+	return newV1Result(status), nil
+}
+
+func newV1Result(status enums.PayoutStatus) *PayoutResult {
+	result := &PayoutResult{Status: status}
+	if status == enums.SUCCESS || status == enums.FAILED {
+		result.SettledAt = time.Now()
+	}
+	return result
+}
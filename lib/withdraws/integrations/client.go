@@ -1,32 +1,103 @@
 package integrations
 
 import (
+	"context"
+	"slices"
+	"wallet/lib/core"
 	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations/internal/common"
 	"wallet/lib/withdraws/integrations/internal/dummy"
+	"wallet/lib/withdraws/integrations/internal/mellat"
+	"wallet/lib/withdraws/integrations/internal/saman"
 
 	"github.com/mitchellh/mapstructure"
 )
 
 type DummyConfig = dummy.Config
+type SamanConfig = saman.Config
+type MellatConfig = mellat.Config
 
+// Simulators of the bank APIs, for running the clients offline.
+type SamanSimulator = saman.Simulator
+type MellatSimulator = mellat.Simulator
+
+var NewSamanSimulator = saman.NewSimulator
+var NewMellatSimulator = mellat.NewSimulator
+
+type PayoutRequest = common.PayoutRequest
+type PayoutResult = common.PayoutResult
+
+// BankClient is the first client interface, which only knows the status of a
+// payout; wrap such clients with AdaptV1.
 type BankClient interface {
 	Send(Iban string, amount int64, trackID string) (enums.PayoutStatus, error)
 	GetStatus(trackID string) (enums.PayoutStatus, error)
 }
 
-func NewBankClient(Type enums.BankType, config any) (BankClient, error) {
+// BankClientV2 sends payouts and reports what the bank says about them. Send
+// returns ErrDuplicatePayout if the bank already has a payout with the track
+// id, and GetStatus ErrPayoutNotFound if it has none.
+type BankClientV2 interface {
+	Send(ctx context.Context, request PayoutRequest) (*PayoutResult, error)
+	GetStatus(ctx context.Context, trackID string) (*PayoutResult, error)
+}
+
+func NewBankClient(Type enums.BankType, config any) (BankClientV2, error) {
 	switch Type {
 	case enums.DUMMY:
 		var cfg dummy.Config
-		if err := mapstructure.Decode(config, &cfg); err != nil {
-			return nil, ErrInvalidConfig
+		if err := decodeConfig(config, &cfg); err != nil {
+			return nil, err
 		}
-		return dummy.New(cfg), nil
+		return AdaptV1(dummy.New(cfg)), nil
 	case enums.SAMANAN:
-		return nil, ErrClientTypeIsNotImplemented
+		var cfg saman.Config
+		if err := decodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.BaseURL == "" {
+			return nil, ErrInvalidConfig
+		}
+		return saman.New(cfg), nil
 	case enums.MELLAT:
-		return nil, ErrClientTypeIsNotImplemented
+		var cfg mellat.Config
+		if err := decodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.BaseURL == "" {
+			return nil, ErrInvalidConfig
+		}
+		return mellat.New(cfg), nil
 	default:
 		return nil, ErrUnknownClientType
 	}
 }
+
+// SupportsCurrency reports whether bank can pay out currency; the dummy bank
+// pays out any.
+func SupportsCurrency(bank enums.BankType, currency core.Currency) bool {
+	switch bank {
+	case enums.SAMANAN:
+		return slices.Contains(saman.Currencies, currency)
+	case enums.MELLAT:
+		return slices.Contains(mellat.Currencies, currency)
+	default:
+		return true
+	}
+}
+
+// decodeConfig decodes the bank_config map of the banker into cfg, parsing
+// durations like "10s".
+func decodeConfig(config any, cfg any) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     cfg,
+	})
+	if err != nil {
+		return ErrInvalidConfig
+	}
+	if err := decoder.Decode(config); err != nil {
+		return ErrInvalidConfig
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package integrations_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"wallet/lib/core"
+	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var payout = integrations.PayoutRequest{
+	TrackID:  "track-1",
+	Iban:     "IR062960000000100324200001",
+	Amount:   1000,
+	Currency: core.IRR,
+}
+
+func TestNewBankClient_Saman(t *testing.T) {
+	simulator := integrations.NewSamanSimulator(integrations.SamanConfig{ClientID: "wallet", ClientSecret: "secret", SigningKey: "key"})
+	simulator.Settle = true
+	server := httptest.NewServer(simulator)
+	defer server.Close()
+
+	// keys as they come from the banker's bank_config
+	client, err := integrations.NewBankClient(enums.SAMANAN, map[string]any{
+		"base_url":      server.URL,
+		"client_id":     "wallet",
+		"client_secret": "secret",
+		"signing_key":   "key",
+		"timeout":       "5s",
+	})
+	assert.NoError(t, err)
+	result, err := client.Send(context.Background(), payout)
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SUCCESS, result.Status)
+	_, err = client.Send(context.Background(), payout)
+	assert.ErrorIs(t, err, integrations.ErrDuplicatePayout)
+}
+
+func TestNewBankClient_Mellat(t *testing.T) {
+	simulator := integrations.NewMellatSimulator(integrations.MellatConfig{TerminalID: "1234", Username: "wallet", Password: "password", SecretKey: "key"})
+	server := httptest.NewServer(simulator)
+	defer server.Close()
+
+	client, err := integrations.NewBankClient(enums.MELLAT, map[string]any{
+		"base_url":    server.URL,
+		"terminal_id": "1234",
+		"username":    "wallet",
+		"password":    "password",
+		"secret_key":  "key",
+	})
+	assert.NoError(t, err)
+	result, err := client.Send(context.Background(), payout)
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SENT, result.Status)
+	_, err = client.Send(context.Background(), payout)
+	assert.ErrorIs(t, err, integrations.ErrDuplicatePayout)
+}
+
+func TestNewBankClient_InvalidConfig(t *testing.T) {
+	_, err := integrations.NewBankClient(enums.MELLAT, map[string]any{"terminal_id": "1234"})
+	assert.ErrorIs(t, err, integrations.ErrInvalidConfig)
+	_, err = integrations.NewBankClient(enums.SAMANAN, map[string]any{"base_url": "http://bank", "timeout": "soon"})
+	assert.ErrorIs(t, err, integrations.ErrInvalidConfig)
+	_, err = integrations.NewBankClient(enums.BankType("tejarat"), nil)
+	assert.ErrorIs(t, err, integrations.ErrUnknownClientType)
+}
+
+// v1Client is a BankClient as written before BankClientV2.
+type v1Client struct {
+	status enums.PayoutStatus
+	sent   []string
+}
+
+func (c *v1Client) Send(Iban string, amount int64, trackID string) (enums.PayoutStatus, error) {
+	for _, sent := range c.sent {
+		if sent == trackID {
+			return "", integrations.ErrDuplicatePayout
+		}
+	}
+	c.sent = append(c.sent, trackID)
+	return c.status, nil
+}
+
+func (c *v1Client) GetStatus(trackID string) (enums.PayoutStatus, error) {
+	return c.status, nil
+}
+
+func TestAdaptV1(t *testing.T) {
+	v1 := &v1Client{status: enums.SENT}
+	client := integrations.AdaptV1(v1)
+
+	result, err := client.Send(context.Background(), payout)
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SENT, result.Status)
+	assert.False(t, result.AcceptedAt.IsZero())
+	assert.True(t, result.SettledAt.IsZero())
+	assert.Equal(t, []string{"track-1"}, v1.sent)
+	_, err = client.Send(context.Background(), payout)
+	assert.ErrorIs(t, err, integrations.ErrDuplicatePayout)
+
+	v1.status = enums.SUCCESS
+	result, err = client.GetStatus(context.Background(), "track-1")
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SUCCESS, result.Status)
+	assert.False(t, result.SettledAt.IsZero())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetStatus(ctx, "track-1")
+	assert.ErrorIs(t, err, context.Canceled)
+}
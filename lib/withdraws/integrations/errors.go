@@ -6,6 +6,8 @@ import (
 )
 
 var ErrDuplicatePayout = common.ErrDuplicatePayout
+var ErrPayoutNotFound = common.ErrPayoutNotFound
+var ErrUnexpectedResponse = common.ErrUnexpectedResponse
+var ErrUnsupportedCurrency = common.ErrUnsupportedCurrency
 var ErrInvalidConfig = errors.New("invalid client config")
 var ErrUnknownClientType = errors.New("unknown client type")
-var ErrClientTypeIsNotImplemented = errors.New("client type is not implemented")
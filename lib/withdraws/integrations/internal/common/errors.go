@@ -3,3 +3,6 @@ package common
 import "errors"
 
 var ErrDuplicatePayout = errors.New("duplicate payout")
+var ErrPayoutNotFound = errors.New("payout not found at the bank")
+var ErrUnexpectedResponse = errors.New("unexpected response from the bank")
+var ErrUnsupportedCurrency = errors.New("currency is not supported by the bank")
@@ -0,0 +1,28 @@
+package common
+
+import (
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/withdraws/enums"
+)
+
+// PayoutRequest is a payout to hand to the bank.
+type PayoutRequest struct {
+	TrackID         string // our id of the payout at the bank, the withdrawal id
+	Iban            string
+	Amount          int64
+	Currency        core.Currency
+	BeneficiaryName string
+	Description     string
+}
+
+// PayoutResult is what the bank reports about a payout; fields the bank does
+// not provide are left zero.
+type PayoutResult struct {
+	Status     enums.PayoutStatus
+	Reference  string // the bank's own reference of the payout
+	ReasonCode string // why the bank failed it
+	Reason     string
+	AcceptedAt time.Time // when the bank registered the payout
+	SettledAt  time.Time // when the payout succeeded or failed
+}
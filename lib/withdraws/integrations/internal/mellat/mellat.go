@@ -0,0 +1,265 @@
+package mellat
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations/internal/common"
+)
+
+type Config struct {
+	BaseURL    string        `mapstructure:"base_url"`
+	TerminalID string        `mapstructure:"terminal_id"`
+	Username   string        `mapstructure:"username"`
+	Password   string        `mapstructure:"password"`
+	SecretKey  string        `mapstructure:"secret_key"` // HMAC key of the sign field
+	Timeout    time.Duration `mapstructure:"timeout"`
+}
+
+// Currencies are what the bank pays out; PAYA transfers are in rials only.
+var Currencies = []core.Currency{core.IRR}
+
+// Mellat answers every call with HTTP 200 and a res_code.
+const (
+	resOK             = 0
+	resInvalidSign    = 11
+	resSessionExpired = 12
+	resDuplicateOrder = 21
+	resOrderNotFound  = 25
+)
+
+// transfer states of the Mellat PAYA API
+const (
+	statePending  = "PENDING"
+	stateSettled  = "SETTLED"
+	stateReturned = "RETURNED"
+	stateCanceled = "CANCELED"
+)
+
+type loginRequest struct {
+	TerminalID string `json:"terminal_id"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+}
+
+type loginResponse struct {
+	ResCode      int    `json:"res_code"`
+	SessionToken string `json:"session_token"`
+}
+
+type transferRequest struct {
+	TerminalID string `json:"terminal_id"`
+	OrderID    string `json:"order_id"`
+	Iban       string `json:"iban"`
+	Amount     int64  `json:"amount"`
+	OwnerName  string `json:"owner_name,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	Sign       string `json:"sign"`
+}
+
+type inquiryRequest struct {
+	TerminalID string `json:"terminal_id"`
+	OrderID    string `json:"order_id"`
+	Sign       string `json:"sign"`
+}
+
+type transferResponse struct {
+	ResCode      int        `json:"res_code"`
+	RefID        string     `json:"ref_id,omitempty"`
+	State        string     `json:"state,omitempty"`
+	ReturnCode   string     `json:"return_code,omitempty"` // why a transfer was returned or canceled
+	ReturnReason string     `json:"return_reason,omitempty"`
+	RegisteredAt *time.Time `json:"registered_at,omitempty"`
+	SettledAt    *time.Time `json:"settled_at,omitempty"`
+}
+
+type mellatClient struct {
+	config     Config
+	httpClient *http.Client
+
+	sessionMutex sync.Mutex
+	session      string
+}
+
+func New(config Config) *mellatClient {
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &mellatClient{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+func (c *mellatClient) Send(ctx context.Context, request common.PayoutRequest) (*common.PayoutResult, error) {
+	if !slices.Contains(Currencies, request.Currency) {
+		return nil, fmt.Errorf("%w: currency %s", common.ErrUnsupportedCurrency, request.Currency)
+	}
+	amount := strconv.FormatInt(request.Amount, 10)
+	resp, err := c.call(ctx, "/transfer/paya", transferRequest{
+		TerminalID: c.config.TerminalID,
+		OrderID:    request.TrackID,
+		Iban:       request.Iban,
+		Amount:     request.Amount,
+		OwnerName:  request.BeneficiaryName,
+		Comment:    request.Description,
+		Sign:       sign(c.config.SecretKey, c.config.TerminalID, request.TrackID, request.Iban, amount),
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.ResCode {
+	case resOK:
+		return toResult(resp)
+	case resDuplicateOrder:
+		return nil, common.ErrDuplicatePayout
+	}
+	return nil, unexpected(resp.ResCode)
+}
+
+func (c *mellatClient) GetStatus(ctx context.Context, trackID string) (*common.PayoutResult, error) {
+	resp, err := c.call(ctx, "/transfer/inquiry", inquiryRequest{
+		TerminalID: c.config.TerminalID,
+		OrderID:    trackID,
+		Sign:       sign(c.config.SecretKey, c.config.TerminalID, trackID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.ResCode {
+	case resOK:
+		return toResult(resp)
+	case resOrderNotFound:
+		return nil, common.ErrPayoutNotFound
+	}
+	return nil, unexpected(resp.ResCode)
+}
+
+// call posts request with the current session, logging in again once if the
+// session expired.
+func (c *mellatClient) call(ctx context.Context, path string, request any) (*transferResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		session, err := c.getSession(ctx, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+		var resp transferResponse
+		if err := c.post(ctx, path, session, body, &resp); err != nil {
+			return nil, err
+		}
+		if resp.ResCode != resSessionExpired || attempt > 0 {
+			return &resp, nil
+		}
+	}
+}
+
+// getSession returns the current session token, or logs in if there is none
+// or refresh is set.
+func (c *mellatClient) getSession(ctx context.Context, refresh bool) (string, error) {
+	c.sessionMutex.Lock()
+	defer c.sessionMutex.Unlock()
+	if !refresh && c.session != "" {
+		return c.session, nil
+	}
+	body, err := json.Marshal(loginRequest{
+		TerminalID: c.config.TerminalID,
+		Username:   c.config.Username,
+		Password:   c.config.Password,
+	})
+	if err != nil {
+		return "", err
+	}
+	var resp loginResponse
+	if err := c.post(ctx, "/login", "", body, &resp); err != nil {
+		return "", err
+	}
+	if resp.ResCode != resOK || resp.SessionToken == "" {
+		return "", fmt.Errorf("%w: login failed", unexpected(resp.ResCode))
+	}
+	c.session = resp.SessionToken
+	return c.session, nil
+}
+
+func (c *mellatClient) post(ctx context.Context, path, session string, body []byte, response any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if session != "" {
+		req.Header.Set("X-Session-Token", session)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", common.ErrUnexpectedResponse, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("%w: %v", common.ErrUnexpectedResponse, err)
+	}
+	return nil
+}
+
+// sign returns the hex HMAC-SHA256 of the fields joined by '|', which Mellat
+// expects in the sign field of a request.
+func sign(key string, fields ...string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func toResult(resp *transferResponse) (*common.PayoutResult, error) {
+	status, err := mapState(resp.State)
+	if err != nil {
+		return nil, err
+	}
+	result := &common.PayoutResult{
+		Status:     status,
+		Reference:  resp.RefID,
+		ReasonCode: resp.ReturnCode,
+		Reason:     resp.ReturnReason,
+	}
+	if resp.RegisteredAt != nil {
+		result.AcceptedAt = *resp.RegisteredAt
+	}
+	if resp.SettledAt != nil {
+		result.SettledAt = *resp.SettledAt
+	}
+	return result, nil
+}
+
+func mapState(state string) (enums.PayoutStatus, error) {
+	switch state {
+	case statePending:
+		return enums.SENT, nil
+	case stateSettled:
+		return enums.SUCCESS, nil
+	case stateReturned, stateCanceled:
+		return enums.FAILED, nil
+	}
+	return "", fmt.Errorf("%w: unknown state %q", common.ErrUnexpectedResponse, state)
+}
+
+func unexpected(resCode int) error {
+	return fmt.Errorf("%w: res_code %d", common.ErrUnexpectedResponse, resCode)
+}
@@ -0,0 +1,119 @@
+package mellat
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"wallet/lib/core"
+	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations/internal/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testConfig = Config{
+	TerminalID: "1234",
+	Username:   "wallet",
+	Password:   "password",
+	SecretKey:  "secret-key",
+}
+
+func newTestClient(t *testing.T) (*mellatClient, *Simulator) {
+	simulator := NewSimulator(testConfig)
+	server := httptest.NewServer(simulator)
+	t.Cleanup(server.Close)
+	config := testConfig
+	config.BaseURL = server.URL
+	return New(config), simulator
+}
+
+func newPayout(trackID string) common.PayoutRequest {
+	return common.PayoutRequest{
+		TrackID:         trackID,
+		Iban:            "IR062960000000100324200001",
+		Amount:          1000,
+		Currency:        core.IRR,
+		BeneficiaryName: "Ali Rezaei",
+		Description:     "withdrawal",
+	}
+}
+
+func TestClient_SendAndGetStatus(t *testing.T) {
+	client, simulator := newTestClient(t)
+	ctx := context.Background()
+
+	result, err := client.Send(ctx, newPayout("track-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SENT, result.Status)
+	assert.NotEmpty(t, result.Reference)
+	assert.False(t, result.AcceptedAt.IsZero())
+	assert.True(t, result.SettledAt.IsZero())
+
+	simulator.SetState("track-1", stateSettled)
+	result, err = client.GetStatus(ctx, "track-1")
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SUCCESS, result.Status)
+	assert.False(t, result.SettledAt.IsZero())
+
+	simulator.Return("track-1", "R03", "no account")
+	result, err = client.GetStatus(ctx, "track-1")
+	assert.NoError(t, err)
+	assert.Equal(t, enums.FAILED, result.Status)
+	assert.Equal(t, "R03", result.ReasonCode)
+	assert.Equal(t, "no account", result.Reason)
+
+	_, err = client.GetStatus(ctx, "track-2")
+	assert.ErrorIs(t, err, common.ErrPayoutNotFound)
+}
+
+func TestClient_SendSettled(t *testing.T) {
+	client, simulator := newTestClient(t)
+	simulator.Settle = true
+
+	result, err := client.Send(context.Background(), newPayout("track-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SUCCESS, result.Status)
+}
+
+func TestClient_SendDuplicate(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.Send(context.Background(), newPayout("track-1"))
+	assert.NoError(t, err)
+	_, err = client.Send(context.Background(), newPayout("track-1"))
+	assert.ErrorIs(t, err, common.ErrDuplicatePayout)
+}
+
+func TestClient_SendUnsupportedCurrency(t *testing.T) {
+	client, _ := newTestClient(t)
+	payout := newPayout("track-1")
+	payout.Currency = core.Currency("USD")
+
+	_, err := client.Send(context.Background(), payout)
+	assert.ErrorIs(t, err, common.ErrUnsupportedCurrency)
+}
+
+func TestClient_LogsInAgainOnExpiredSession(t *testing.T) {
+	client, simulator := newTestClient(t)
+
+	_, err := client.Send(context.Background(), newPayout("track-1"))
+	assert.NoError(t, err)
+	simulator.ExpireSessions()
+
+	result, err := client.GetStatus(context.Background(), "track-1")
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SENT, result.Status)
+}
+
+func TestClient_Rejected(t *testing.T) {
+	client, _ := newTestClient(t)
+	client.config.SecretKey = "wrong"
+
+	_, err := client.Send(context.Background(), newPayout("track-1"))
+	assert.ErrorIs(t, err, common.ErrUnexpectedResponse)
+
+	client.config.Password = "wrong"
+	client.session = ""
+	_, err = client.GetStatus(context.Background(), "track-1")
+	assert.ErrorIs(t, err, common.ErrUnexpectedResponse)
+}
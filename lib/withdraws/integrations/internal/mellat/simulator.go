@@ -0,0 +1,158 @@
+package mellat
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const resInvalidLogin = 3
+
+// Simulator is an in-memory stand-in of the Mellat PAYA API for offline
+// tests, e.g. behind httptest.NewServer.
+type Simulator struct {
+	config Config
+
+	// Settle makes new transfers SETTLED right away instead of PENDING.
+	Settle bool
+
+	mutex     sync.Mutex
+	sessions  map[string]struct{}
+	transfers map[string]*transferResponse
+}
+
+func NewSimulator(config Config) *Simulator {
+	return &Simulator{
+		config:    config,
+		sessions:  make(map[string]struct{}),
+		transfers: make(map[string]*transferResponse),
+	}
+}
+
+// SetState moves a transfer to state, e.g. SETTLED or RETURNED.
+func (s *Simulator) SetState(orderID, state string) {
+	s.setState(orderID, state, "", "")
+}
+
+// Return returns a transfer with the reason the bank gives.
+func (s *Simulator) Return(orderID, returnCode, reason string) {
+	s.setState(orderID, stateReturned, returnCode, reason)
+}
+
+func (s *Simulator) setState(orderID, state, returnCode, reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	transfer, ok := s.transfers[orderID]
+	if !ok {
+		return
+	}
+	transfer.State = state
+	transfer.ReturnCode = returnCode
+	transfer.ReturnReason = reason
+	transfer.SettledAt = nil
+	if state != statePending {
+		now := time.Now()
+		transfer.SettledAt = &now
+	}
+}
+
+// ExpireSessions ends all sessions.
+func (s *Simulator) ExpireSessions() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions = make(map[string]struct{})
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	switch r.URL.Path {
+	case "/login":
+		var request loginRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, s.login(request))
+	case "/transfer/paya":
+		var request transferRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, s.transfer(r, request))
+	case "/transfer/inquiry":
+		var request inquiryRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, s.inquiry(r, request))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *Simulator) login(request loginRequest) loginResponse {
+	if request.TerminalID != s.config.TerminalID || request.Username != s.config.Username || request.Password != s.config.Password {
+		return loginResponse{ResCode: resInvalidLogin}
+	}
+	session := uuid.NewString()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions[session] = struct{}{}
+	return loginResponse{ResCode: resOK, SessionToken: session}
+}
+
+func (s *Simulator) transfer(r *http.Request, request transferRequest) *transferResponse {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.sessions[r.Header.Get("X-Session-Token")]; !ok {
+		return &transferResponse{ResCode: resSessionExpired}
+	}
+	expected := sign(s.config.SecretKey, request.TerminalID, request.OrderID, request.Iban, strconv.FormatInt(request.Amount, 10))
+	if request.TerminalID != s.config.TerminalID || !hmac.Equal([]byte(expected), []byte(request.Sign)) {
+		return &transferResponse{ResCode: resInvalidSign}
+	}
+	if _, ok := s.transfers[request.OrderID]; ok {
+		return &transferResponse{ResCode: resDuplicateOrder}
+	}
+	now := time.Now()
+	transfer := &transferResponse{ResCode: resOK, RefID: uuid.NewString(), State: statePending, RegisteredAt: &now}
+	if s.Settle {
+		transfer.State = stateSettled
+		transfer.SettledAt = &now
+	}
+	s.transfers[request.OrderID] = transfer
+	return transfer
+}
+
+func (s *Simulator) inquiry(r *http.Request, request inquiryRequest) *transferResponse {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.sessions[r.Header.Get("X-Session-Token")]; !ok {
+		return &transferResponse{ResCode: resSessionExpired}
+	}
+	expected := sign(s.config.SecretKey, request.TerminalID, request.OrderID)
+	if request.TerminalID != s.config.TerminalID || !hmac.Equal([]byte(expected), []byte(request.Sign)) {
+		return &transferResponse{ResCode: resInvalidSign}
+	}
+	transfer, ok := s.transfers[request.OrderID]
+	if !ok {
+		return &transferResponse{ResCode: resOrderNotFound}
+	}
+	response := *transfer
+	return &response
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,252 @@
+package saman
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations/internal/common"
+)
+
+type Config struct {
+	BaseURL      string        `mapstructure:"base_url"`
+	ClientID     string        `mapstructure:"client_id"`
+	ClientSecret string        `mapstructure:"client_secret"`
+	SigningKey   string        `mapstructure:"signing_key"` // HMAC key of the payout requests
+	SourceIban   string        `mapstructure:"source_iban"` // our account the payouts are paid from
+	Timeout      time.Duration `mapstructure:"timeout"`
+}
+
+// Currencies are what the bank pays out.
+var Currencies = []core.Currency{core.IRR}
+
+// payout states of the Saman payout API
+const (
+	stateRegistered = "REGISTERED"
+	stateProcessing = "PROCESSING"
+	stateDone       = "DONE"
+	stateRejected   = "REJECTED"
+	stateFailed     = "FAILED"
+)
+
+const codeDuplicateTrackID = "DUPLICATE_TRACK_ID"
+
+type payoutRequest struct {
+	TrackID         string `json:"track_id"`
+	SourceIban      string `json:"source_iban"`
+	DestinationIban string `json:"destination_iban"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	OwnerName       string `json:"owner_name,omitempty"`
+	Description     string `json:"description,omitempty"`
+}
+
+type payoutResponse struct {
+	TrackID    string     `json:"track_id"`
+	State      string     `json:"state"`
+	Reference  string     `json:"reference,omitempty"`
+	ReasonCode string     `json:"reason_code,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SettledAt  *time.Time `json:"settled_at,omitempty"`
+}
+
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"` // seconds
+}
+
+type samanClient struct {
+	config     Config
+	httpClient *http.Client
+
+	tokenMutex sync.Mutex
+	token      string
+	tokenUntil time.Time
+}
+
+func New(config Config) *samanClient {
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &samanClient{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+func (c *samanClient) Send(ctx context.Context, request common.PayoutRequest) (*common.PayoutResult, error) {
+	if !slices.Contains(Currencies, request.Currency) {
+		return nil, fmt.Errorf("%w: currency %s", common.ErrUnsupportedCurrency, request.Currency)
+	}
+	body, err := json.Marshal(payoutRequest{
+		TrackID:         request.TrackID,
+		SourceIban:      c.config.SourceIban,
+		DestinationIban: request.Iban,
+		Amount:          request.Amount,
+		Currency:        string(request.Currency),
+		OwnerName:       request.BeneficiaryName,
+		Description:     request.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/payouts", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return decodeResult(resp)
+	case http.StatusConflict:
+		var e errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&e); err == nil && e.Code == codeDuplicateTrackID {
+			return nil, common.ErrDuplicatePayout
+		}
+	}
+	return nil, unexpected(resp)
+}
+
+func (c *samanClient) GetStatus(ctx context.Context, trackID string) (*common.PayoutResult, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/payouts/"+url.PathEscape(trackID), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return decodeResult(resp)
+	case http.StatusNotFound:
+		return nil, common.ErrPayoutNotFound
+	}
+	return nil, unexpected(resp)
+}
+
+// do sends a signed request, fetching a new access token once if the current
+// one is rejected.
+func (c *samanClient) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := c.getToken(ctx, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Timestamp", timestamp)
+		req.Header.Set("X-Signature", sign(c.config.SigningKey, method, path, timestamp, body))
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
+
+// getToken returns the cached access token, or fetches one with the client
+// credentials if there is none, it expired or refresh is set.
+func (c *samanClient) getToken(ctx context.Context, refresh bool) (string, error) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	if !refresh && c.token != "" && time.Now().Before(c.tokenUntil) {
+		return c.token, nil
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.BaseURL+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.config.ClientID, c.config.ClientSecret)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", unexpected(resp)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("%w: invalid token response", common.ErrUnexpectedResponse)
+	}
+	c.token = token.AccessToken
+	// renew a little before the bank expires it
+	c.tokenUntil = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - 10*time.Second)
+	return c.token, nil
+}
+
+// sign returns the hex HMAC-SHA256 of a request, which Saman expects in the
+// X-Signature header.
+func sign(key, method, path, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(method + "\n" + path + "\n" + timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func decodeResult(resp *http.Response) (*common.PayoutResult, error) {
+	var payout payoutResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payout); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrUnexpectedResponse, err)
+	}
+	status, err := mapState(payout.State)
+	if err != nil {
+		return nil, err
+	}
+	result := &common.PayoutResult{
+		Status:     status,
+		Reference:  payout.Reference,
+		ReasonCode: payout.ReasonCode,
+		Reason:     payout.Reason,
+		AcceptedAt: payout.CreatedAt,
+	}
+	if payout.SettledAt != nil {
+		result.SettledAt = *payout.SettledAt
+	}
+	return result, nil
+}
+
+func mapState(state string) (enums.PayoutStatus, error) {
+	switch state {
+	case stateRegistered, stateProcessing:
+		return enums.SENT, nil
+	case stateDone:
+		return enums.SUCCESS, nil
+	case stateRejected, stateFailed:
+		return enums.FAILED, nil
+	}
+	return "", fmt.Errorf("%w: unknown state %q", common.ErrUnexpectedResponse, state)
+}
+
+func unexpected(resp *http.Response) error {
+	var e errorResponse
+	_ = json.NewDecoder(resp.Body).Decode(&e)
+	return fmt.Errorf("%w: status %d %s %s", common.ErrUnexpectedResponse, resp.StatusCode, e.Code, e.Message)
+}
@@ -0,0 +1,182 @@
+package saman
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Simulator is an in-memory stand-in of the Saman payout API for offline
+// tests, e.g. behind httptest.NewServer.
+type Simulator struct {
+	config Config
+
+	// Settle makes new payouts DONE right away instead of PROCESSING.
+	Settle bool
+	// TokenLifetime is the expires_in of issued tokens, one hour by default.
+	TokenLifetime time.Duration
+
+	mutex   sync.Mutex
+	tokens  map[string]time.Time
+	payouts map[string]*payoutResponse
+}
+
+func NewSimulator(config Config) *Simulator {
+	return &Simulator{
+		config:        config,
+		TokenLifetime: time.Hour,
+		tokens:        make(map[string]time.Time),
+		payouts:       make(map[string]*payoutResponse),
+	}
+}
+
+// SetState moves a payout to state, e.g. DONE or REJECTED.
+func (s *Simulator) SetState(trackID, state string) {
+	s.setState(trackID, state, "", "")
+}
+
+// Reject rejects a payout with the reason the bank gives.
+func (s *Simulator) Reject(trackID, reasonCode, reason string) {
+	s.setState(trackID, stateRejected, reasonCode, reason)
+}
+
+func (s *Simulator) setState(trackID, state, reasonCode, reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	payout, ok := s.payouts[trackID]
+	if !ok {
+		return
+	}
+	payout.State = state
+	payout.ReasonCode = reasonCode
+	payout.Reason = reason
+	payout.SettledAt = nil
+	if state == stateDone || state == stateRejected || state == stateFailed {
+		now := time.Now()
+		payout.SettledAt = &now
+	}
+}
+
+// RevokeTokens invalidates all issued tokens.
+func (s *Simulator) RevokeTokens() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tokens = make(map[string]time.Time)
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && r.URL.Path == "/oauth/token" {
+		s.issueToken(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: "BAD_REQUEST"})
+		return
+	}
+	if !s.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Code: "INVALID_TOKEN"})
+		return
+	}
+	if !s.signed(r, body) {
+		writeJSON(w, http.StatusForbidden, errorResponse{Code: "INVALID_SIGNATURE"})
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/payouts":
+		s.createPayout(w, body)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/payouts/"):
+		s.getPayout(w, strings.TrimPrefix(r.URL.Path, "/api/v1/payouts/"))
+	default:
+		writeJSON(w, http.StatusNotFound, errorResponse{Code: "NOT_FOUND"})
+	}
+}
+
+func (s *Simulator) issueToken(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != s.config.ClientID || secret != s.config.ClientSecret || r.FormValue("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Code: "INVALID_CLIENT"})
+		return
+	}
+	token := uuid.NewString()
+	s.mutex.Lock()
+	s.tokens[token] = time.Now().Add(s.TokenLifetime)
+	s.mutex.Unlock()
+	writeJSON(w, http.StatusOK, tokenResponse{AccessToken: token, ExpiresIn: int64(s.TokenLifetime / time.Second)})
+}
+
+func (s *Simulator) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	until, ok := s.tokens[token]
+	return ok && time.Now().Before(until)
+}
+
+func (s *Simulator) signed(r *http.Request, body []byte) bool {
+	timestamp := r.Header.Get("X-Timestamp")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(unix, 0)).Abs() > 5*time.Minute {
+		return false
+	}
+	expected := sign(s.config.SigningKey, r.Method, r.URL.EscapedPath(), timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Signature")))
+}
+
+func (s *Simulator) createPayout(w http.ResponseWriter, body []byte) {
+	var request payoutRequest
+	if err := json.Unmarshal(body, &request); err != nil || request.TrackID == "" || request.Amount <= 0 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: "BAD_REQUEST"})
+		return
+	}
+	if request.Currency != "IRR" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: "INVALID_CURRENCY"})
+		return
+	}
+	if request.SourceIban != s.config.SourceIban {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: "INVALID_SOURCE"})
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.payouts[request.TrackID]; ok {
+		writeJSON(w, http.StatusConflict, errorResponse{Code: codeDuplicateTrackID})
+		return
+	}
+	now := time.Now()
+	payout := &payoutResponse{TrackID: request.TrackID, State: stateProcessing, Reference: uuid.NewString(), CreatedAt: now}
+	if s.Settle {
+		payout.State = stateDone
+		payout.SettledAt = &now
+	}
+	s.payouts[request.TrackID] = payout
+	writeJSON(w, http.StatusCreated, payout)
+}
+
+func (s *Simulator) getPayout(w http.ResponseWriter, trackID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	payout, ok := s.payouts[trackID]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Code: "PAYOUT_NOT_FOUND"})
+		return
+	}
+	response := *payout
+	writeJSON(w, http.StatusOK, &response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
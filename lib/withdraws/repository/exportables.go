@@ -2,18 +2,30 @@ package repository
 
 import (
 	"context"
+	"time"
+	"wallet/lib/core"
 	"wallet/lib/withdraws/enums"
 	"wallet/lib/withdraws/repository/internal"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type Withdrawal = internal.Withdrawal
+type Filter = internal.Filter
+type BankResult = internal.BankResult
+type BankHealth = internal.BankHealth
 
 type Repo interface {
 	Create(context.Context, *Withdrawal) error
 	Update(context.Context, *Withdrawal) error
+	Get(ctx context.Context, id uuid.UUID) (*Withdrawal, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*Withdrawal, error)
+	List(ctx context.Context, filter Filter, pageNumber int, pageSize int) ([]Withdrawal, bool, error)
 	GetUnFinishedWithdraws(ctx context.Context, IDPrefix string, bankType enums.BankType) ([]Withdrawal, error)
+	RoutedAmount(ctx context.Context, bank enums.BankType, currency core.Currency, since time.Time) (int64, error)
+	GetBankHealth(ctx context.Context, bank enums.BankType) (*BankHealth, error)
+	RecordBankSend(ctx context.Context, bank enums.BankType, sendErr error) error
 
 	GetDBTransaction() *gorm.DB
 	Commit() error
@@ -2,21 +2,63 @@ package internal
 
 import (
 	"time"
+	"wallet/lib/core"
+	"wallet/lib/fees"
 	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/routing"
 
 	"github.com/google/uuid"
 )
 
+// Filter selects withdrawals; zero fields match everything.
+type Filter struct {
+	WalletID uuid.UUID
+	Status   enums.PayoutStatus
+	Bank     enums.BankType
+	From     time.Time // inclusive, on created_at
+	To       time.Time // exclusive, on created_at
+}
+
 type Withdrawal struct {
 	ID                      uuid.UUID          `gorm:"type:uuid;primaryKey" json:"id"`
 	WalletID                uuid.UUID          `gorm:"type:uuid;index" json:"wallet_id"`
+	Currency                core.Currency      `gorm:"type:varchar(3);not null" json:"currency"`
 	Status                  enums.PayoutStatus `gorm:"type:varchar(32);index" json:"status"`
 	Bank                    enums.BankType     `gorm:"type:varchar(32);index" json:"bank"`
-	Iban                    string             `gorm:"type:varchar(24);not null;index" json:"iban"`
+	Route                   routing.Route      `gorm:"embedded;embeddedPrefix:route_" json:"route"` // how the bank was chosen
+	Iban                    string             `gorm:"type:varchar(34);not null;index" json:"iban"`
+	BeneficiaryBankCode     string             `gorm:"type:varchar(16)" json:"beneficiary_bank_code,omitempty"` // from the iban
+	BeneficiaryName         string             `gorm:"type:varchar(128)" json:"beneficiary_name,omitempty"`
+	BeneficiaryID           *uuid.UUID         `gorm:"type:uuid;index" json:"beneficiary_id,omitempty"` // the saved beneficiary the iban was taken from
+	Description             string             `gorm:"type:varchar(255)" json:"description,omitempty"`
 	BlockTransactionID      uint64             `gorm:"index" json:"block_transaction_id"`
 	WithdrawalTransactionID uint64             `gorm:"index" json:"withdrawal_transaction_id"`
 	ReverserTransactionID   uint64             `gorm:"index" json:"reverser_transaction_id"`
-	Amount                  int64              `gorm:"not null" json:"amount"`
+	FeeTransactionID        uint64             `gorm:"index" json:"fee_transaction_id"`
+	Amount                  int64              `gorm:"not null" json:"amount"`                  // paid out to the iban
+	Fee                     fees.Breakdown     `gorm:"embedded;embeddedPrefix:fee_" json:"fee"` // blocked with the amount, charged on completion
+	BankResult              BankResult         `gorm:"embedded;embeddedPrefix:bank_" json:"bank_result"`
+	SentAt                  *time.Time         `json:"sent_at,omitempty"` // when the bank acknowledged it
 	CreatedAt               time.Time          `json:"created_at"`
 	UpdatedAt               time.Time          `json:"updated_at"`
 }
+
+// BankResult is what the bank last reported about the payout of a withdrawal.
+type BankResult struct {
+	Reference  string     `gorm:"type:varchar(64);index" json:"reference,omitempty"`
+	ReasonCode string     `gorm:"type:varchar(64)" json:"reason_code,omitempty"` // why the bank failed the payout
+	Reason     string     `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	SettledAt  *time.Time `json:"settled_at,omitempty"`
+}
+
+// BankHealth counts the failed sends to a bank since its last successful one.
+type BankHealth struct {
+	Bank      enums.BankType `gorm:"type:varchar(32);primaryKey" json:"bank"`
+	Failures  int            `gorm:"not null" json:"failures"`
+	LastError string         `gorm:"type:varchar(255)" json:"last_error,omitempty"`
+	FailedAt  *time.Time     `json:"failed_at,omitempty"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func (BankHealth) TableName() string { return "bank_health" }
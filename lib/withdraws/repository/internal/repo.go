@@ -2,9 +2,15 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
+	"wallet/lib/core"
 	"wallet/lib/withdraws/enums"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type withdrawalRepo struct {
@@ -23,12 +29,72 @@ func (r *withdrawalRepo) Update(ctx context.Context, w *Withdrawal) error {
 	return r.tx.WithContext(ctx).Save(w).Error
 }
 
-// GetUnFinishedWithdraws returns withdrawals with status "new" or "sent"
+func (r *withdrawalRepo) Get(ctx context.Context, id uuid.UUID) (*Withdrawal, error) {
+	var w Withdrawal
+	if err := r.tx.WithContext(ctx).First(&w, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// GetForUpdate fetches a withdrawal by ID with row-level locking. The banker
+// and cancellations take this lock before changing the status.
+func (r *withdrawalRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*Withdrawal, error) {
+	var w Withdrawal
+	if err := r.tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&w, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// List returns a page of withdrawals matching filter, newest first, and
+// whether there are more.
+func (r *withdrawalRepo) List(ctx context.Context, filter Filter, pageNumber int, pageSize int) ([]Withdrawal, bool, error) {
+	var withdraws []Withdrawal
+	query := r.tx.WithContext(ctx)
+	if filter.WalletID != uuid.Nil {
+		query = query.Where("wallet_id = ?", filter.WalletID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Bank != "" {
+		query = query.Where("bank = ?", filter.Bank)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	// one extra row tells whether there is a next page
+	if err := query.
+		Order("created_at DESC, id").
+		Offset((pageNumber - 1) * pageSize).
+		Limit(pageSize + 1).
+		Find(&withdraws).Error; err != nil {
+		return nil, false, err
+	}
+	if len(withdraws) > pageSize {
+		return withdraws[:pageSize], true, nil
+	}
+	return withdraws, false, nil
+}
+
+// GetUnFinishedWithdraws returns withdrawals with status "new", "sending" or
+// "sent". New withdrawals of wallets that are not active are left out: they
+// stay pending until the wallet is unfrozen instead of being sent or failed.
 func (r *withdrawalRepo) GetUnFinishedWithdraws(ctx context.Context, idPrefix string, bankType enums.BankType) ([]Withdrawal, error) {
 	var withdraws []Withdrawal
 
 	query := r.tx.WithContext(ctx).Model(&Withdrawal{}).
-		Where("status IN ?", []enums.PayoutStatus{enums.NEW, enums.SENT}).
+		Where("status IN ? OR (status = ? AND NOT EXISTS (?))", []enums.PayoutStatus{enums.SENDING, enums.SENT}, enums.NEW,
+			r.tx.Table("wallets").Select("1").
+				Where("wallets.user_id = withdrawals.wallet_id AND wallets.currency = withdrawals.currency").
+				Where("wallets.status <> ?", core.WalletActive),
+		).
 		Where("bank = ?", bankType)
 
 	if idPrefix != "" {
@@ -39,6 +105,70 @@ func (r *withdrawalRepo) GetUnFinishedWithdraws(ctx context.Context, idPrefix st
 	return withdraws, err
 }
 
+// RoutedAmount returns the amount of the withdrawals in currency created for
+// bank since, leaving failed ones out. It first waits for other transactions
+// that asked for an amount in currency since then to end and keeps them
+// waiting until this one ends, so two withdrawals can not both fit in what is
+// left of a quota. The lock is the same for every bank: a transaction checking
+// several banks holds a single lock and can not deadlock with one checking
+// them in another order.
+func (r *withdrawalRepo) RoutedAmount(ctx context.Context, bank enums.BankType, currency core.Currency, since time.Time) (int64, error) {
+	key := fmt.Sprintf("withdrawal_quota:%s:%s", currency, since.UTC().Format(time.RFC3339))
+	if err := r.tx.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", key).Error; err != nil {
+		return 0, err
+	}
+	// a statement of its own, so it sees what the transactions waited for committed
+	var amount int64
+	err := r.tx.WithContext(ctx).Model(&Withdrawal{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("bank = ? AND currency = ? AND created_at >= ? AND status <> ?", bank, currency, since, enums.FAILED).
+		Scan(&amount).Error
+	return amount, err
+}
+
+// GetBankHealth returns the health of bank; a bank never failed has none
+// stored and gets a zero one.
+func (r *withdrawalRepo) GetBankHealth(ctx context.Context, bank enums.BankType) (*BankHealth, error) {
+	var health BankHealth
+	err := r.tx.WithContext(ctx).First(&health, "bank = ?", bank).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &BankHealth{Bank: bank}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// RecordBankSend counts a failed send to bank, or resets the count when
+// sendErr is nil.
+func (r *withdrawalRepo) RecordBankSend(ctx context.Context, bank enums.BankType, sendErr error) error {
+	now := time.Now()
+	health := BankHealth{Bank: bank, UpdatedAt: now}
+	updates := map[string]any{"failures": 0, "updated_at": now}
+	if sendErr != nil {
+		lastError := sendErr.Error()
+		if len(lastError) > 255 {
+			lastError = lastError[:255]
+		}
+		health.Failures = 1
+		health.LastError = lastError
+		health.FailedAt = &now
+		updates = map[string]any{
+			"failures":   gorm.Expr("bank_health.failures + 1"),
+			"last_error": lastError,
+			"failed_at":  now,
+			"updated_at": now,
+		}
+	}
+	return r.tx.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "bank"}},
+			DoUpdates: clause.Assignments(updates),
+		}).
+		Create(&health).Error
+}
+
 func (r *withdrawalRepo) GetDBTransaction() *gorm.DB {
 	return r.tx
 }
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/utils/db/dbtest"
+	"wallet/lib/withdraws/enums"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithdrawalRepo_RoutedAmountLocksTheQuota(t *testing.T) {
+	recorder := &dbtest.Recorder{Columns: []string{"coalesce"}, Rows: [][]driver.Value{{int64(4500)}}}
+	repo := NewWithdrawalRepo(dbtest.Open(t, recorder))
+	day := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	amount, err := repo.RoutedAmount(context.Background(), enums.MELLAT, core.IRR, day)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4500), amount)
+
+	// the lock of the currency and day, shared by all banks, comes before the sum
+	lock, at, ok := recorder.Find("pg_advisory_xact_lock")
+	assert.True(t, ok)
+	assert.Zero(t, at)
+	assert.Equal(t, []any{"withdrawal_quota:IRR:2025-10-01T00:00:00Z"}, lock.Args)
+	assert.Len(t, recorder.Statements, 2)
+}
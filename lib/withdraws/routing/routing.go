@@ -0,0 +1,231 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations"
+)
+
+var ErrInvalidConfig = errors.New("invalid routing config")
+var ErrNoRoute = errors.New("no bank can take the withdrawal")
+var ErrQuotaExceeded = errors.New("the withdrawal exceeds the daily quota of its bank")
+
+const defaultRule = "default"
+
+// Config routes withdrawals to banks. Amounts are in minor units of the
+// withdrawal currency.
+type Config struct {
+	Rules      []Rule                      // the first matching rule routes the withdrawal
+	Default    []string                    // banks when no rule matches, in order of preference
+	Quotas     map[string]map[string]int64 // per bank and currency, the amount routed per day, unlimited when missing
+	DownAfter  int                         // consecutive failed sends that take a bank down, 3 by default
+	RetryAfter time.Duration               // a down bank is tried again after, 5m by default
+}
+
+func (c *Config) FillDefaults() {
+	if c.DownAfter == 0 {
+		c.DownAfter = 3
+	}
+	if c.RetryAfter == 0 {
+		c.RetryAfter = 5 * time.Minute
+	}
+}
+
+// Rule matches withdrawals by the bank code of the destination IBAN and the
+// amount; empty conditions match everything. A zero MaxAmount means no upper
+// bound.
+type Rule struct {
+	Name      string
+	BankCodes []string `mapstructure:"bank_codes"` // e.g. "012" of IRkk012...
+	MinAmount int64    `mapstructure:"min_amount"` // inclusive
+	MaxAmount int64    `mapstructure:"max_amount"` // inclusive
+	Banks     []string // in order of preference, the rest are fallbacks
+}
+
+// Route is how a withdrawal was routed to its bank; it is zero for
+// withdrawals created with a bank.
+type Route struct {
+	Rule     string `gorm:"type:varchar(64)" json:"rule,omitempty"`     // the matched rule, "default" when none matched
+	Fallback bool   `json:"fallback,omitempty"`                         // the preferred bank of the rule was passed over
+	Skipped  string `gorm:"type:varchar(255)" json:"skipped,omitempty"` // the banks passed over and why, e.g. "saman: down"
+}
+
+// Payout is what the router knows of a withdrawal.
+type Payout struct {
+	BankCode string // of the destination IBAN, see iban.IBAN.BankCode
+	Currency core.Currency
+	Amount   int64
+}
+
+// State is what the router needs to know about the banks.
+type State interface {
+	// Failures returns the consecutive failed sends to bank and when the
+	// last one was.
+	Failures(ctx context.Context, bank enums.BankType) (int, time.Time, error)
+	// Routed returns the amount routed to bank in currency since, not
+	// counting failed withdrawals. It must hold other routings in currency
+	// off until the routed withdrawal is stored or given up, with one lock
+	// for all banks so checking several of them can not deadlock.
+	Routed(ctx context.Context, bank enums.BankType, currency core.Currency, since time.Time) (int64, error)
+}
+
+type Router interface {
+	// Route picks the bank of payout, or returns ErrNoRoute when all banks
+	// of the matching rule are down, out of quota or can not pay its
+	// currency.
+	Route(ctx context.Context, state State, payout Payout) (enums.BankType, Route, error)
+	// CheckQuota returns ErrQuotaExceeded when payout does not fit in what
+	// is left of the daily quota of bank, for withdrawals created with one.
+	CheckQuota(ctx context.Context, state State, bank enums.BankType, payout Payout) error
+}
+
+func New(config Config) (Router, error) {
+	config.FillDefaults()
+	if config.DownAfter < 0 || config.RetryAfter < 0 {
+		return nil, ErrInvalidConfig
+	}
+	if len(config.Default) == 0 {
+		return nil, fmt.Errorf("%w: no default banks", ErrInvalidConfig)
+	}
+	if err := validateBanks(config.Default); err != nil {
+		return nil, err
+	}
+	for i, rule := range config.Rules {
+		if rule.Name == "" || rule.Name == defaultRule {
+			return nil, fmt.Errorf("%w: rule %d needs a name other than %q", ErrInvalidConfig, i, defaultRule)
+		}
+		if len(rule.Banks) == 0 || rule.MinAmount < 0 || rule.MaxAmount < 0 ||
+			(rule.MaxAmount != 0 && rule.MaxAmount < rule.MinAmount) {
+			return nil, fmt.Errorf("%w: rule %s", ErrInvalidConfig, rule.Name)
+		}
+		if err := validateBanks(rule.Banks); err != nil {
+			return nil, err
+		}
+	}
+	quotas := make(map[enums.BankType]map[core.Currency]int64, len(config.Quotas))
+	for bank, currencies := range config.Quotas {
+		if err := validateBanks([]string{bank}); err != nil {
+			return nil, err
+		}
+		bankQuotas := make(map[core.Currency]int64, len(currencies))
+		for code, quota := range currencies {
+			// config keys come in lower case, ParseCurrency takes them
+			currency, err := core.ParseCurrency(code)
+			if err != nil || quota < 0 {
+				return nil, fmt.Errorf("%w: quota of %s in %s", ErrInvalidConfig, bank, code)
+			}
+			bankQuotas[currency] = quota
+		}
+		quotas[enums.BankType(strings.ToLower(bank))] = bankQuotas
+	}
+	return &router{config: config, quotas: quotas}, nil
+}
+
+func validateBanks(banks []string) error {
+	for _, bank := range banks {
+		switch enums.BankType(strings.ToLower(bank)) {
+		case enums.DUMMY, enums.SAMANAN, enums.MELLAT:
+		default:
+			return fmt.Errorf("%w: unknown bank %q", ErrInvalidConfig, bank)
+		}
+	}
+	return nil
+}
+
+type router struct {
+	config Config
+	quotas map[enums.BankType]map[core.Currency]int64
+}
+
+func (r *router) Route(ctx context.Context, state State, payout Payout) (enums.BankType, Route, error) {
+	route := Route{Rule: defaultRule}
+	banks := r.config.Default
+	for _, rule := range r.config.Rules {
+		if rule.matches(payout) {
+			route.Rule = rule.Name
+			banks = rule.Banks
+			break
+		}
+	}
+	var skipped []string
+	for _, name := range banks {
+		bank := enums.BankType(strings.ToLower(name))
+		reason, err := r.unavailable(ctx, state, bank, payout)
+		if err != nil {
+			return "", Route{}, err
+		}
+		if reason != "" {
+			skipped = append(skipped, string(bank)+": "+reason)
+			continue
+		}
+		route.Fallback = len(skipped) > 0
+		route.Skipped = strings.Join(skipped, ", ")
+		return bank, route, nil
+	}
+	return "", Route{}, fmt.Errorf("%w: %s", ErrNoRoute, strings.Join(skipped, ", "))
+}
+
+// unavailable returns why bank can not take payout, or "" if it can.
+func (r *router) unavailable(ctx context.Context, state State, bank enums.BankType, payout Payout) (string, error) {
+	if !integrations.SupportsCurrency(bank, payout.Currency) {
+		return "currency", nil
+	}
+	failures, lastFailure, err := state.Failures(ctx, bank)
+	if err != nil {
+		return "", err
+	}
+	if failures >= r.config.DownAfter && time.Since(lastFailure) < r.config.RetryAfter {
+		return "down", nil
+	}
+	exceeded, err := r.exceedsQuota(ctx, state, bank, payout)
+	if err != nil || !exceeded {
+		return "", err
+	}
+	return "quota", nil
+}
+
+func (r *router) CheckQuota(ctx context.Context, state State, bank enums.BankType, payout Payout) error {
+	exceeded, err := r.exceedsQuota(ctx, state, bank, payout)
+	if err != nil {
+		return err
+	}
+	if exceeded {
+		return fmt.Errorf("%w: %s", ErrQuotaExceeded, bank)
+	}
+	return nil
+}
+
+// exceedsQuota reports whether payout does not fit in the daily quota of
+// bank in its currency; banks without one take everything.
+func (r *router) exceedsQuota(ctx context.Context, state State, bank enums.BankType, payout Payout) (bool, error) {
+	quota, ok := r.quotas[bank][payout.Currency]
+	if !ok {
+		return false, nil
+	}
+	// days start at midnight UTC
+	routed, err := state.Routed(ctx, bank, payout.Currency, time.Now().UTC().Truncate(24*time.Hour))
+	if err != nil {
+		return false, err
+	}
+	return routed+payout.Amount > quota, nil
+}
+
+func (rule *Rule) matches(payout Payout) bool {
+	if payout.Amount < rule.MinAmount || (rule.MaxAmount != 0 && payout.Amount > rule.MaxAmount) {
+		return false
+	}
+	if len(rule.BankCodes) == 0 {
+		return true
+	}
+	for _, code := range rule.BankCodes {
+		if code == payout.BankCode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/core"
+	"wallet/lib/withdraws/enums"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeState struct {
+	failures map[enums.BankType]int
+	failedAt time.Time
+	routed   map[enums.BankType]int64
+}
+
+func (s *fakeState) Failures(ctx context.Context, bank enums.BankType) (int, time.Time, error) {
+	return s.failures[bank], s.failedAt, nil
+}
+
+func (s *fakeState) Routed(ctx context.Context, bank enums.BankType, currency core.Currency, since time.Time) (int64, error) {
+	return s.routed[bank], nil
+}
+
+var testConfig = Config{
+	Rules: []Rule{
+		{Name: "large", MinAmount: 1_000_000, Banks: []string{"saman"}},
+		{Name: "mellat-accounts", BankCodes: []string{"012"}, Banks: []string{"mellat", "saman"}},
+	},
+	Default: []string{"saman", "mellat"},
+	Quotas:  map[string]map[string]int64{"Mellat": {"irr": 5000}},
+}
+
+func TestRouter_Rules(t *testing.T) {
+	router, err := New(testConfig)
+	assert.NoError(t, err)
+	state := &fakeState{}
+
+	for _, tc := range []struct {
+		bankCode string
+		amount   int64
+		bank     enums.BankType
+		rule     string
+	}{
+		{"012", 1000, enums.MELLAT, "mellat-accounts"},
+		{"012", 1_000_000, enums.SAMANAN, "large"},
+		{"054", 1000, enums.SAMANAN, "default"},
+		{"", 1000, enums.SAMANAN, "default"},
+	} {
+		bank, route, err := router.Route(context.Background(), state, Payout{BankCode: tc.bankCode, Currency: core.IRR, Amount: tc.amount})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.bank, bank, tc.bankCode)
+		assert.Equal(t, Route{Rule: tc.rule}, route, tc.bankCode)
+	}
+}
+
+func TestRouter_Fallback(t *testing.T) {
+	router, err := New(testConfig)
+	assert.NoError(t, err)
+	payout := Payout{BankCode: "012", Currency: core.IRR, Amount: 1000}
+
+	// down after three failed sends in a row
+	state := &fakeState{failures: map[enums.BankType]int{enums.MELLAT: 3}, failedAt: time.Now()}
+	bank, route, err := router.Route(context.Background(), state, payout)
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SAMANAN, bank)
+	assert.Equal(t, Route{Rule: "mellat-accounts", Fallback: true, Skipped: "mellat: down"}, route)
+
+	// and tried again once the retry time passed
+	state.failedAt = time.Now().Add(-10 * time.Minute)
+	bank, _, err = router.Route(context.Background(), state, payout)
+	assert.NoError(t, err)
+	assert.Equal(t, enums.MELLAT, bank)
+
+	// out of its daily quota
+	state = &fakeState{routed: map[enums.BankType]int64{enums.MELLAT: 4500}}
+	bank, route, err = router.Route(context.Background(), state, payout)
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SAMANAN, bank)
+	assert.Equal(t, "mellat: quota", route.Skipped)
+
+	// no bank left
+	state.failures = map[enums.BankType]int{enums.SAMANAN: 5}
+	state.failedAt = time.Now()
+	_, _, err = router.Route(context.Background(), state, payout)
+	assert.ErrorIs(t, err, ErrNoRoute)
+}
+
+func TestRouter_Currency(t *testing.T) {
+	router, err := New(Config{
+		Default: []string{"saman", "mellat", "dummy"},
+		Quotas:  map[string]map[string]int64{"dummy": {"usd": 5000}},
+	})
+	assert.NoError(t, err)
+	state := &fakeState{routed: map[enums.BankType]int64{enums.DUMMY: 4500}}
+
+	// saman and mellat pay out rials only
+	bank, route, err := router.Route(context.Background(), state, Payout{Currency: core.EUR, Amount: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, enums.DUMMY, bank)
+	assert.Equal(t, Route{Rule: "default", Fallback: true, Skipped: "saman: currency, mellat: currency"}, route)
+
+	// the quota of one currency does not limit another
+	_, _, err = router.Route(context.Background(), state, Payout{Currency: core.USD, Amount: 1000})
+	assert.ErrorIs(t, err, ErrNoRoute)
+	assert.ErrorContains(t, err, "dummy: quota")
+	bank, _, err = router.Route(context.Background(), state, Payout{Currency: core.IRR, Amount: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, enums.SAMANAN, bank)
+}
+
+func TestRouter_CheckQuota(t *testing.T) {
+	router, err := New(testConfig)
+	assert.NoError(t, err)
+	state := &fakeState{routed: map[enums.BankType]int64{enums.MELLAT: 4500}}
+
+	assert.NoError(t, router.CheckQuota(context.Background(), state, enums.MELLAT, Payout{Currency: core.IRR, Amount: 500}))
+	err = router.CheckQuota(context.Background(), state, enums.MELLAT, Payout{Currency: core.IRR, Amount: 501})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	// banks and currencies without a quota take everything
+	assert.NoError(t, router.CheckQuota(context.Background(), state, enums.SAMANAN, Payout{Currency: core.IRR, Amount: 1_000_000}))
+	assert.NoError(t, router.CheckQuota(context.Background(), state, enums.MELLAT, Payout{Currency: core.USD, Amount: 1_000_000}))
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	for _, config := range []Config{
+		{},
+		{Default: []string{"tejarat"}},
+		{Default: []string{"saman"}, Rules: []Rule{{Banks: []string{"saman"}}}},
+		{Default: []string{"saman"}, Rules: []Rule{{Name: "empty"}}},
+		{Default: []string{"saman"}, Rules: []Rule{{Name: "range", MinAmount: 10, MaxAmount: 5, Banks: []string{"saman"}}}},
+		{Default: []string{"saman"}, Quotas: map[string]map[string]int64{"saman": {"IRR": -1}}},
+		{Default: []string{"saman"}, Quotas: map[string]map[string]int64{"saman": {"XYZ": 1}}},
+	} {
+		_, err := New(config)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	}
+}
@@ -2,16 +2,29 @@ package withdraws
 
 import (
 	"context"
+	"errors"
+	"time"
+	"wallet/lib/beneficiaries"
 	"wallet/lib/core"
+	"wallet/lib/fees"
+	"wallet/lib/iban"
+	"wallet/lib/idempotency"
 	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations"
 	"wallet/lib/withdraws/repository"
+	"wallet/lib/withdraws/routing"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type service struct {
-	coreRepoFactory     core.RepoFactory
-	withdrawRepoFactory repository.RepoFactory
+	coreRepoFactory        core.RepoFactory
+	withdrawRepoFactory    repository.RepoFactory
+	idempotencyRepoFactory idempotency.RepoFactory
+	feePolicy              fees.Policy
+	router                 routing.Router
+	beneficiaryService     beneficiaries.Service
 }
 
 func (s *service) Create(ctx context.Context, withdraw *Withdrawal) error {
@@ -23,15 +36,64 @@ func (s *service) Create(ctx context.Context, withdraw *Withdrawal) error {
 	defer func() {
 		_ = withdrawRepo.RollBack()
 	}()
-	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, withdraw.WalletID)
+	if err := s.create(ctx, withdrawRepo, coreRepo, withdraw); err != nil {
+		return err
+	}
+	return withdrawRepo.Commit()
+}
+
+// CreateIdempotent creates the withdrawal once per key; a replayed request
+// gets the originally created withdrawal back in withdraw.
+func (s *service) CreateIdempotent(ctx context.Context, withdraw *Withdrawal, key idempotency.Key) (bool, error) {
+	if withdraw.ID != uuid.Nil {
+		return false, ErrInvalidState
+	}
+	withdrawRepo := s.withdrawRepoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(withdrawRepo.GetDBTransaction())
+	idempotencyRepo := s.idempotencyRepoFactory.New(withdrawRepo.GetDBTransaction())
+	defer func() {
+		_ = withdrawRepo.RollBack()
+	}()
+	replayed, err := idempotency.Do(ctx, idempotencyRepo, key, withdraw, func() error {
+		return s.create(ctx, withdrawRepo, coreRepo, withdraw)
+	})
+	if err != nil || replayed {
+		return replayed, err
+	}
+	return false, withdrawRepo.Commit()
+}
+
+func (s *service) create(ctx context.Context, withdrawRepo repository.Repo, coreRepo core.Repo, withdraw *Withdrawal) error {
+	if withdraw.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, withdraw.WalletID, withdraw.Currency)
 	if err != nil {
 		return err
 	}
-	if wallet.AvailableBalance < withdraw.Amount {
+	if err := wallet.CanDebit(); err != nil {
+		return err
+	}
+	if err := s.destination(ctx, withdraw); err != nil {
+		return err
+	}
+	if withdraw.Bank == "" {
+		if err := s.route(ctx, withdrawRepo, withdraw); err != nil {
+			return err
+		}
+	} else if err := s.checkQuota(ctx, withdrawRepo, withdraw); err != nil {
+		return err
+	}
+	withdraw.Fee, err = s.feePolicy.Calculate(withdraw.Currency, string(withdraw.Bank), withdraw.Amount)
+	if err != nil {
+		return err
+	}
+	blocked := withdraw.Amount + withdraw.Fee.Total
+	if wallet.Spendable() < blocked {
 		return ErrInsufficientBalance
 	}
-	wallet.AvailableBalance -= withdraw.Amount
-	wallet.BlockedBalance += withdraw.Amount
+	wallet.AvailableBalance -= blocked
+	wallet.BlockedBalance += blocked
 	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
 		return err
 	}
@@ -39,68 +101,228 @@ func (s *service) Create(ctx context.Context, withdraw *Withdrawal) error {
 		return err
 	}
 	trx := &core.Transaction{
-		Amount:        -withdraw.Amount,
-		BlockedAmount: withdraw.Amount,
+		Kind:          core.KindBlock,
+		Amount:        -blocked,
+		BlockedAmount: blocked,
 		WalletID:      withdraw.WalletID,
+		Currency:      withdraw.Currency,
 		Description:   "blocking for withdrawal",
 		Reference:     withdraw.ID,
 	}
-	if err := coreRepo.Transaction().Create(ctx, trx); err != nil {
+	if err := core.Post(ctx, coreRepo, core.Suspense, trx); err != nil {
 		return err
 	}
 	withdraw.BlockTransactionID = trx.ID
 	withdraw.Status = enums.NEW
-	if err := withdrawRepo.Update(ctx, withdraw); err != nil {
+	return withdrawRepo.Update(ctx, withdraw)
+}
+
+// destination fills in the IBAN of a withdrawal to a saved beneficiary, and
+// the beneficiary of one to an IBAN. Either way the beneficiary must be past
+// its cooling-off period.
+func (s *service) destination(ctx context.Context, withdraw *Withdrawal) error {
+	var beneficiary *beneficiaries.Beneficiary
+	if withdraw.BeneficiaryID != nil {
+		var err error
+		beneficiary, err = s.beneficiaryService.Resolve(ctx, *withdraw.BeneficiaryID, withdraw.WalletID)
+		if err != nil {
+			return err
+		}
+		withdraw.Iban = beneficiary.Iban
+	}
+	account, err := iban.Parse(withdraw.Iban)
+	if err != nil {
+		return err
+	}
+	withdraw.Iban = account.String()
+	withdraw.BeneficiaryBankCode = account.BankCode()
+	if beneficiary == nil {
+		beneficiary, err = s.beneficiaryService.ResolveIban(ctx, withdraw.WalletID, withdraw.Iban)
+		if err != nil || beneficiary == nil {
+			return err
+		}
+	}
+	withdraw.BeneficiaryID = &beneficiary.ID
+	if withdraw.BeneficiaryName == "" {
+		withdraw.BeneficiaryName = beneficiary.HolderName
+	}
+	return nil
+}
+
+// route picks the bank of a withdrawal created without one.
+func (s *service) route(ctx context.Context, withdrawRepo repository.Repo, withdraw *Withdrawal) error {
+	if s.router == nil {
+		return ErrNoRoute
+	}
+	bank, route, err := s.router.Route(ctx, &routingState{withdrawRepo: withdrawRepo}, routing.Payout{
+		BankCode: withdraw.BeneficiaryBankCode,
+		Currency: withdraw.Currency,
+		Amount:   withdraw.Amount,
+	})
+	if errors.Is(err, routing.ErrNoRoute) {
+		return ErrNoRoute
+	}
+	if err != nil {
+		return err
+	}
+	withdraw.Bank = bank
+	withdraw.Route = route
+	return nil
+}
+
+// checkQuota counts a withdrawal created with a bank against the daily quota
+// of the bank like a routed one.
+func (s *service) checkQuota(ctx context.Context, withdrawRepo repository.Repo, withdraw *Withdrawal) error {
+	if s.router == nil {
+		return nil
+	}
+	return s.router.CheckQuota(ctx, &routingState{withdrawRepo: withdrawRepo}, withdraw.Bank, routing.Payout{
+		BankCode: withdraw.BeneficiaryBankCode,
+		Currency: withdraw.Currency,
+		Amount:   withdraw.Amount,
+	})
+}
+
+// routingState tells the router about the banks from the withdrawals and
+// their recorded health.
+type routingState struct {
+	withdrawRepo repository.Repo
+}
+
+func (rs *routingState) Failures(ctx context.Context, bank enums.BankType) (int, time.Time, error) {
+	health, err := rs.withdrawRepo.GetBankHealth(ctx, bank)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if health.FailedAt == nil {
+		return health.Failures, time.Time{}, nil
+	}
+	return health.Failures, *health.FailedAt, nil
+}
+
+func (rs *routingState) Routed(ctx context.Context, bank enums.BankType, currency core.Currency, since time.Time) (int64, error) {
+	return rs.withdrawRepo.RoutedAmount(ctx, bank, currency, since)
+}
+
+func (s *service) RecordBankSend(ctx context.Context, bank enums.BankType, sendErr error) error {
+	withdrawRepo := s.withdrawRepoFactory.New(nil)
+	defer func() {
+		_ = withdrawRepo.RollBack()
+	}()
+	if err := withdrawRepo.RecordBankSend(ctx, bank, sendErr); err != nil {
 		return err
 	}
 	return withdrawRepo.Commit()
 }
 
 func (s *service) Reverse(ctx context.Context, withdraw *Withdrawal) error {
-	if withdraw.Status == enums.FAILED || withdraw.Status == enums.SUCCESS {
+	withdrawRepo := s.withdrawRepoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(withdrawRepo.GetDBTransaction())
+	defer func() {
+		_ = withdrawRepo.RollBack()
+	}()
+	locked, err := getForUpdate(ctx, withdrawRepo, withdraw.ID)
+	if err != nil {
+		return err
+	}
+	if locked.Status == enums.FAILED || locked.Status == enums.SUCCESS {
 		return ErrInvalidState
 	}
+	*withdraw = *locked
+	if err := s.reverse(ctx, withdrawRepo, coreRepo, withdraw); err != nil {
+		return err
+	}
+	return withdrawRepo.Commit()
+}
+
+func (s *service) Cancel(ctx context.Context, id uuid.UUID) (*Withdrawal, error) {
 	withdrawRepo := s.withdrawRepoFactory.New(nil)
 	coreRepo := s.coreRepoFactory.New(withdrawRepo.GetDBTransaction())
 	defer func() {
 		_ = withdrawRepo.RollBack()
 	}()
-	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, withdraw.WalletID)
+	// holding the row lock keeps the banker from claiming it meanwhile
+	withdraw, err := getForUpdate(ctx, withdrawRepo, id)
+	if err != nil {
+		return nil, err
+	}
+	if withdraw.Status != enums.NEW {
+		return nil, ErrNotCancellable
+	}
+	if err := s.reverse(ctx, withdrawRepo, coreRepo, withdraw); err != nil {
+		return nil, err
+	}
+	return withdraw, withdrawRepo.Commit()
+}
+
+// reverse returns the blocked amount and fee of a locked withdrawal to the
+// available balance and marks it failed.
+func (s *service) reverse(ctx context.Context, withdrawRepo repository.Repo, coreRepo core.Repo, withdraw *Withdrawal) error {
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, withdraw.WalletID, withdraw.Currency)
 	if err != nil {
 		return err
 	}
-	wallet.AvailableBalance += withdraw.Amount
-	wallet.BlockedBalance -= withdraw.Amount
+	blocked := withdraw.Amount + withdraw.Fee.Total
+	wallet.AvailableBalance += blocked
+	wallet.BlockedBalance -= blocked
 	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
-		return nil
+		return err
 	}
 	trx := &core.Transaction{
-		Amount:        withdraw.Amount,
-		BlockedAmount: -withdraw.Amount,
+		Kind:          core.KindReversal,
+		Amount:        blocked,
+		BlockedAmount: -blocked,
 		WalletID:      withdraw.WalletID,
+		Currency:      withdraw.Currency,
 		Description:   "withdraw cancellation",
 		Reference:     withdraw.ID,
 	}
-	if err := coreRepo.Transaction().Create(ctx, trx); err != nil {
+	if err := core.Post(ctx, coreRepo, core.Suspense, trx); err != nil {
 		return err
 	}
 	withdraw.ReverserTransactionID = trx.ID
 	withdraw.Status = enums.FAILED
-	if err := withdrawRepo.Update(ctx, withdraw); err != nil {
-		return err
-	}
-	return withdrawRepo.Commit()
+	return withdrawRepo.Update(ctx, withdraw)
+}
+
+func (s *service) Claim(ctx context.Context, withdraw *Withdrawal) error {
+	// it may have been cancelled since the banker listed it
+	return s.moveStatus(ctx, withdraw, enums.NEW, enums.SENDING)
 }
 
 func (s *service) MarkAsSent(ctx context.Context, withdraw *Withdrawal) error {
-	if withdraw.Status != enums.NEW {
-		return ErrInvalidState
+	return s.moveStatus(ctx, withdraw, enums.SENDING, enums.SENT)
+}
+
+func (s *service) Resend(ctx context.Context, withdraw *Withdrawal) error {
+	return s.moveStatus(ctx, withdraw, enums.SENT, enums.SENDING)
+}
+
+// setStatus changes the status of a withdrawal, noting when the bank
+// acknowledged it.
+func setStatus(withdraw *Withdrawal, status enums.PayoutStatus) {
+	if status == enums.SENT && withdraw.Status != enums.SENT {
+		now := time.Now()
+		withdraw.SentAt = &now
 	}
+	withdraw.Status = status
+}
+
+// moveStatus changes the status of a withdrawal that is still in from.
+func (s *service) moveStatus(ctx context.Context, withdraw *Withdrawal, from enums.PayoutStatus, to enums.PayoutStatus) error {
 	withdrawRepo := s.withdrawRepoFactory.New(nil)
 	defer func() {
 		_ = withdrawRepo.RollBack()
 	}()
-	withdraw.Status = enums.SENT
+	locked, err := getForUpdate(ctx, withdrawRepo, withdraw.ID)
+	if err != nil {
+		return err
+	}
+	if locked.Status != from {
+		return ErrInvalidState
+	}
+	*withdraw = *locked
+	setStatus(withdraw, to)
 	if err := withdrawRepo.Update(ctx, withdraw); err != nil {
 		return err
 	}
@@ -108,36 +330,149 @@ func (s *service) MarkAsSent(ctx context.Context, withdraw *Withdrawal) error {
 }
 
 func (s *service) Complete(ctx context.Context, withdraw *Withdrawal) error {
-	if withdraw.Status != enums.SENT {
-		return ErrInvalidState
-	}
 	withdrawRepo := s.withdrawRepoFactory.New(nil)
 	coreRepo := s.coreRepoFactory.New(withdrawRepo.GetDBTransaction())
 	defer func() {
 		_ = withdrawRepo.RollBack()
 	}()
-	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, withdraw.WalletID)
+	locked, err := getForUpdate(ctx, withdrawRepo, withdraw.ID)
+	if err != nil {
+		return err
+	}
+	if locked.Status != enums.SENT {
+		return ErrInvalidState
+	}
+	*withdraw = *locked
+	if err := s.complete(ctx, withdrawRepo, coreRepo, withdraw); err != nil {
+		return err
+	}
+	return withdrawRepo.Commit()
+}
+
+// complete pays the blocked amount of a locked withdrawal out, charges the
+// fee and marks it successful.
+func (s *service) complete(ctx context.Context, withdrawRepo repository.Repo, coreRepo core.Repo, withdraw *Withdrawal) error {
+	wallet, err := coreRepo.Wallet().GetOrCreateForUpdate(ctx, withdraw.WalletID, withdraw.Currency)
 	if err != nil {
 		return err
 	}
-	wallet.BlockedBalance -= withdraw.Amount
+	wallet.BlockedBalance -= withdraw.Amount + withdraw.Fee.Total
 	if err := coreRepo.Wallet().Update(ctx, wallet); err != nil {
-		return nil
+		return err
 	}
 	trx := &core.Transaction{
-		Amount:      withdraw.Amount,
-		WalletID:    withdraw.WalletID,
-		Description: "withdraw completion",
-		Reference:   withdraw.ID,
+		Kind:          core.KindWithdraw,
+		BlockedAmount: -withdraw.Amount,
+		WalletID:      withdraw.WalletID,
+		Currency:      withdraw.Currency,
+		Description:   "withdraw completion",
+		Reference:     withdraw.ID,
 	}
-	if err := coreRepo.Transaction().Create(ctx, trx); err != nil {
+	if err := core.Post(ctx, coreRepo, core.BankClearing, trx); err != nil {
 		return err
 	}
-	withdraw.ReverserTransactionID = trx.ID
+	withdraw.WithdrawalTransactionID = trx.ID
+	if withdraw.Fee.Total != 0 {
+		feeTrx := &core.Transaction{
+			Kind:          core.KindFee,
+			BlockedAmount: -withdraw.Fee.Total,
+			WalletID:      withdraw.WalletID,
+			Currency:      withdraw.Currency,
+			Description:   "withdraw fee",
+			Reference:     withdraw.ID,
+		}
+		if err := core.Post(ctx, coreRepo, core.Fees, feeTrx); err != nil {
+			return err
+		}
+		withdraw.FeeTransactionID = feeTrx.ID
+	}
 	withdraw.Status = enums.SUCCESS
-	if err := withdrawRepo.Update(ctx, withdraw); err != nil {
+	return withdrawRepo.Update(ctx, withdraw)
+}
+
+func (s *service) ApplyPayoutResult(ctx context.Context, withdraw *Withdrawal, result *integrations.PayoutResult) error {
+	withdrawRepo := s.withdrawRepoFactory.New(nil)
+	coreRepo := s.coreRepoFactory.New(withdrawRepo.GetDBTransaction())
+	defer func() {
+		_ = withdrawRepo.RollBack()
+	}()
+	locked, err := getForUpdate(ctx, withdrawRepo, withdraw.ID)
+	if err != nil {
+		return err
+	}
+	if locked.Status != enums.SENDING && locked.Status != enums.SENT {
+		return ErrInvalidState
+	}
+	*withdraw = *locked
+	recordBankResult(&withdraw.BankResult, result)
+	switch result.Status {
+	case enums.SUCCESS:
+		err = s.complete(ctx, withdrawRepo, coreRepo, withdraw)
+	case enums.FAILED:
+		err = s.reverse(ctx, withdrawRepo, coreRepo, withdraw)
+	default:
+		// the bank has it now
+		setStatus(withdraw, enums.SENT)
+		err = withdrawRepo.Update(ctx, withdraw)
+	}
+	if err != nil {
 		return err
 	}
 	return withdrawRepo.Commit()
+}
+
+// recordBankResult keeps what the bank reported, without forgetting what it
+// reported earlier but left out this time.
+func recordBankResult(stored *repository.BankResult, result *integrations.PayoutResult) {
+	if result.Reference != "" {
+		stored.Reference = result.Reference
+	}
+	if result.ReasonCode != "" || result.Reason != "" {
+		stored.ReasonCode = result.ReasonCode
+		stored.Reason = result.Reason
+	}
+	if !result.AcceptedAt.IsZero() && stored.AcceptedAt == nil {
+		acceptedAt := result.AcceptedAt
+		stored.AcceptedAt = &acceptedAt
+	}
+	if !result.SettledAt.IsZero() {
+		settledAt := result.SettledAt
+		stored.SettledAt = &settledAt
+	}
+}
 
+func getForUpdate(ctx context.Context, withdrawRepo repository.Repo, id uuid.UUID) (*Withdrawal, error) {
+	withdraw, err := withdrawRepo.GetForUpdate(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return withdraw, err
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*Withdrawal, error) {
+	withdrawRepo := s.withdrawRepoFactory.New(nil)
+	defer func() {
+		_ = withdrawRepo.RollBack()
+	}()
+	withdraw, err := withdrawRepo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return withdraw, err
+}
+
+func (s *service) List(ctx context.Context, filter repository.Filter, pageNumber int, pageSize int) ([]Withdrawal, bool, error) {
+	withdrawRepo := s.withdrawRepoFactory.New(nil)
+	defer func() {
+		_ = withdrawRepo.RollBack()
+	}()
+	return withdrawRepo.List(ctx, filter, pageNumber, pageSize)
+}
+
+func (s *service) GetUnFinishedWithdraws(ctx context.Context, IDPrefix string, bankType enums.BankType) ([]Withdrawal, error) {
+	withdrawRepo := s.withdrawRepoFactory.New(nil)
+	defer func() {
+		_ = withdrawRepo.RollBack()
+	}()
+	return withdrawRepo.GetUnFinishedWithdraws(ctx, IDPrefix, bankType)
 }
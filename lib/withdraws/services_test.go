@@ -0,0 +1,649 @@
+package withdraws_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/lib/beneficiaries"
+	"wallet/lib/core"
+	"wallet/lib/fees"
+	"wallet/lib/idempotency"
+	"wallet/lib/withdraws"
+	"wallet/lib/withdraws/enums"
+	"wallet/lib/withdraws/integrations"
+	"wallet/lib/withdraws/repository"
+	"wallet/lib/withdraws/routing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// --- Mocks ---
+
+// MockWithdrawRepo keeps a single stored withdrawal, so status changes are
+// seen by the next locked read like they would be in the database.
+type MockWithdrawRepo struct {
+	mock.Mock
+	stored *repository.Withdrawal
+}
+
+func (m *MockWithdrawRepo) Create(ctx context.Context, w *repository.Withdrawal) error {
+	return m.Called(ctx, w).Error(0)
+}
+func (m *MockWithdrawRepo) Update(ctx context.Context, w *repository.Withdrawal) error {
+	saved := *w
+	m.stored = &saved
+	return m.Called(ctx, w).Error(0)
+}
+func (m *MockWithdrawRepo) Get(ctx context.Context, id uuid.UUID) (*repository.Withdrawal, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*repository.Withdrawal), args.Error(1)
+}
+func (m *MockWithdrawRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*repository.Withdrawal, error) {
+	locked := *m.stored
+	return &locked, m.Called(ctx, id).Error(0)
+}
+func (m *MockWithdrawRepo) List(ctx context.Context, filter repository.Filter, pageNumber int, pageSize int) ([]repository.Withdrawal, bool, error) {
+	args := m.Called(ctx, filter, pageNumber, pageSize)
+	return args.Get(0).([]repository.Withdrawal), args.Bool(1), args.Error(2)
+}
+func (m *MockWithdrawRepo) GetUnFinishedWithdraws(ctx context.Context, prefix string, bankType enums.BankType) ([]repository.Withdrawal, error) {
+	args := m.Called(ctx, prefix, bankType)
+	return args.Get(0).([]repository.Withdrawal), args.Error(1)
+}
+func (m *MockWithdrawRepo) RoutedAmount(ctx context.Context, bank enums.BankType, currency core.Currency, since time.Time) (int64, error) {
+	args := m.Called(ctx, bank, currency, mock.Anything)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockWithdrawRepo) GetBankHealth(ctx context.Context, bank enums.BankType) (*repository.BankHealth, error) {
+	args := m.Called(ctx, bank)
+	return args.Get(0).(*repository.BankHealth), args.Error(1)
+}
+func (m *MockWithdrawRepo) RecordBankSend(ctx context.Context, bank enums.BankType, sendErr error) error {
+	return m.Called(ctx, bank, sendErr).Error(0)
+}
+func (m *MockWithdrawRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockWithdrawRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockWithdrawRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockWithdrawRepoFactory struct{ mock.Mock }
+
+func (m *MockWithdrawRepoFactory) New(tx *gorm.DB) repository.Repo {
+	return m.Called(tx).Get(0).(repository.Repo)
+}
+
+type MockWalletRepo struct{ mock.Mock }
+
+func (m *MockWalletRepo) GetOrCreateForUpdate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) GetOrCreate(ctx context.Context, userID uuid.UUID, currency core.Currency) (*core.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
+	return args.Get(0).(*core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) List(ctx context.Context, userID uuid.UUID) ([]core.Wallet, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]core.Wallet), args.Error(1)
+}
+func (m *MockWalletRepo) Update(ctx context.Context, wallet *core.Wallet) error {
+	// the real repo enforces the balance policy
+	if err := wallet.Validate(); err != nil {
+		return err
+	}
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateCreditLimit(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+func (m *MockWalletRepo) UpdateStatus(ctx context.Context, wallet *core.Wallet) error {
+	return m.Called(ctx, wallet).Error(0)
+}
+
+type MockTransactionRepo struct{ mock.Mock }
+
+func (m *MockTransactionRepo) Create(ctx context.Context, trx *core.Transaction) error {
+	trx.ID = 42
+	return m.Called(ctx, trx).Error(0)
+}
+func (m *MockTransactionRepo) Get(ctx context.Context, filter core.TransactionFilter, pageNumber int, pageSize int) ([]core.Transaction, bool, error) {
+	args := m.Called(ctx, filter, pageNumber, pageSize)
+	return args.Get(0).([]core.Transaction), args.Bool(1), args.Error(2)
+}
+func (m *MockTransactionRepo) List(ctx context.Context, filter core.TransactionFilter, cursor uint64, limit int) ([]core.Transaction, uint64, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	return args.Get(0).([]core.Transaction), args.Get(1).(uint64), args.Error(2)
+}
+func (m *MockTransactionRepo) GetBalanceAt(ctx context.Context, userID uuid.UUID, currency core.Currency, at time.Time) (*core.Balance, error) {
+	args := m.Called(ctx, userID, currency, at)
+	return args.Get(0).(*core.Balance), args.Error(1)
+}
+
+type MockLedgerRepo struct{ mock.Mock }
+
+func (m *MockLedgerRepo) Post(ctx context.Context, entries []core.LedgerEntry) error {
+	return m.Called(ctx, entries).Error(0)
+}
+func (m *MockLedgerRepo) GetBalance(ctx context.Context, account core.Account, currency core.Currency) (*core.AccountBalance, error) {
+	args := m.Called(ctx, account, currency)
+	return args.Get(0).(*core.AccountBalance), args.Error(1)
+}
+
+type MockCoreRepo struct{ mock.Mock }
+
+func (m *MockCoreRepo) Wallet() core.WalletRepo {
+	return m.Called().Get(0).(core.WalletRepo)
+}
+func (m *MockCoreRepo) Transaction() core.TransactionRepo {
+	return m.Called().Get(0).(core.TransactionRepo)
+}
+func (m *MockCoreRepo) Ledger() core.LedgerRepo {
+	return m.Called().Get(0).(core.LedgerRepo)
+}
+func (m *MockCoreRepo) CreditLimitChange() core.CreditLimitChangeRepo {
+	return m.Called().Get(0).(core.CreditLimitChangeRepo)
+}
+func (m *MockCoreRepo) GetDBTransaction() *gorm.DB { return nil }
+func (m *MockCoreRepo) Commit() error              { return m.Called().Error(0) }
+func (m *MockCoreRepo) RollBack() error            { return m.Called().Error(0) }
+
+type MockCoreRepoFactory struct{ mock.Mock }
+
+func (m *MockCoreRepoFactory) New(tx *gorm.DB) core.Repo {
+	return m.Called(tx).Get(0).(core.Repo)
+}
+
+// MockBeneficiaryService only resolves destinations, which is all withdrawals need.
+type MockBeneficiaryService struct {
+	beneficiaries.Service
+	mock.Mock
+}
+
+func (m *MockBeneficiaryService) Resolve(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*beneficiaries.Beneficiary, error) {
+	args := m.Called(ctx, id, userID)
+	beneficiary, _ := args.Get(0).(*beneficiaries.Beneficiary)
+	return beneficiary, args.Error(1)
+}
+func (m *MockBeneficiaryService) ResolveIban(ctx context.Context, userID uuid.UUID, iban string) (*beneficiaries.Beneficiary, error) {
+	args := m.Called(ctx, userID, iban)
+	beneficiary, _ := args.Get(0).(*beneficiaries.Beneficiary)
+	return beneficiary, args.Error(1)
+}
+
+// --- Tests ---
+
+func setup(withdraw *repository.Withdrawal, wallet *core.Wallet) (withdraws.Service, *MockWithdrawRepo) {
+	service, withdrawRepo, _ := setupWithFees(withdraw, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {}}})
+	return service, withdrawRepo
+}
+
+func setupWithFees(withdraw *repository.Withdrawal, wallet *core.Wallet, feeConfig fees.Config) (withdraws.Service, *MockWithdrawRepo, *MockLedgerRepo) {
+	return setupWithRouter(withdraw, wallet, feeConfig, nil)
+}
+
+func setupWithRouter(withdraw *repository.Withdrawal, wallet *core.Wallet, feeConfig fees.Config, router routing.Router) (withdraws.Service, *MockWithdrawRepo, *MockLedgerRepo) {
+	// IBANs need not be saved beneficiaries
+	beneficiaryService := new(MockBeneficiaryService)
+	beneficiaryService.On("ResolveIban", context.Background(), mock.Anything, mock.Anything).Return(nil, nil)
+	return setupWithBeneficiaries(withdraw, wallet, feeConfig, router, beneficiaryService)
+}
+
+func setupWithBeneficiaries(withdraw *repository.Withdrawal, wallet *core.Wallet, feeConfig fees.Config, router routing.Router, beneficiaryService beneficiaries.Service) (withdraws.Service, *MockWithdrawRepo, *MockLedgerRepo) {
+	return setupWithKeys(withdraw, wallet, feeConfig, router, beneficiaryService, nil)
+}
+
+func setupWithKeys(withdraw *repository.Withdrawal, wallet *core.Wallet, feeConfig fees.Config, router routing.Router, beneficiaryService beneficiaries.Service, keys idempotency.RepoFactory) (withdraws.Service, *MockWithdrawRepo, *MockLedgerRepo) {
+	ctx := context.Background()
+	withdrawRepo := &MockWithdrawRepo{stored: withdraw}
+	withdrawRepoFactory := new(MockWithdrawRepoFactory)
+	walletRepo := new(MockWalletRepo)
+	trxRepo := new(MockTransactionRepo)
+	ledgerRepo := new(MockLedgerRepo)
+	coreRepo := new(MockCoreRepo)
+	coreRepoFactory := new(MockCoreRepoFactory)
+
+	withdrawRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*repository.Withdrawal).ID = withdraw.ID
+	}).Return(nil)
+	withdrawRepo.On("Update", ctx, mock.Anything).Return(nil)
+	withdrawRepo.On("GetForUpdate", ctx, withdraw.ID).Return(nil)
+	withdrawRepo.On("GetUnFinishedWithdraws", ctx, "", enums.DUMMY).Return([]repository.Withdrawal{*withdraw}, nil)
+	withdrawRepo.On("Commit").Return(nil)
+	withdrawRepo.On("RollBack").Return(nil)
+	withdrawRepoFactory.On("New", (*gorm.DB)(nil)).Return(withdrawRepo)
+
+	walletRepo.On("GetOrCreateForUpdate", ctx, wallet.UserID, wallet.Currency).Return(wallet, nil)
+	walletRepo.On("Update", ctx, wallet).Return(nil)
+	trxRepo.On("Create", ctx, mock.Anything).Return(nil)
+	ledgerRepo.On("Post", ctx, mock.Anything).Return(nil)
+	coreRepo.On("Wallet").Return(walletRepo)
+	coreRepo.On("Transaction").Return(trxRepo)
+	coreRepo.On("Ledger").Return(ledgerRepo)
+	coreRepoFactory.On("New", (*gorm.DB)(nil)).Return(coreRepo)
+
+	feePolicy, err := fees.New(feeConfig)
+	if err != nil {
+		panic(err)
+	}
+	return withdraws.NewService(coreRepoFactory, withdrawRepoFactory, keys, feePolicy, router, beneficiaryService), withdrawRepo, ledgerRepo
+}
+
+// keyStore is an idempotency repo keeping its keys in memory.
+type keyStore struct {
+	records map[string]idempotency.Record
+}
+
+func (s *keyStore) Reserve(ctx context.Context, scope, key, fingerprint string) (*idempotency.Record, error) {
+	record, ok := s.records[scope+key]
+	if !ok {
+		record = idempotency.Record{Scope: scope, Key: key, Fingerprint: fingerprint}
+		s.records[scope+key] = record
+	}
+	return &record, nil
+}
+func (s *keyStore) Update(ctx context.Context, record *idempotency.Record) error {
+	s.records[record.Scope+record.Key] = *record
+	return nil
+}
+func (s *keyStore) GetDBTransaction() *gorm.DB { return nil }
+func (s *keyStore) Commit() error              { return nil }
+func (s *keyStore) RollBack() error            { return nil }
+
+type keyStoreFactory struct{ store *keyStore }
+
+func (f *keyStoreFactory) New(tx *gorm.DB) idempotency.Repo { return f.store }
+
+func setupIdempotent(withdraw *repository.Withdrawal, wallet *core.Wallet) (withdraws.Service, *MockWithdrawRepo) {
+	beneficiaryService := new(MockBeneficiaryService)
+	beneficiaryService.On("ResolveIban", context.Background(), mock.Anything, mock.Anything).Return(nil, nil)
+	keys := &keyStoreFactory{store: &keyStore{records: map[string]idempotency.Record{}}}
+	service, withdrawRepo, _ := setupWithKeys(withdraw, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {}}}, nil, beneficiaryService, keys)
+	return service, withdrawRepo
+}
+
+const testIban = "IR062960000000100324200001"
+
+func newWithdrawal(wallet *core.Wallet, status enums.PayoutStatus) *repository.Withdrawal {
+	return &repository.Withdrawal{
+		ID:       uuid.New(),
+		WalletID: wallet.UserID,
+		Currency: wallet.Currency,
+		Status:   status,
+		Bank:     enums.DUMMY,
+		Iban:     testIban,
+		Amount:   200,
+	}
+}
+
+func TestService_Cancel(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 300, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.NEW)
+	service, withdrawRepo := setup(withdraw, wallet)
+
+	cancelled, err := service.Cancel(context.Background(), withdraw.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, enums.FAILED, cancelled.Status)
+	assert.Equal(t, uint64(42), cancelled.ReverserTransactionID)
+	assert.Equal(t, int64(500), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+	withdrawRepo.AssertCalled(t, "Commit")
+
+	_, err = service.Cancel(context.Background(), withdraw.ID)
+	assert.ErrorIs(t, err, withdraws.ErrNotCancellable)
+}
+
+func TestService_CancelSent(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.SENT)
+	service, withdrawRepo := setup(withdraw, wallet)
+
+	_, err := service.Cancel(context.Background(), withdraw.ID)
+	assert.ErrorIs(t, err, withdraws.ErrNotCancellable)
+	assert.Equal(t, int64(200), wallet.BlockedBalance)
+	withdrawRepo.AssertNotCalled(t, "Commit")
+}
+
+func TestService_ClaimAfterCancel(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.NEW)
+	service, _ := setup(withdraw, wallet)
+
+	// the banker listed it while it was new
+	listed, err := service.GetUnFinishedWithdraws(context.Background(), "", enums.DUMMY)
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	_, err = service.Cancel(context.Background(), withdraw.ID)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, service.Claim(context.Background(), &listed[0]), withdraws.ErrInvalidState)
+	assert.Equal(t, enums.NEW, listed[0].Status)
+}
+
+func TestService_ClaimThenComplete(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.NEW)
+	service, _ := setup(withdraw, wallet)
+
+	claimed := *withdraw
+	assert.NoError(t, service.Claim(context.Background(), &claimed))
+	assert.Equal(t, enums.SENDING, claimed.Status)
+
+	_, err := service.Cancel(context.Background(), withdraw.ID)
+	assert.ErrorIs(t, err, withdraws.ErrNotCancellable)
+
+	assert.NoError(t, service.MarkAsSent(context.Background(), &claimed))
+	assert.Equal(t, enums.SENT, claimed.Status)
+
+	assert.NoError(t, service.Complete(context.Background(), &claimed))
+	assert.Equal(t, enums.SUCCESS, claimed.Status)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+}
+
+func TestService_Fees(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	service, _, ledgerRepo := setupWithFees(stored, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {
+		Default: fees.Schedule{Flat: 100},
+		Banks:   map[string]fees.Schedule{"dummy": {Flat: 10, Bps: 500}},
+	}}})
+
+	// the fee is blocked together with the amount
+	withdraw := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: testIban, Amount: 200}
+	assert.NoError(t, service.Create(context.Background(), withdraw))
+	assert.Equal(t, fees.Breakdown{Flat: 10, Bps: 500, Percentage: 10, Total: 20}, withdraw.Fee)
+	assert.Equal(t, int64(780), wallet.AvailableBalance)
+	assert.Equal(t, int64(220), wallet.BlockedBalance)
+
+	// and charged to the fee account on completion
+	assert.NoError(t, service.Claim(context.Background(), withdraw))
+	assert.NoError(t, service.MarkAsSent(context.Background(), withdraw))
+	assert.NoError(t, service.Complete(context.Background(), withdraw))
+	assert.Equal(t, int64(780), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+	assert.Equal(t, uint64(42), withdraw.FeeTransactionID)
+	ledgerRepo.AssertCalled(t, "Post", context.Background(), mock.MatchedBy(func(entries []core.LedgerEntry) bool {
+		return len(entries) == 2 &&
+			entries[0].Account == core.WalletBlockedAccount(wallet.UserID) && entries[0].Debit == 20 &&
+			entries[1].Account == core.Fees && entries[1].Credit == 20
+	}))
+}
+
+func TestService_FeeRefundedOnReverse(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	service, _, ledgerRepo := setupWithFees(stored, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {Default: fees.Schedule{Flat: 100}}}})
+
+	withdraw := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.SAMANAN, Iban: testIban, Amount: 200}
+	assert.ErrorIs(t, service.Create(context.Background(), &repository.Withdrawal{
+		WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.SAMANAN, Iban: testIban, Amount: 950,
+	}), withdraws.ErrInsufficientBalance)
+	assert.NoError(t, service.Create(context.Background(), withdraw))
+	assert.Equal(t, int64(700), wallet.AvailableBalance)
+
+	assert.NoError(t, service.Reverse(context.Background(), withdraw))
+	assert.Equal(t, int64(1000), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+	assert.Zero(t, withdraw.FeeTransactionID)
+	ledgerRepo.AssertNotCalled(t, "Post", context.Background(), mock.MatchedBy(func(entries []core.LedgerEntry) bool {
+		for _, entry := range entries {
+			if entry.Account == core.Fees {
+				return true
+			}
+		}
+		return false
+	}))
+}
+
+func TestService_CreateToBeneficiary(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	beneficiary := &beneficiaries.Beneficiary{ID: uuid.New(), UserID: wallet.UserID, Iban: testIban, HolderName: "Sara Ahmadi"}
+	beneficiaryService := new(MockBeneficiaryService)
+	beneficiaryService.On("Resolve", context.Background(), beneficiary.ID, wallet.UserID).Return(beneficiary, nil)
+	beneficiaryService.On("ResolveIban", context.Background(), wallet.UserID, testIban).Return(beneficiary, nil)
+	service, _, _ := setupWithBeneficiaries(stored, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {}}}, nil, beneficiaryService)
+
+	// by id, the iban and holder name are taken from the beneficiary
+	withdraw := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, BeneficiaryID: &beneficiary.ID, Amount: 200}
+	assert.NoError(t, service.Create(context.Background(), withdraw))
+	assert.Equal(t, testIban, withdraw.Iban)
+	assert.Equal(t, "Sara Ahmadi", withdraw.BeneficiaryName)
+
+	// by iban, the withdrawal is linked to the saved beneficiary
+	withdraw = &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: testIban, BeneficiaryName: "S. Ahmadi", Amount: 200}
+	assert.NoError(t, service.Create(context.Background(), withdraw))
+	assert.Equal(t, &beneficiary.ID, withdraw.BeneficiaryID)
+	assert.Equal(t, "S. Ahmadi", withdraw.BeneficiaryName)
+}
+
+func TestService_CreateCoolingOff(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	beneficiaryID := uuid.New()
+	unsavedIban := "IR160120000000001234567890"
+	beneficiaryService := new(MockBeneficiaryService)
+	beneficiaryService.On("Resolve", context.Background(), beneficiaryID, wallet.UserID).Return(nil, beneficiaries.ErrCoolingOff)
+	beneficiaryService.On("ResolveIban", context.Background(), wallet.UserID, testIban).Return(nil, beneficiaries.ErrCoolingOff)
+	beneficiaryService.On("ResolveIban", context.Background(), wallet.UserID, unsavedIban).Return(nil, beneficiaries.ErrNotSaved)
+	service, withdrawRepo, _ := setupWithBeneficiaries(stored, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {}}}, nil, beneficiaryService)
+
+	// a new beneficiary can not be paid by id, nor by typing its iban in
+	err := service.Create(context.Background(), &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, BeneficiaryID: &beneficiaryID, Amount: 200})
+	assert.ErrorIs(t, err, withdraws.ErrCoolingOff)
+	err = service.Create(context.Background(), &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: "ir06 2960 0000 0010 0324 2000 01", Amount: 200})
+	assert.ErrorIs(t, err, withdraws.ErrCoolingOff)
+	// and an iban never saved can not skip the period
+	err = service.Create(context.Background(), &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: unsavedIban, Amount: 200})
+	assert.ErrorIs(t, err, withdraws.ErrBeneficiaryRequired)
+
+	assert.Equal(t, int64(1000), wallet.AvailableBalance)
+	withdrawRepo.AssertNotCalled(t, "Create", context.Background(), mock.Anything)
+}
+
+func TestService_CreateIdempotentReplay(t *testing.T) {
+	ctx := context.Background()
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	service, withdrawRepo := setupIdempotent(stored, wallet)
+	key := idempotency.Key{Scope: "withdraw", Value: "retry-1", Fingerprint: idempotency.Fingerprint([]byte(`{"amount":200}`))}
+
+	first := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: testIban, Amount: 200}
+	replayed, err := service.CreateIdempotent(ctx, first, key)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	// the retry gets the first withdrawal back and blocks nothing more
+	retry := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: testIban, Amount: 200}
+	replayed, err = service.CreateIdempotent(ctx, retry, key)
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ID, retry.ID)
+	assert.Equal(t, enums.NEW, retry.Status)
+	assert.Equal(t, int64(800), wallet.AvailableBalance)
+	assert.Equal(t, int64(200), wallet.BlockedBalance)
+	withdrawRepo.AssertNumberOfCalls(t, "Create", 1)
+	withdrawRepo.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestService_CreateIdempotentKeyReused(t *testing.T) {
+	ctx := context.Background()
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	service, withdrawRepo := setupIdempotent(stored, wallet)
+
+	first := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: testIban, Amount: 200}
+	key := idempotency.Key{Scope: "withdraw", Value: "retry-1", Fingerprint: idempotency.Fingerprint([]byte(`{"amount":200}`))}
+	_, err := service.CreateIdempotent(ctx, first, key)
+	assert.NoError(t, err)
+
+	other := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: testIban, Amount: 500}
+	key.Fingerprint = idempotency.Fingerprint([]byte(`{"amount":500}`))
+	replayed, err := service.CreateIdempotent(ctx, other, key)
+	assert.ErrorIs(t, err, idempotency.ErrKeyReused)
+	assert.False(t, replayed)
+	assert.Equal(t, uuid.Nil, other.ID)
+	assert.Equal(t, int64(800), wallet.AvailableBalance)
+	withdrawRepo.AssertNumberOfCalls(t, "Create", 1)
+	withdrawRepo.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestService_CreateInvalidAmount(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	// a fee above the negative amount would make the blocked total positive
+	service, withdrawRepo, _ := setupWithFees(stored, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {Default: fees.Schedule{Flat: 500}}}})
+
+	for _, amount := range []int64{0, -200} {
+		withdraw := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: testIban, Amount: amount}
+		assert.ErrorIs(t, service.Create(context.Background(), withdraw), withdraws.ErrInvalidAmount)
+	}
+	assert.Equal(t, int64(1000), wallet.AvailableBalance)
+	assert.Zero(t, wallet.BlockedBalance)
+	withdrawRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_NoFeeSchedule(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.USD, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	service, withdrawRepo := setup(stored, wallet)
+
+	// only IRR has a schedule, so a USD payout is refused instead of being free
+	err := service.Create(context.Background(), &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.USD, Bank: enums.DUMMY, Iban: testIban, Amount: 200})
+	assert.ErrorIs(t, err, withdraws.ErrNoFeeSchedule)
+	assert.Equal(t, int64(1000), wallet.AvailableBalance)
+	withdrawRepo.AssertNotCalled(t, "Create", context.Background(), mock.Anything)
+}
+
+func TestService_ApplyPayoutResult(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.SENT)
+	service, withdrawRepo := setup(withdraw, wallet)
+	acceptedAt := time.Now().Add(-time.Minute)
+
+	// still pending at the bank, only its reference is kept
+	assert.NoError(t, service.ApplyPayoutResult(context.Background(), withdraw, &integrations.PayoutResult{
+		Status:     enums.SENT,
+		Reference:  "ref-1",
+		AcceptedAt: acceptedAt,
+	}))
+	assert.Equal(t, enums.SENT, withdraw.Status)
+	assert.Equal(t, "ref-1", withdrawRepo.stored.BankResult.Reference)
+	assert.Equal(t, acceptedAt, *withdrawRepo.stored.BankResult.AcceptedAt)
+	assert.Equal(t, int64(200), wallet.BlockedBalance)
+
+	// failed by the bank, the reason is kept and the withdrawal reversed
+	settledAt := time.Now()
+	assert.NoError(t, service.ApplyPayoutResult(context.Background(), withdraw, &integrations.PayoutResult{
+		Status:     enums.FAILED,
+		ReasonCode: "ACCOUNT_CLOSED",
+		Reason:     "destination account is closed",
+		SettledAt:  settledAt,
+	}))
+	assert.Equal(t, enums.FAILED, withdraw.Status)
+	assert.Equal(t, repository.BankResult{
+		Reference:  "ref-1",
+		ReasonCode: "ACCOUNT_CLOSED",
+		Reason:     "destination account is closed",
+		AcceptedAt: &acceptedAt,
+		SettledAt:  &settledAt,
+	}, withdrawRepo.stored.BankResult)
+	assert.Equal(t, int64(200), wallet.AvailableBalance)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+
+	assert.ErrorIs(t, service.ApplyPayoutResult(context.Background(), withdraw, &integrations.PayoutResult{
+		Status: enums.SUCCESS,
+	}), withdraws.ErrInvalidState)
+}
+
+func TestService_ApplyPayoutResultSuccess(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, BlockedBalance: 200}
+	withdraw := newWithdrawal(wallet, enums.SENT)
+	service, _ := setup(withdraw, wallet)
+
+	assert.NoError(t, service.ApplyPayoutResult(context.Background(), withdraw, &integrations.PayoutResult{
+		Status:    enums.SUCCESS,
+		Reference: "ref-1",
+		SettledAt: time.Now(),
+	}))
+	assert.Equal(t, enums.SUCCESS, withdraw.Status)
+	assert.Equal(t, "ref-1", withdraw.BankResult.Reference)
+	assert.NotNil(t, withdraw.BankResult.SettledAt)
+	assert.Equal(t, uint64(42), withdraw.WithdrawalTransactionID)
+	assert.Equal(t, int64(0), wallet.BlockedBalance)
+}
+
+func TestService_Routing(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	router, err := routing.New(routing.Config{Default: []string{"saman", "mellat"}})
+	assert.NoError(t, err)
+	service, withdrawRepo, _ := setupWithRouter(stored, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {
+		Banks: map[string]fees.Schedule{"mellat": {Flat: 30}},
+	}}}, router)
+	failedAt := time.Now()
+	withdrawRepo.On("GetBankHealth", context.Background(), enums.SAMANAN).Return(&repository.BankHealth{Bank: enums.SAMANAN, Failures: 3, FailedAt: &failedAt}, nil)
+	withdrawRepo.On("GetBankHealth", context.Background(), enums.MELLAT).Return(&repository.BankHealth{Bank: enums.MELLAT}, nil)
+
+	// saman is down, so it goes to mellat and pays the mellat fee
+	withdraw := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Iban: "IR160120000000001234567890", Amount: 200}
+	assert.NoError(t, service.Create(context.Background(), withdraw))
+	assert.Equal(t, enums.MELLAT, withdraw.Bank)
+	assert.Equal(t, routing.Route{Rule: "default", Fallback: true, Skipped: "saman: down"}, withdraw.Route)
+	assert.Equal(t, int64(30), withdraw.Fee.Total)
+
+	// a requested bank is kept
+	requested := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.SAMANAN, Iban: testIban, Amount: 200}
+	assert.NoError(t, service.Create(context.Background(), requested))
+	assert.Equal(t, enums.SAMANAN, requested.Bank)
+	assert.Zero(t, requested.Route)
+}
+
+func TestService_RequestedBankQuota(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	router, err := routing.New(routing.Config{Default: []string{"saman"}, Quotas: map[string]map[string]int64{"saman": {"irr": 5000}}})
+	assert.NoError(t, err)
+	service, withdrawRepo, _ := setupWithRouter(stored, wallet, fees.Config{Currencies: map[string]fees.Schedules{"IRR": {}}}, router)
+	withdrawRepo.On("RoutedAmount", context.Background(), enums.SAMANAN, core.IRR, mock.Anything).Return(int64(4900), nil)
+
+	// a requested bank is counted against its quota like a routed one
+	requested := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.SAMANAN, Iban: testIban, Amount: 200}
+	assert.ErrorIs(t, service.Create(context.Background(), requested), withdraws.ErrQuotaExceeded)
+	assert.Equal(t, int64(1000), wallet.AvailableBalance)
+	withdrawRepo.AssertNotCalled(t, "Commit")
+
+	requested.Amount = 100
+	assert.NoError(t, service.Create(context.Background(), requested))
+	assert.Equal(t, enums.SAMANAN, requested.Bank)
+	assert.Zero(t, requested.Route)
+}
+
+func TestService_NoRoute(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	service, withdrawRepo := setup(stored, wallet)
+
+	withdraw := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Iban: testIban, Amount: 200}
+	assert.ErrorIs(t, service.Create(context.Background(), withdraw), withdraws.ErrNoRoute)
+	assert.Equal(t, int64(1000), wallet.AvailableBalance)
+	withdrawRepo.AssertNotCalled(t, "Commit")
+}
+
+func TestService_CreateValidatesIban(t *testing.T) {
+	wallet := &core.Wallet{UserID: uuid.New(), Currency: core.IRR, AvailableBalance: 1000}
+	stored := newWithdrawal(wallet, enums.NEW)
+	service, _ := setup(stored, wallet)
+
+	invalid := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: "IR120120000000001234567890", Amount: 200}
+	assert.ErrorIs(t, service.Create(context.Background(), invalid), withdraws.ErrInvalidIban)
+	assert.Equal(t, int64(1000), wallet.AvailableBalance)
+
+	// stored in electronic format with the bank it is held at
+	withdraw := &repository.Withdrawal{WalletID: wallet.UserID, Currency: core.IRR, Bank: enums.DUMMY, Iban: "ir16 0120 0000 0000 1234 5678 90", Amount: 200}
+	assert.NoError(t, service.Create(context.Background(), withdraw))
+	assert.Equal(t, "IR160120000000001234567890", withdraw.Iban)
+	assert.Equal(t, "012", withdraw.BeneficiaryBankCode)
+}
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    journal_id UUID NOT NULL,
+    account VARCHAR(64) NOT NULL,
+    debit BIGINT NOT NULL DEFAULT 0 CHECK (debit >= 0),
+    credit BIGINT NOT NULL DEFAULT 0 CHECK (credit >= 0),
+    transaction_id BIGINT,
+    reference UUID NOT NULL,
+    description VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ledger_entries_journal_id ON ledger_entries(journal_id);
+CREATE INDEX idx_ledger_entries_account ON ledger_entries(account);
+CREATE INDEX idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+CREATE INDEX idx_ledger_entries_reference ON ledger_entries(reference);
+
+-- opening balances of existing wallets are booked against the suspense account
+INSERT INTO ledger_entries (journal_id, account, credit, reference, description)
+SELECT gen_random_uuid(), 'wallet:' || user_id || ':available', available_balance, user_id, 'opening balance'
+FROM wallets WHERE available_balance > 0;
+
+INSERT INTO ledger_entries (journal_id, account, debit, reference, description)
+SELECT gen_random_uuid(), 'wallet:' || user_id || ':available', -available_balance, user_id, 'opening balance'
+FROM wallets WHERE available_balance < 0;
+
+INSERT INTO ledger_entries (journal_id, account, credit, reference, description)
+SELECT gen_random_uuid(), 'wallet:' || user_id || ':blocked', blocked_balance, user_id, 'opening balance'
+FROM wallets WHERE blocked_balance > 0;
+
+INSERT INTO ledger_entries (journal_id, account, debit, reference, description)
+SELECT gen_random_uuid(), 'wallet:' || user_id || ':blocked', -blocked_balance, user_id, 'opening balance'
+FROM wallets WHERE blocked_balance < 0;
+
+INSERT INTO ledger_entries (journal_id, account, debit, credit, reference, description)
+SELECT journal_id, 'system:suspense', credit, debit, reference, description
+FROM ledger_entries;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS ledger_entries;
+
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE transfers (
+    id UUID PRIMARY KEY,
+    from_wallet_id UUID NOT NULL,
+    to_wallet_id UUID NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    reference VARCHAR(255),
+    held BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    apply_at TIMESTAMPTZ NOT NULL,
+    debit_transaction_id BIGINT,
+    credit_transaction_id BIGINT,
+    apply_transaction_id BIGINT NOT NULL DEFAULT 0,
+    CHECK (from_wallet_id <> to_wallet_id)
+);
+
+CREATE INDEX idx_transfers_from_wallet_id ON transfers(from_wallet_id);
+CREATE INDEX idx_transfers_to_wallet_id ON transfers(to_wallet_id);
+CREATE INDEX idx_transfers_reference ON transfers(reference);
+CREATE INDEX idx_transfers_created_at ON transfers(created_at);
+CREATE INDEX idx_transfers_apply_at ON transfers(apply_at);
+CREATE INDEX idx_transfers_debit_transaction_id ON transfers(debit_transaction_id);
+CREATE INDEX idx_transfers_credit_transaction_id ON transfers(credit_transaction_id);
+CREATE INDEX idx_transfers_apply_transaction_id ON transfers(apply_transaction_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS transfers;
+
+-- +goose StatementEnd
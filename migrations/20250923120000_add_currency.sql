@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_wallet_id_fkey;
+
+-- existing balances are all rials
+ALTER TABLE wallets ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IRR';
+ALTER TABLE wallets DROP CONSTRAINT wallets_pkey;
+ALTER TABLE wallets ADD PRIMARY KEY (user_id, currency);
+ALTER TABLE wallets ALTER COLUMN currency DROP DEFAULT;
+
+ALTER TABLE transactions ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IRR';
+ALTER TABLE transactions ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_wallet_fkey FOREIGN KEY (wallet_id, currency)
+    REFERENCES wallets(user_id, currency) ON DELETE CASCADE;
+DROP INDEX IF EXISTS idx_transactions_wallet_id;
+CREATE INDEX idx_transactions_wallet_id_currency ON transactions(wallet_id, currency);
+
+ALTER TABLE ledger_entries ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IRR';
+ALTER TABLE ledger_entries ALTER COLUMN currency DROP DEFAULT;
+DROP INDEX IF EXISTS idx_ledger_entries_account;
+CREATE INDEX idx_ledger_entries_account_currency ON ledger_entries(account, currency);
+
+ALTER TABLE deposits ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IRR';
+ALTER TABLE deposits ALTER COLUMN currency DROP DEFAULT;
+
+ALTER TABLE withdrawals ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IRR';
+ALTER TABLE withdrawals ALTER COLUMN currency DROP DEFAULT;
+
+ALTER TABLE transfers ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IRR';
+ALTER TABLE transfers ALTER COLUMN currency DROP DEFAULT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE transfers DROP COLUMN currency;
+ALTER TABLE withdrawals DROP COLUMN currency;
+ALTER TABLE deposits DROP COLUMN currency;
+
+DROP INDEX IF EXISTS idx_ledger_entries_account_currency;
+ALTER TABLE ledger_entries DROP COLUMN currency;
+CREATE INDEX idx_ledger_entries_account ON ledger_entries(account);
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_wallet_fkey;
+DROP INDEX IF EXISTS idx_transactions_wallet_id_currency;
+ALTER TABLE transactions DROP COLUMN currency;
+CREATE INDEX idx_transactions_wallet_id ON transactions(wallet_id);
+
+ALTER TABLE wallets DROP CONSTRAINT wallets_pkey;
+ALTER TABLE wallets DROP COLUMN currency;
+ALTER TABLE wallets ADD PRIMARY KEY (user_id);
+
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_wallet_id_fkey FOREIGN KEY (wallet_id)
+    REFERENCES wallets(user_id) ON DELETE CASCADE;
+
+-- +goose StatementEnd
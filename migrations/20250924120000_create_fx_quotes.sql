@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE fx_quotes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    from_amount BIGINT NOT NULL CHECK (from_amount > 0),
+    to_amount BIGINT NOT NULL CHECK (to_amount > 0),
+    rate NUMERIC(36, 18) NOT NULL,
+    spread_bps BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    executed_at TIMESTAMPTZ,
+    debit_transaction_id BIGINT,
+    credit_transaction_id BIGINT,
+    CHECK (from_currency <> to_currency)
+);
+
+CREATE INDEX idx_fx_quotes_user_id ON fx_quotes(user_id);
+CREATE INDEX idx_fx_quotes_expires_at ON fx_quotes(expires_at);
+CREATE INDEX idx_fx_quotes_debit_transaction_id ON fx_quotes(debit_transaction_id);
+CREATE INDEX idx_fx_quotes_credit_transaction_id ON fx_quotes(credit_transaction_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS fx_quotes;
+
+-- +goose StatementEnd
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE transactions ADD COLUMN kind VARCHAR(16);
+
+UPDATE transactions t SET kind = 'block'
+    FROM deposits d WHERE d.block_transaction_id = t.id;
+UPDATE transactions t SET kind = 'apply'
+    FROM deposits d WHERE d.apply_transaction_id = t.id;
+UPDATE transactions t SET kind = 'block'
+    FROM withdrawals w WHERE w.block_transaction_id = t.id;
+UPDATE transactions t SET kind = 'withdraw'
+    FROM withdrawals w WHERE w.withdrawal_transaction_id = t.id;
+-- completed withdrawals kept their final debit in reverser_transaction_id too,
+-- only the failed ones were actually reversed
+UPDATE transactions t SET kind = CASE w.status WHEN 'success' THEN 'withdraw' ELSE 'reversal' END
+    FROM withdrawals w WHERE w.reverser_transaction_id = t.id AND w.status IN ('success', 'failed');
+UPDATE transactions t SET kind = 'transfer'
+    FROM transfers tr WHERE t.id IN (tr.debit_transaction_id, tr.credit_transaction_id);
+UPDATE transactions t SET kind = 'apply'
+    FROM transfers tr WHERE tr.apply_transaction_id = t.id;
+UPDATE transactions t SET kind = 'exchange'
+    FROM fx_quotes q WHERE t.id IN (q.debit_transaction_id, q.credit_transaction_id);
+
+-- rows not linked to any operation are classified by their shape
+UPDATE transactions SET kind = CASE
+    WHEN amount > 0 AND blocked_amount < 0 THEN 'apply'
+    WHEN amount = 0 AND blocked_amount < 0 THEN 'withdraw'
+    ELSE 'block'
+END WHERE kind IS NULL;
+
+ALTER TABLE transactions ALTER COLUMN kind SET NOT NULL;
+CREATE INDEX idx_transactions_kind ON transactions(kind);
+
+-- keyset pagination walks a wallet's transactions by id
+CREATE INDEX idx_transactions_wallet_id_currency_id ON transactions(wallet_id, currency, id DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_transactions_wallet_id_currency_id;
+DROP INDEX IF EXISTS idx_transactions_kind;
+ALTER TABLE transactions DROP COLUMN kind;
+
+-- +goose StatementEnd
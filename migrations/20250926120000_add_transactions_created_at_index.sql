@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- point-in-time balances and statements sum a wallet's transactions by creation time
+CREATE INDEX idx_transactions_wallet_id_currency_created_at ON transactions(wallet_id, currency, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_transactions_wallet_id_currency_created_at;
+
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- mirrors Wallet.Validate in lib/core; fails if a wallet already broke the policy,
+-- run bin/ledger_audit and fix those wallets first
+ALTER TABLE wallets ADD CONSTRAINT wallets_available_balance_check CHECK (available_balance >= 0);
+ALTER TABLE wallets ADD CONSTRAINT wallets_blocked_balance_check CHECK (blocked_balance >= 0);
+
+ALTER TABLE deposits ADD CONSTRAINT deposits_amount_check CHECK (amount > 0);
+ALTER TABLE withdrawals ADD CONSTRAINT withdrawals_amount_check CHECK (amount > 0);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE withdrawals DROP CONSTRAINT withdrawals_amount_check;
+ALTER TABLE deposits DROP CONSTRAINT deposits_amount_check;
+ALTER TABLE wallets DROP CONSTRAINT wallets_blocked_balance_check;
+ALTER TABLE wallets DROP CONSTRAINT wallets_available_balance_check;
+
+-- +goose StatementEnd
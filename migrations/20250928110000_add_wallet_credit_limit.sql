@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE wallets ADD COLUMN credit_limit BIGINT NOT NULL DEFAULT 0;
+
+-- mirrors Wallet.Validate in lib/core, the available balance may now use the credit
+ALTER TABLE wallets ADD CONSTRAINT wallets_credit_limit_check CHECK (credit_limit >= 0);
+ALTER TABLE wallets DROP CONSTRAINT wallets_available_balance_check;
+ALTER TABLE wallets ADD CONSTRAINT wallets_available_balance_check CHECK (available_balance >= -credit_limit);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- fails while wallets use their credit
+ALTER TABLE wallets DROP CONSTRAINT wallets_available_balance_check;
+ALTER TABLE wallets ADD CONSTRAINT wallets_available_balance_check CHECK (available_balance >= 0);
+ALTER TABLE wallets DROP CONSTRAINT wallets_credit_limit_check;
+ALTER TABLE wallets DROP COLUMN credit_limit;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE wallet_credit_limit_changes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    old_limit BIGINT NOT NULL,
+    new_limit BIGINT NOT NULL CHECK (new_limit >= 0),
+    actor VARCHAR(64) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id, currency) REFERENCES wallets(user_id, currency) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_credit_limit_changes_wallet ON wallet_credit_limit_changes(user_id, currency);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS wallet_credit_limit_changes;
+
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE wallets ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'debit_frozen', 'fully_frozen', 'closed'));
+ALTER TABLE wallets ADD COLUMN status_reason VARCHAR(255);
+ALTER TABLE wallets ADD COLUMN status_changed_by VARCHAR(64);
+ALTER TABLE wallets ADD COLUMN status_changed_at TIMESTAMPTZ;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE wallets DROP COLUMN status_changed_at;
+ALTER TABLE wallets DROP COLUMN status_changed_by;
+ALTER TABLE wallets DROP COLUMN status_reason;
+ALTER TABLE wallets DROP COLUMN status;
+
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE adjustments (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    balance VARCHAR(16) NOT NULL CHECK (balance IN ('available', 'blocked')),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    reason VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('pending', 'approved', 'rejected')),
+    proposed_by VARCHAR(64) NOT NULL,
+    proposed_at TIMESTAMPTZ NOT NULL,
+    reviewed_by VARCHAR(64),
+    reviewed_at TIMESTAMPTZ,
+    transaction_id BIGINT,
+    -- maker-checker: only another operator approves, proposers may withdraw their own proposal
+    CHECK (reviewed_by IS NULL OR reviewed_by <> proposed_by OR status = 'rejected')
+);
+
+CREATE INDEX idx_adjustments_user_id ON adjustments(user_id);
+CREATE INDEX idx_adjustments_status ON adjustments(status);
+CREATE INDEX idx_adjustments_proposed_at ON adjustments(proposed_at);
+CREATE INDEX idx_adjustments_transaction_id ON adjustments(transaction_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS adjustments;
+
+-- +goose StatementEnd
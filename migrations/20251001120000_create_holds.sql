@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE holds (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    captured_amount BIGINT NOT NULL DEFAULT 0,
+    reference VARCHAR(255),
+    status VARCHAR(16) NOT NULL CHECK (status IN ('active', 'captured', 'released', 'expired')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ,
+    block_transaction_id BIGINT,
+    capture_transaction_id BIGINT NOT NULL DEFAULT 0,
+    release_transaction_id BIGINT NOT NULL DEFAULT 0,
+    CHECK (captured_amount >= 0 AND captured_amount <= amount)
+);
+
+CREATE INDEX idx_holds_user_id ON holds(user_id);
+CREATE INDEX idx_holds_reference ON holds(reference);
+CREATE INDEX idx_holds_created_at ON holds(created_at);
+CREATE INDEX idx_holds_block_transaction_id ON holds(block_transaction_id);
+CREATE INDEX idx_holds_capture_transaction_id ON holds(capture_transaction_id);
+CREATE INDEX idx_holds_release_transaction_id ON holds(release_transaction_id);
+-- the expiry worker only looks at active holds
+CREATE INDEX idx_holds_active_expires_at ON holds(expires_at) WHERE status = 'active';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS holds;
+
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE deposits ADD COLUMN cancel_transaction_id BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX idx_deposits_cancel_transaction_id ON deposits(cancel_transaction_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_deposits_cancel_transaction_id;
+ALTER TABLE deposits DROP COLUMN cancel_transaction_id;
+
+-- +goose StatementEnd
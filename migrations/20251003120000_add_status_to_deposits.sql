@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE deposits ADD COLUMN status VARCHAR(16);
+
+UPDATE deposits SET status = CASE
+    WHEN cancel_transaction_id <> 0 THEN 'cancelled'
+    WHEN COALESCE(apply_transaction_id, 0) <> 0 THEN 'applied'
+    ELSE 'pending'
+END;
+
+ALTER TABLE deposits ALTER COLUMN status SET NOT NULL;
+ALTER TABLE deposits ADD CONSTRAINT deposits_status_check
+    CHECK (status IN ('pending', 'applied', 'cancelled', 'failed'));
+
+CREATE INDEX idx_deposits_user_id_status_created_at ON deposits(user_id, status, created_at DESC);
+-- the applier only looks at pending deposits
+CREATE INDEX idx_deposits_pending_apply_at ON deposits(apply_at) WHERE status = 'pending';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_deposits_pending_apply_at;
+DROP INDEX IF EXISTS idx_deposits_user_id_status_created_at;
+ALTER TABLE deposits DROP CONSTRAINT IF EXISTS deposits_status_check;
+ALTER TABLE deposits DROP COLUMN status;
+
+-- +goose StatementEnd
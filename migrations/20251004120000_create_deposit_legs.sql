@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE deposit_legs (
+    id UUID PRIMARY KEY,
+    deposit_id UUID NOT NULL REFERENCES deposits(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    apply_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('pending', 'applied', 'cancelled')),
+    apply_transaction_id BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_deposit_legs_deposit_id ON deposit_legs(deposit_id);
+CREATE INDEX idx_deposit_legs_apply_transaction_id ON deposit_legs(apply_transaction_id);
+-- the applier only looks at pending legs
+CREATE INDEX idx_deposit_legs_pending_apply_at ON deposit_legs(apply_at) WHERE status = 'pending';
+
+-- existing deposits are released in a single leg
+INSERT INTO deposit_legs (id, deposit_id, user_id, currency, amount, apply_at, status, apply_transaction_id)
+SELECT gen_random_uuid(), id, user_id, currency, amount, apply_at,
+    CASE WHEN status = 'failed' THEN 'pending' ELSE status END,
+    COALESCE(apply_transaction_id, 0)
+FROM deposits;
+
+ALTER TABLE deposits ADD COLUMN blocked_amount BIGINT NOT NULL DEFAULT 0;
+UPDATE deposits SET blocked_amount = amount WHERE status IN ('pending', 'failed');
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE deposits DROP COLUMN blocked_amount;
+DROP TABLE IF EXISTS deposit_legs;
+
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- existing deposits have no known source
+ALTER TABLE deposits ADD COLUMN source_type VARCHAR(32) NOT NULL DEFAULT 'other';
+ALTER TABLE deposits ALTER COLUMN source_type DROP DEFAULT;
+ALTER TABLE deposits ADD COLUMN external_reference VARCHAR(255);
+ALTER TABLE deposits ADD COLUMN metadata JSONB;
+
+-- duplicate upstream callbacks are rejected; also serves lookups by reference
+CREATE UNIQUE INDEX idx_deposits_external_reference_source_type ON deposits(external_reference, source_type);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_deposits_external_reference_source_type;
+ALTER TABLE deposits DROP COLUMN metadata;
+ALTER TABLE deposits DROP COLUMN external_reference;
+ALTER TABLE deposits DROP COLUMN source_type;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- existing withdrawals were created without a fee
+ALTER TABLE withdrawals ADD COLUMN fee_flat BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE withdrawals ADD COLUMN fee_bps BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE withdrawals ADD COLUMN fee_percentage BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE withdrawals ADD COLUMN fee_total BIGINT NOT NULL DEFAULT 0 CHECK (fee_total >= 0);
+ALTER TABLE withdrawals ADD COLUMN fee_transaction_id BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_withdrawals_fee_transaction_id ON withdrawals(fee_transaction_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_withdrawals_fee_transaction_id;
+ALTER TABLE withdrawals DROP COLUMN fee_transaction_id;
+ALTER TABLE withdrawals DROP COLUMN fee_total;
+ALTER TABLE withdrawals DROP COLUMN fee_percentage;
+ALTER TABLE withdrawals DROP COLUMN fee_bps;
+ALTER TABLE withdrawals DROP COLUMN fee_flat;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE withdrawals ADD COLUMN beneficiary_name VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE withdrawals ADD COLUMN description VARCHAR(255) NOT NULL DEFAULT '';
+
+-- what the bank last reported about the payout
+ALTER TABLE withdrawals ADD COLUMN bank_reference VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE withdrawals ADD COLUMN bank_reason_code VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE withdrawals ADD COLUMN bank_reason VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE withdrawals ADD COLUMN bank_accepted_at TIMESTAMPTZ;
+ALTER TABLE withdrawals ADD COLUMN bank_settled_at TIMESTAMPTZ;
+
+CREATE INDEX idx_withdrawals_bank_reference ON withdrawals(bank_reference);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_withdrawals_bank_reference;
+ALTER TABLE withdrawals DROP COLUMN bank_settled_at;
+ALTER TABLE withdrawals DROP COLUMN bank_accepted_at;
+ALTER TABLE withdrawals DROP COLUMN bank_reason;
+ALTER TABLE withdrawals DROP COLUMN bank_reason_code;
+ALTER TABLE withdrawals DROP COLUMN bank_reference;
+ALTER TABLE withdrawals DROP COLUMN description;
+ALTER TABLE withdrawals DROP COLUMN beneficiary_name;
+
+-- +goose StatementEnd
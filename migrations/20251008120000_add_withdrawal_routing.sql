@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- how the bank of a withdrawal was chosen, empty when the client named it
+ALTER TABLE withdrawals ADD COLUMN route_rule VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE withdrawals ADD COLUMN route_fallback BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE withdrawals ADD COLUMN route_skipped VARCHAR(255) NOT NULL DEFAULT '';
+
+-- daily quotas sum the withdrawals of a bank
+CREATE INDEX idx_withdrawals_bank_created_at ON withdrawals(bank, created_at);
+
+-- failed sends to a bank since its last successful one, kept by the banker
+CREATE TABLE bank_health (
+    bank VARCHAR(32) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_error VARCHAR(255) NOT NULL DEFAULT '',
+    failed_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS bank_health;
+DROP INDEX IF EXISTS idx_withdrawals_bank_created_at;
+ALTER TABLE withdrawals DROP COLUMN route_skipped;
+ALTER TABLE withdrawals DROP COLUMN route_fallback;
+ALTER TABLE withdrawals DROP COLUMN route_rule;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- IBANs are up to 34 characters (ISO 13616), Iranian ones 26
+ALTER TABLE withdrawals ALTER COLUMN iban TYPE VARCHAR(34);
+ALTER TABLE withdrawals ADD COLUMN beneficiary_bank_code VARCHAR(16) NOT NULL DEFAULT '';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- fails while withdrawals with longer IBANs exist
+ALTER TABLE withdrawals DROP COLUMN beneficiary_bank_code;
+ALTER TABLE withdrawals ALTER COLUMN iban TYPE VARCHAR(24);
+
+-- +goose StatementEnd
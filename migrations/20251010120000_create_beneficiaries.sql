@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE beneficiaries (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    iban VARCHAR(34) NOT NULL,
+    bank_code VARCHAR(16) NOT NULL DEFAULT '',
+    holder_name VARCHAR(128) NOT NULL DEFAULT '',
+    label VARCHAR(64) NOT NULL DEFAULT '',
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_at TIMESTAMPTZ,
+    verified_by VARCHAR(64),
+    available_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- a user saves an IBAN once
+    UNIQUE (user_id, iban)
+);
+
+CREATE INDEX idx_beneficiaries_user_id ON beneficiaries(user_id);
+
+-- no foreign key, a beneficiary can be deleted while its withdrawals remain
+ALTER TABLE withdrawals ADD COLUMN beneficiary_id UUID;
+CREATE INDEX idx_withdrawals_beneficiary_id ON withdrawals(beneficiary_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_withdrawals_beneficiary_id;
+ALTER TABLE withdrawals DROP COLUMN IF EXISTS beneficiary_id;
+DROP TABLE IF EXISTS beneficiaries;
+
+-- +goose StatementEnd
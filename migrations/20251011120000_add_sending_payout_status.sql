@@ -0,0 +1,11 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+
+-- withdrawals claimed by the banker that the bank may not have yet
+ALTER TYPE payout_status ADD VALUE IF NOT EXISTS 'sending' AFTER 'new';
+
+-- +goose Down
+
+-- enum values can not be dropped; claimed withdrawals are left to the status
+-- checks of the banker, which treats them like sent ones
+UPDATE withdrawals SET status = 'sent' WHERE status = 'sending';
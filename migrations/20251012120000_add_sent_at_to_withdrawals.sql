@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE withdrawals ADD COLUMN sent_at TIMESTAMPTZ;
+-- the last change of a sent withdrawal is the closest to when the bank took it
+UPDATE withdrawals SET sent_at = updated_at WHERE status = 'sent';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE withdrawals DROP COLUMN sent_at;
+
+-- +goose StatementEnd
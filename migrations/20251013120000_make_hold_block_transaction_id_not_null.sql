@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- like the capture and release transaction ids, 0 means none
+UPDATE holds SET block_transaction_id = 0 WHERE block_transaction_id IS NULL;
+ALTER TABLE holds
+    ALTER COLUMN block_transaction_id SET DEFAULT 0,
+    ALTER COLUMN block_transaction_id SET NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE holds
+    ALTER COLUMN block_transaction_id DROP NOT NULL,
+    ALTER COLUMN block_transaction_id DROP DEFAULT;
+
+-- +goose StatementEnd